@@ -0,0 +1,172 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	orm "github.com/medatechnology/simpleorm"
+)
+
+//------------------------------------------------------------------
+// AGGREGATE HELPERS
+//------------------------------------------------------------------
+
+// runAggregate runs SELECT function(column) FROM tableName WHERE condition and returns the value
+// as float64. NULL (ie: SUM of no rows) is returned as 0. OrderBy, Limit and Offset of the
+// condition are ignored.
+func (c *Client) runAggregate(function, tableName, column string, condition *orm.Condition) (float64, error) {
+	whereClause, values, err := ConditionToWhere(condition)
+	if err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf("SELECT %s(%s) AS aggregate_value FROM %s", function, column, tableName)
+	if strings.TrimSpace(whereClause) != "" {
+		query += " WHERE " + whereClause
+	}
+
+	value, err := c.ScalarFloat(orm.ParametereizedSQL{Query: query, Values: values})
+	if errors.Is(err, orm.ErrSQLNoRows) {
+		return 0, nil
+	}
+	return value, err
+}
+
+// Sum returns the sum of column over the rows matching condition (nil for all rows), 0 if none match
+func (c *Client) Sum(tableName, column string, condition *orm.Condition) (float64, error) {
+	return c.runAggregate("SUM", tableName, column, condition)
+}
+
+// Avg returns the average of column over the rows matching condition, 0 if none match
+func (c *Client) Avg(tableName, column string, condition *orm.Condition) (float64, error) {
+	return c.runAggregate("AVG", tableName, column, condition)
+}
+
+// Min returns the smallest value of column over the rows matching condition, 0 if none match
+func (c *Client) Min(tableName, column string, condition *orm.Condition) (float64, error) {
+	return c.runAggregate("MIN", tableName, column, condition)
+}
+
+// Max returns the largest value of column over the rows matching condition, 0 if none match
+func (c *Client) Max(tableName, column string, condition *orm.Condition) (float64, error) {
+	return c.runAggregate("MAX", tableName, column, condition)
+}
+
+// GroupRow is one group of GroupByCount
+type GroupRow struct {
+	Key   interface{}
+	Count int64
+}
+
+// GroupedRow is one group of GroupBy, Keys and Aggregates are in the order of the requested
+// group columns and aggregate expressions
+type GroupedRow struct {
+	Keys       []interface{}
+	Aggregates []interface{}
+}
+
+// GroupByCount counts the rows matching condition per value of groupColumn, biggest groups first
+// Usage:
+//
+//	rows, err := db.GroupByCount("orders", "status", nil)
+//	// rows[0].Key = "paid", rows[0].Count = 1204
+func (c *Client) GroupByCount(tableName, groupColumn string, condition *orm.Condition) ([]GroupRow, error) {
+	grouped, err := c.groupBy(tableName, []string{groupColumn}, []string{"COUNT(*)"}, condition, "aggregate_0 DESC")
+	if err != nil {
+		return nil, err
+	}
+	result := make([]GroupRow, 0, len(grouped))
+	for _, row := range grouped {
+		count, err := toInt64(row.Aggregates[0])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, GroupRow{Key: row.Keys[0], Count: count})
+	}
+	return result, nil
+}
+
+// GroupBy runs the aggregate expressions (ie: "COUNT(*)", "SUM(total)") per group of groupColumns
+// over the rows matching condition. Groups are ordered by condition.OrderBy (the expressions can be
+// referenced as aggregate_0, aggregate_1...) or by the group columns, condition.Limit limits the groups.
+// Usage:
+//
+//	rows, err := db.GroupBy("orders", []string{"country", "status"}, []string{"COUNT(*)", "SUM(total)"}, nil)
+func (c *Client) GroupBy(tableName string, groupColumns, aggregates []string, condition *orm.Condition) ([]GroupedRow, error) {
+	return c.groupBy(tableName, groupColumns, aggregates, condition, strings.Join(groupColumns, ", "))
+}
+
+func (c *Client) groupBy(tableName string, groupColumns, aggregates []string, condition *orm.Condition, defaultOrder string) ([]GroupedRow, error) {
+	if len(groupColumns) == 0 {
+		return nil, errors.New("group by needs at least one column")
+	}
+	whereClause, values, err := ConditionToWhere(condition)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]string, 0, len(groupColumns)+len(aggregates))
+	for i, column := range groupColumns {
+		selected = append(selected, fmt.Sprintf("%s AS group_%d", column, i))
+	}
+	for i, expression := range aggregates {
+		selected = append(selected, fmt.Sprintf("%s AS aggregate_%d", expression, i))
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(selected, ", "), tableName)
+	if strings.TrimSpace(whereClause) != "" {
+		query += " WHERE " + whereClause
+	}
+	query += " GROUP BY " + strings.Join(groupColumns, ", ")
+	if condition != nil && len(condition.OrderBy) > 0 {
+		query += " ORDER BY " + strings.Join(condition.OrderBy, ", ")
+	} else {
+		query += " ORDER BY " + defaultOrder
+	}
+	if condition != nil && condition.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", condition.Limit)
+	}
+
+	records, err := c.SelectOneSQLParameterized(orm.ParametereizedSQL{Query: query, Values: values})
+	if err != nil {
+		if errors.Is(err, orm.ErrSQLNoRows) {
+			return []GroupedRow{}, nil
+		}
+		return nil, err
+	}
+	result := make([]GroupedRow, 0, len(records))
+	for _, rec := range records {
+		row := GroupedRow{
+			Keys:       make([]interface{}, len(groupColumns)),
+			Aggregates: make([]interface{}, len(aggregates)),
+		}
+		for i := range groupColumns {
+			row.Keys[i] = rec.Data[fmt.Sprintf("group_%d", i)]
+		}
+		for i := range aggregates {
+			row.Aggregates[i] = rec.Data[fmt.Sprintf("aggregate_%d", i)]
+		}
+		result = append(result, row)
+	}
+	return result, nil
+}
+
+// toInt64 converts a JSON decoded whole number to int64
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("value %v is not an integer", v)
+		}
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case json.Number:
+		return v.Int64()
+	}
+	return 0, fmt.Errorf("cannot convert value of type %T to int", value)
+}
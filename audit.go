@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/medatechnology/suresql"
+)
+
+//------------------------------------------------------------------
+// AUDIT HOOKS
+//------------------------------------------------------------------
+
+// REQUEST_ID_HEADER carries the RequestInfo.RequestID of audited requests, so client and
+// server logs can be correlated. Servers that don't log it simply ignore it.
+const REQUEST_ID_HEADER = "X-Request-ID"
+
+// RequestInfo describes an HTTP request for the BeforeRequest and AfterResponse hooks.
+// SQL never contains bound parameter values and quoted literals of raw statements are replaced
+// with '?'. Params holds a copy of the bound values (one slice per parameterized statement), the
+// hook can redact or drop them freely, the request itself is not affected.
+type RequestInfo struct {
+	RequestID string // same for the retries (token refresh, fallback to leader) of one call
+	Method    string
+	Endpoint  string
+	NodeID    string
+	NodeURL   string
+	User      string
+	SQL       string
+	Params    [][]interface{}
+}
+
+type requestIDCtx struct{}
+
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtx{}).(string)
+	return id
+}
+
+// auditEnabled reports whether any audit hook is set
+func (c *Client) auditEnabled() bool {
+	return c.Config.BeforeRequest != nil || c.Config.AfterResponse != nil
+}
+
+// beforeRequest builds the RequestInfo (giving ctx a request ID if it has none yet)
+// and fires the BeforeRequest hook
+func (c *Client) beforeRequest(ctx context.Context, conn *Connection, method, endpoint string, body interface{}) (context.Context, RequestInfo) {
+	requestID := requestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = newIdempotencyKey()
+		ctx = context.WithValue(ctx, requestIDCtx{}, requestID)
+	}
+	info := RequestInfo{
+		RequestID: requestID,
+		Method:    method,
+		Endpoint:  endpoint,
+		NodeID:    conn.NodeID,
+		NodeURL:   conn.URL,
+		User:      c.Config.Username,
+		SQL:       querySQLFromBody(body),
+		Params:    paramsFromBody(body),
+	}
+	if c.Config.BeforeRequest != nil {
+		c.Config.BeforeRequest(info)
+	}
+	return ctx, info
+}
+
+// afterResponse fires the AfterResponse hook with the HTTP status (0 if there was no response)
+func (c *Client) afterResponse(info RequestInfo, resp *http.Response, elapsed time.Duration, err error) {
+	if c.Config.AfterResponse == nil {
+		return
+	}
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	c.Config.AfterResponse(info, status, elapsed, err)
+}
+
+// paramsFromBody copies the bound values of the parameterized statements of a request body
+func paramsFromBody(body interface{}) [][]interface{} {
+	req, ok := body.(*suresql.SQLRequest)
+	if !ok || len(req.ParamSQL) == 0 {
+		return nil
+	}
+	params := make([][]interface{}, 0, len(req.ParamSQL))
+	for _, paramSQL := range req.ParamSQL {
+		params = append(params, append([]interface{}(nil), paramSQL.Values...))
+	}
+	return params
+}
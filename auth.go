@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/medatechnology/suresql"
+)
+
+//------------------------------------------------------------------
+// TOKEN RENEWAL COORDINATION
+//------------------------------------------------------------------
+
+// For existing connection (maybe when call send it failed) try to renew the token by:
+// 1. First try to refresh using refresh token, if succeed then exit.
+// 2. If refresh failed, try to renew by calling /connect
+//
+// Renewals are coordinated to avoid a storm of auth calls when many requests see an expired
+// token at once (ie: after an outage):
+//   - requests on the same connection renew one at a time, a request whose staleToken was already
+//     replaced by another request just uses the new token
+//   - the /db/refresh of all connections holding the same refresh token is shared, see refreshShared
+//   - when the refresh fails, the /db/connect of all connections of the same node is shared,
+//     one call is made and every connection waiting for it gets the same new token
+func (c *Client) renewToken(ctx context.Context, conn *Connection, staleToken string) error {
+	conn.refreshMutex.Lock()
+	defer conn.refreshMutex.Unlock()
+	if conn.token().Token != staleToken {
+		return nil
+	}
+
+	stats := c.nodeTokenStats(conn.NodeID)
+	atomic.AddInt64(&stats.refreshAttempts, 1)
+	if err := c.refreshShared(ctx, conn); err == nil {
+		atomic.AddInt64(&stats.refreshSuccesses, 1)
+		return nil
+	}
+	atomic.AddInt64(&stats.connectFallbacks, 1)
+
+	// the connections of a node all log in with the same credentials, share the new token
+	token, err, shared := c.loginFlights.do(conn.NodeID+"\x00"+conn.URL, func() (suresql.TokenTable, error) {
+		atomic.AddInt64(&c.authCalls, 1)
+		err := conn.newOrRefreshToken(ctx, &c.Config, false)
+		return conn.token(), err
+	})
+	if err != nil {
+		// the connection is left without a valid token
+		atomic.AddInt64(&stats.refreshFailures, 1)
+		return err
+	}
+	if shared {
+		conn.setToken(token)
+	}
+	return nil
+}
+
+// refreshShared renews the token of conn with /db/refresh. The server rotates refresh tokens: once
+// used, the old one is rejected. Connections holding the same refresh token (every connection after
+// ConnectWithToken, or the connections sharing a login) therefore make one refresh call together,
+// and the new token is copied to all connections still holding the old one.
+func (c *Client) refreshShared(ctx context.Context, conn *Connection) error {
+	staleRefresh := conn.token().Refresh
+	if staleRefresh == "" {
+		return errors.New("no refresh token available for connection")
+	}
+	token, err, _ := c.refreshFlights.do(staleRefresh, func() (suresql.TokenTable, error) {
+		c.rotationMutex.Lock()
+		rotatedRefresh, rotatedToken := c.rotatedRefresh, c.rotatedToken
+		c.rotationMutex.Unlock()
+		if rotatedRefresh == staleRefresh {
+			// refreshed by a call that ended just before this one started
+			return rotatedToken, nil
+		}
+
+		atomic.AddInt64(&c.authCalls, 1)
+		if err := conn.newOrRefreshToken(ctx, &c.Config, CALL_REFRESH); err != nil {
+			return suresql.TokenTable{}, err
+		}
+		token := conn.token()
+		c.rotationMutex.Lock()
+		c.rotatedRefresh, c.rotatedToken = staleRefresh, token
+		c.rotationMutex.Unlock()
+		c.replaceRotatedToken(staleRefresh, token)
+		return token, nil
+	})
+	if err != nil {
+		return err
+	}
+	conn.setToken(token)
+	return nil
+}
+
+// replaceRotatedToken gives token to every connection still holding the refresh token staleRefresh
+func (c *Client) replaceRotatedToken(staleRefresh string, token suresql.TokenTable) {
+	connections := append(c.readPool.GetAllConnections(), c.writePool.GetAllConnections()...)
+	connections = append(connections, c.getLeaderConnection())
+	for _, conn := range connections {
+		conn.tokenMutex.Lock()
+		if conn.Token.Refresh == staleRefresh {
+			conn.Token = token
+			conn.LastRefresh = time.Now()
+		}
+		conn.tokenMutex.Unlock()
+	}
+}
+
+// token returns the current token of the connection
+func (c *Connection) token() suresql.TokenTable {
+	c.tokenMutex.RLock()
+	defer c.tokenMutex.RUnlock()
+	return c.Token
+}
+
+// setToken replaces the token of the connection and marks it as just refreshed
+func (c *Connection) setToken(token suresql.TokenTable) {
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+	c.Token = token
+	c.LastRefresh = time.Now()
+}
+
+// lastRefresh returns when the token of the connection was last renewed
+func (c *Connection) lastRefresh() time.Time {
+	c.tokenMutex.RLock()
+	defer c.tokenMutex.RUnlock()
+	return c.LastRefresh
+}
+
+// tokenStats counts the token renewals of a node, updated atomically
+type tokenStats struct {
+	refreshAttempts  int64 // renewals started with /db/refresh
+	refreshSuccesses int64 // renewals done by /db/refresh
+	connectFallbacks int64 // renewals where /db/refresh failed and /db/connect was needed
+	refreshFailures  int64 // renewals where /db/connect failed too
+}
+
+// nodeTokenStats returns the token renewal counters of the node, created on first use
+func (c *Client) nodeTokenStats(nodeID string) *tokenStats {
+	if stats, ok := c.tokenStatsPerNode.Load(nodeID); ok {
+		return stats.(*tokenStats)
+	}
+	stats, _ := c.tokenStatsPerNode.LoadOrStore(nodeID, &tokenStats{})
+	return stats.(*tokenStats)
+}
+
+// tokenMetrics fills the token renewal part of the node metrics, LastTokenRefresh is the most
+// recent token of the given connections
+func (c *Client) tokenMetrics(metrics *NodePoolMetrics, connections []*Connection) {
+	if stats, ok := c.tokenStatsPerNode.Load(metrics.NodeID); ok {
+		stats := stats.(*tokenStats)
+		metrics.RefreshAttempts = atomic.LoadInt64(&stats.refreshAttempts)
+		metrics.RefreshSuccesses = atomic.LoadInt64(&stats.refreshSuccesses)
+		metrics.ConnectFallbacks = atomic.LoadInt64(&stats.connectFallbacks)
+		metrics.RefreshFailures = atomic.LoadInt64(&stats.refreshFailures)
+	}
+	for _, conn := range connections {
+		if lastRefresh := conn.lastRefresh(); lastRefresh.After(metrics.LastTokenRefresh) {
+			metrics.LastTokenRefresh = lastRefresh
+		}
+	}
+}
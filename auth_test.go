@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/medatechnology/suresql"
+)
+
+// rotatingServer issues token-N/refresh-N and, like suresql, rejects a refresh token once used
+type rotatingServer struct {
+	mutex      sync.Mutex
+	generation int
+	expired    bool // the current access token is rejected until the next refresh
+	refreshes  int
+}
+
+func (s *rotatingServer) roundTrip(req *http.Request) (*http.Response, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if req.URL.Path == "/db/refresh" {
+		var body map[string]string
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		if body["refresh_token"] != fmt.Sprintf("refresh-%d", s.generation) {
+			return stubResponse(req, http.StatusUnauthorized, "application/json", `{"status":401,"message":"invalid refresh token"}`), nil
+		}
+		s.generation++
+		s.expired = false
+		s.refreshes++
+		return okResponse(req, fmt.Sprintf(`{"token":"token-%d","refresh_token":"refresh-%d"}`, s.generation, s.generation)), nil
+	}
+	if s.expired || req.Header.Get("Authorization") != fmt.Sprintf("Bearer token-%d", s.generation) {
+		return stubResponse(req, http.StatusUnauthorized, "application/json", `{"status":401,"message":"token expired"}`), nil
+	}
+	return okResponse(req, `{"ok":true}`), nil
+}
+
+func (s *rotatingServer) expire() {
+	s.mutex.Lock()
+	s.expired = true
+	s.mutex.Unlock()
+}
+
+func TestRefreshTokenRotationAcrossConnections(t *testing.T) {
+	server := &rotatingServer{}
+	c := newStubClient(t, roundTripFunc(server.roundTrip))
+	token := suresql.TokenTable{Token: "token-0", Refresh: "refresh-0"}
+	c.Config.tokenOnly = true
+	c.leaderConn.setToken(token)
+	var connections []*Connection
+	for i := 0; i < 3; i++ {
+		conn := NewConnection(&c.Config, "http://leader.test", "node1", "r", false, token)
+		c.readPool.Add(conn)
+		connections = append(connections, conn)
+	}
+	send := func(conn *Connection) error {
+		_, err := c.doRequestToPool(context.Background(), conn, "POST", "/db/api/sql", nil, WITH_TOKEN, AUTO_REFRESH, NO_FALLBACK)
+		return err
+	}
+
+	// each renewal rotates the refresh token, the other connections must get the new one
+	for round, conn := range connections {
+		server.expire()
+		if err := send(conn); err != nil {
+			t.Fatalf("round %d: unexpected error: %v", round, err)
+		}
+	}
+	// every connection expires at once, one refresh renews them all
+	server.expire()
+	var wg sync.WaitGroup
+	for _, conn := range connections {
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(conn *Connection) {
+				defer wg.Done()
+				if err := send(conn); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}(conn)
+		}
+	}
+	wg.Wait()
+
+	if server.refreshes != 4 {
+		t.Errorf("refreshes = %d, want 4", server.refreshes)
+	}
+	for _, conn := range append(connections, c.leaderConn) {
+		if got := conn.token().Refresh; got != "refresh-4" {
+			t.Errorf("connection %p refresh token = %q, want refresh-4", conn, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	orm "github.com/medatechnology/simpleorm"
+)
+
+//------------------------------------------------------------------
+// SERVER BACKPRESSURE (429 / 503)
+//------------------------------------------------------------------
+
+// ErrServerThrottled is wrapped by ThrottledError, use errors.Is to detect an overloaded server
+var ErrServerThrottled = errors.New("server is throttling requests")
+
+// ThrottledError is returned when the server answers 429 Too Many Requests or 503 Service Unavailable.
+// RetryAfter is the delay asked by the Retry-After header, 0 if there was none.
+type ThrottledError struct {
+	Status     int
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e *ThrottledError) Error() string {
+	msg := fmt.Sprintf("request error: HTTP %d %s", e.Status, http.StatusText(e.Status))
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(" (retry after %s)", e.RetryAfter)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrServerThrottled
+}
+
+// throttledError returns a *ThrottledError for a 429/503 response, nil for any other response
+func throttledError(resp *http.Response, body []byte) error {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil
+	}
+	// the server's own 429/503 has a StandardResponse body, a proxy's has anything
+	message := bodySnippet(body)
+	var standard rawStandardResponse
+	if json.Unmarshal(body, &standard) == nil && standard.Message != "" {
+		message = standard.Message
+	}
+	return &ThrottledError{
+		Status:     resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		Message:    message,
+	}
+}
+
+// parseRetryAfter reads a Retry-After header, in seconds or as an HTTP date. Returns 0 if missing or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
+
+// throttleBackoff returns how long to wait before retry attempt (0 based) of a throttled request:
+// the server's Retry-After if given, otherwise an exponential backoff, both capped at DEFAULT_MAX_THROTTLE_BACKOFF
+func throttleBackoff(throttled *ThrottledError, attempt int) time.Duration {
+	wait := throttled.RetryAfter
+	if wait <= 0 {
+		wait = DEFAULT_THROTTLE_BACKOFF << min(attempt, 16)
+	}
+	return min(wait, DEFAULT_MAX_THROTTLE_BACKOFF)
+}
+
+// InsertManyInChunks inserts records in chunks of chunkSize (all in one request if 0) with
+// InsertManyDBRecords. When the server is overloaded (429/503) the chunk is retried after the
+// Retry-After delay (or an exponential backoff), up to DEFAULT_THROTTLE_RETRIES times, and once it
+// went through the remaining chunks continue. Any other error (or too many retries) stops the insert,
+// it returns how many records were persisted and the results of the chunks sent, so the caller can
+// resume with records[inserted:].
+// Usage:
+//
+//	inserted, results, err := db.InsertManyInChunks(ctx, records, 500, false)
+//	if err != nil {
+//		// records[inserted:] were not inserted
+//	}
+func (c *Client) InsertManyInChunks(ctx context.Context, records []orm.DBRecord, chunkSize int, queue bool) (int, []orm.BasicSQLResult, error) {
+	if chunkSize <= 0 {
+		chunkSize = len(records)
+	}
+	inserted := 0
+	var results []orm.BasicSQLResult
+	for inserted < len(records) {
+		chunk := records[inserted:min(inserted+chunkSize, len(records))]
+		chunkResults, err := c.insertChunk(ctx, chunk, queue)
+		if err != nil {
+			return inserted, results, fmt.Errorf("insert stopped after %d of %d records: %w", inserted, len(records), err)
+		}
+		results = append(results, chunkResults...)
+		inserted += len(chunk)
+	}
+	return inserted, results, nil
+}
+
+// insertChunk sends one chunk, retrying while the server is throttling
+func (c *Client) insertChunk(ctx context.Context, chunk []orm.DBRecord, queue bool) ([]orm.BasicSQLResult, error) {
+	for attempt := 0; ; attempt++ {
+		results, err := c.InsertManyDBRecords(chunk, queue)
+		var throttled *ThrottledError
+		if err == nil || !errors.As(err, &throttled) || attempt >= DEFAULT_THROTTLE_RETRIES {
+			return results, err
+		}
+		timer := time.NewTimer(throttleBackoff(throttled, attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
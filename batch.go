@@ -0,0 +1,135 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/medatechnology/goutil/object"
+	orm "github.com/medatechnology/simpleorm"
+	"github.com/medatechnology/suresql"
+)
+
+//------------------------------------------------------------------
+// QUERY BATCH
+//------------------------------------------------------------------
+
+// QueryBatch collects independent queries, sends them in a single /db/api/querysql request
+// and scatters the results into their targets in order.
+// Usage:
+//
+//	var users orm.DBRecords
+//	var total orm.DBRecord
+//	var products []ProductModel
+//	batch := db.NewQueryBatch().
+//	  Add(orm.ParametereizedSQL{Query: "SELECT * FROM users LIMIT 10"}, &users).
+//	  AddOne(orm.ParametereizedSQL{Query: "SELECT COUNT(*) AS total FROM users"}, &total)
+//	client.BatchInto(batch, orm.ParametereizedSQL{Query: "SELECT * FROM products WHERE id = ?", Values: []interface{}{1}}, &products)
+//	err := batch.Execute()
+type QueryBatch struct {
+	client  *Client
+	queries []orm.ParametereizedSQL
+	targets []func(records orm.DBRecords) error
+}
+
+// BatchError holds the errors of a QueryBatch by position, nil for the queries that succeeded
+type BatchError struct {
+	Errors []error
+}
+
+func (e *BatchError) Error() string {
+	var msgs []string
+	for i, err := range e.Errors {
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("query %d: %v", i, err))
+		}
+	}
+	return "batch failed: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap allows errors.Is/As on the errors of each position
+func (e *BatchError) Unwrap() []error {
+	return e.Errors
+}
+
+// NewQueryBatch creates an empty batch
+func (c *Client) NewQueryBatch() *QueryBatch {
+	return &QueryBatch{client: c}
+}
+
+// add queues a query and the function that stores its records
+func (b *QueryBatch) add(paramSQL orm.ParametereizedSQL, target func(records orm.DBRecords) error) *QueryBatch {
+	b.queries = append(b.queries, paramSQL)
+	b.targets = append(b.targets, target)
+	return b
+}
+
+// Len returns the number of queries in the batch
+func (b *QueryBatch) Len() int {
+	return len(b.queries)
+}
+
+// Add queues a query whose rows are stored in target (empty if no rows)
+func (b *QueryBatch) Add(paramSQL orm.ParametereizedSQL, target *orm.DBRecords) *QueryBatch {
+	return b.add(paramSQL, func(records orm.DBRecords) error {
+		*target = records
+		return nil
+	})
+}
+
+// AddOne queues a query that must return exactly one row, stored in target.
+// Its position gets orm.ErrSQLNoRows or orm.ErrSQLMoreThanOneRow otherwise.
+func (b *QueryBatch) AddOne(paramSQL orm.ParametereizedSQL, target *orm.DBRecord) *QueryBatch {
+	return b.add(paramSQL, func(records orm.DBRecords) error {
+		if len(records) == 0 {
+			return orm.ErrSQLNoRows
+		}
+		if len(records) > 1 {
+			return orm.ErrSQLMoreThanOneRow
+		}
+		*target = records[0]
+		return nil
+	})
+}
+
+// BatchInto queues a query whose rows are scanned into a slice of T using the `db` tags of T
+func BatchInto[T any](b *QueryBatch, paramSQL orm.ParametereizedSQL, target *[]T) *QueryBatch {
+	return b.add(paramSQL, func(records orm.DBRecords) error {
+		result := make([]T, 0, len(records))
+		for _, rec := range records {
+			result = append(result, object.MapToStructSlowDB[T](rec.Data))
+		}
+		*target = result
+		return nil
+	})
+}
+
+// Execute sends all queries in one request. It returns the request error if the request failed
+// as a whole, or a *BatchError if some of the positions failed.
+func (b *QueryBatch) Execute() error {
+	if len(b.queries) == 0 {
+		return nil
+	}
+	req := &suresql.SQLRequest{
+		ParamSQL:  b.queries,
+		SingleRow: false,
+	}
+	response, err := sendRequest[suresql.QueryResponseSQL](b.client, "POST", "/db/api/querysql", req, RequestTypeSQLQuery, AUTO_REFRESH, FALLBACK_LEADER)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, len(b.queries))
+	failed := false
+	for i, target := range b.targets {
+		if i >= len(response) {
+			errs[i] = fmt.Errorf("no result returned (got %d results for %d queries)", len(response), len(b.queries))
+		} else {
+			errs[i] = target(response[i].Records)
+		}
+		failed = failed || errs[i] != nil
+	}
+	if failed {
+		return &BatchError{Errors: errs}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+//------------------------------------------------------------------
+// PER-NODE IN-FLIGHT LIMIT (BULKHEAD)
+//------------------------------------------------------------------
+
+// errNodesAtCapacity is returned when every candidate node already has MaxInFlightPerNode requests
+var errNodesAtCapacity = errors.New("all nodes are at their maximum in-flight requests")
+
+// bulkhead limits the number of in-flight requests per node (read and write combined),
+// so a single slow node can't hold all the client's goroutines
+type bulkhead struct {
+	mutex    sync.Mutex
+	limit    int
+	inFlight map[string]int
+	rejected map[string]int64
+	queued   map[string]int64
+	released chan struct{} // closed and replaced on every release to wake up waiters
+	fifo     bool          // hand released slots to the waiters in arrival order
+	waiters  []*slotWaiter
+}
+
+// slotWaiter is a request waiting in the FIFO queue for a slot on any of its nodes
+type slotWaiter struct {
+	nodes   []string
+	granted chan string // receives the node whose slot was handed over
+}
+
+func (w *slotWaiter) accepts(nodeID string) bool {
+	for _, n := range w.nodes {
+		if n == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// newBulkhead returns nil (no limit) if limit <= 0
+func newBulkhead(limit int, fifo bool) *bulkhead {
+	if limit <= 0 {
+		return nil
+	}
+	return &bulkhead{
+		fifo:     fifo,
+		limit:    limit,
+		inFlight: make(map[string]int),
+		rejected: make(map[string]int64),
+		queued:   make(map[string]int64),
+		released: make(chan struct{}),
+	}
+}
+
+// tryAcquire takes a slot of the node, it counts a rejection if the node is full
+func (b *bulkhead) tryAcquire(nodeID string) bool {
+	if b == nil {
+		return true
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.inFlight[nodeID] >= b.limit {
+		b.rejected[nodeID]++
+		return false
+	}
+	b.inFlight[nodeID]++
+	return true
+}
+
+// release gives back a slot of the node and wakes up the waiters
+func (b *bulkhead) release(nodeID string) {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	// the slot goes straight to the oldest waiter that can use it, so newer requests can't take it
+	for i, w := range b.waiters {
+		if w.accepts(nodeID) {
+			b.waiters = append(b.waiters[:i], b.waiters[i+1:]...)
+			w.granted <- nodeID
+			return
+		}
+	}
+	if b.inFlight[nodeID] > 0 {
+		b.inFlight[nodeID]--
+	}
+	close(b.released)
+	b.released = make(chan struct{})
+}
+
+// queue counts a request waiting for the node and returns the channel closed on the next release
+func (b *bulkhead) queue(nodeID string) <-chan struct{} {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.queued[nodeID]++
+	return b.released
+}
+
+// wait queues the request until a slot of one of nodes is free and returns that node, the slot
+// is already taken for the caller. Waiters are served in arrival order. firstNode is only used
+// to count the queued request.
+func (b *bulkhead) wait(ctx context.Context, nodes []string, firstNode string) (string, error) {
+	b.mutex.Lock()
+	b.queued[firstNode]++
+	// a slot might have been released since the caller failed to get one
+	for _, nodeID := range nodes {
+		if b.inFlight[nodeID] < b.limit {
+			b.inFlight[nodeID]++
+			b.mutex.Unlock()
+			return nodeID, nil
+		}
+	}
+	w := &slotWaiter{nodes: nodes, granted: make(chan string, 1)}
+	b.waiters = append(b.waiters, w)
+	b.mutex.Unlock()
+
+	select {
+	case nodeID := <-w.granted:
+		return nodeID, nil
+	case <-ctx.Done():
+		b.mutex.Lock()
+		for i, other := range b.waiters {
+			if other == w {
+				b.waiters = append(b.waiters[:i], b.waiters[i+1:]...)
+				b.mutex.Unlock()
+				return "", ctx.Err()
+			}
+		}
+		b.mutex.Unlock()
+		// the slot was handed over while cancelling, pass it on
+		b.release(<-w.granted)
+		return "", ctx.Err()
+	}
+}
+
+// waiting returns the number of requests in the FIFO queue
+func (b *bulkhead) waiting() int {
+	if b == nil {
+		return 0
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return len(b.waiters)
+}
+
+// stats returns the in-flight, rejected and queued counts of the node
+func (b *bulkhead) stats(nodeID string) (int, int64, int64) {
+	if b == nil {
+		return 0, 0, 0
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.inFlight[nodeID], b.rejected[nodeID], b.queued[nodeID]
+}
+
+// pickConnection gets a connection from the pool whose node is below MaxInFlightPerNode, trying
+// preferredNode first (if any) then every node once in round-robin order
+func (c *Client) pickConnection(pool *ConnectionPool, preferredNode string) (*Connection, error) {
+	if preferredNode != "" && !pool.IsDraining(preferredNode) {
+		if conn, err := pool.GetConnectionForNode(preferredNode); err == nil && c.bulkhead.tryAcquire(conn.NodeID) {
+			return conn, nil
+		}
+	}
+	attempts := max(1, pool.NodeCount())
+	for i := 0; i < attempts; i++ {
+		conn, err := pool.GetConnection()
+		if err != nil {
+			return nil, err
+		}
+		if c.bulkhead.tryAcquire(conn.NodeID) {
+			return conn, nil
+		}
+	}
+	return nil, errNodesAtCapacity
+}
@@ -0,0 +1,231 @@
+package client
+
+import (
+	"container/list"
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/medatechnology/suresql"
+)
+
+//------------------------------------------------------------------
+// CLIENT-SIDE READ CACHE
+//------------------------------------------------------------------
+
+// readCache is a size bounded (least recently used is evicted) cache of SQL query responses
+// with a TTL. A nil readCache is disabled, all methods are no-ops.
+type readCache struct {
+	mutex   sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element // key -> element of order
+	order   *list.List               // most recently used first
+}
+
+type readCacheEntry struct {
+	key     string
+	raw     json.RawMessage
+	tables  []string // tables read by the query, lower case
+	expires time.Time
+}
+
+// newReadCache returns nil (disabled) if size or ttl is not set
+func newReadCache(size int, ttl time.Duration) *readCache {
+	if size <= 0 || ttl <= 0 {
+		return nil
+	}
+	return &readCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (rc *readCache) get(key string) (json.RawMessage, bool) {
+	if rc == nil {
+		return nil, false
+	}
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	elem, exists := rc.entries[key]
+	if !exists {
+		return nil, false
+	}
+	entry := elem.Value.(*readCacheEntry)
+	if time.Now().After(entry.expires) {
+		rc.remove(elem)
+		return nil, false
+	}
+	rc.order.MoveToFront(elem)
+	return entry.raw, true
+}
+
+func (rc *readCache) put(key string, raw json.RawMessage, tables []string) {
+	if rc == nil {
+		return
+	}
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	entry := &readCacheEntry{key: key, raw: raw, tables: tables, expires: time.Now().Add(rc.ttl)}
+	if elem, exists := rc.entries[key]; exists {
+		elem.Value = entry
+		rc.order.MoveToFront(elem)
+		return
+	}
+	rc.entries[key] = rc.order.PushFront(entry)
+	for rc.order.Len() > rc.size {
+		rc.remove(rc.order.Back())
+	}
+}
+
+// remove must be called with the mutex held
+func (rc *readCache) remove(elem *list.Element) {
+	rc.order.Remove(elem)
+	delete(rc.entries, elem.Value.(*readCacheEntry).key)
+}
+
+// invalidate removes the entries reading a table matching the pattern (path.Match syntax,
+// case insensitive), or all entries if pattern is "" or "*". Entries whose tables could not
+// be parsed are always removed. Returns the number of entries removed.
+func (rc *readCache) invalidate(pattern string) int {
+	if rc == nil {
+		return 0
+	}
+	pattern = strings.ToLower(pattern)
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	removed := 0
+	for elem := rc.order.Front(); elem != nil; {
+		next := elem.Next()
+		if pattern == "" || pattern == "*" || readsTableMatching(elem.Value.(*readCacheEntry).tables, pattern) {
+			rc.remove(elem)
+			removed++
+		}
+		elem = next
+	}
+	return removed
+}
+
+func readsTableMatching(tables []string, pattern string) bool {
+	if len(tables) == 0 {
+		return true
+	}
+	for _, table := range tables {
+		if matched, _ := path.Match(pattern, table); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidateWrite removes the entries of the tables written by body, everything if the
+// written tables cannot be determined
+func (rc *readCache) invalidateWrite(body interface{}) {
+	if rc == nil {
+		return
+	}
+	var tables []string
+	switch req := body.(type) {
+	case *suresql.SQLRequest:
+		for _, query := range requestQueries(req) {
+			written := writtenTables(query)
+			if len(written) == 0 {
+				rc.invalidate("")
+				return
+			}
+			tables = append(tables, written...)
+		}
+	case *suresql.InsertRequest:
+		for _, rec := range req.Records {
+			if rec.TableName == "" {
+				rc.invalidate("")
+				return
+			}
+			tables = append(tables, strings.ToLower(rec.TableName))
+		}
+	default:
+		rc.invalidate("")
+		return
+	}
+	for _, table := range tables {
+		rc.invalidate(table)
+	}
+}
+
+func requestQueries(req *suresql.SQLRequest) []string {
+	queries := append([]string(nil), req.Statements...)
+	for _, paramSQL := range req.ParamSQL {
+		queries = append(queries, paramSQL.Query)
+	}
+	return queries
+}
+
+var (
+	// best-effort, quoted identifiers and schema prefixes are handled, CTEs and sub-queries are not
+	writeTableRegex = regexp.MustCompile(`(?i)\b(?:INSERT(?:\s+OR\s+\w+)?\s+INTO|REPLACE\s+INTO|UPDATE(?:\s+OR\s+\w+)?|DELETE\s+FROM|(?:DROP|ALTER|CREATE)\s+TABLE(?:\s+IF(?:\s+NOT)?\s+EXISTS)?)\s+([\w."\x60\[\]]+)`)
+	readTableRegex  = regexp.MustCompile(`(?i)\b(?:FROM|JOIN)\s+([\w."\x60\[\]]+)`)
+)
+
+// writtenTables returns the tables written by a statement, lower case without quotes
+func writtenTables(query string) []string {
+	return matchTables(writeTableRegex, query)
+}
+
+// readTables returns the tables read by a query, lower case without quotes
+func readTables(query string) []string {
+	return matchTables(readTableRegex, query)
+}
+
+func matchTables(re *regexp.Regexp, query string) []string {
+	var tables []string
+	for _, match := range re.FindAllStringSubmatch(query, -1) {
+		name := match[1]
+		if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+			name = name[dot+1:]
+		}
+		name = strings.ToLower(unquoteIdentifier(name))
+		if name != "" {
+			tables = append(tables, name)
+		}
+	}
+	return tables
+}
+
+// cachedRead returns the cached response of a SQL query, or the key to cache its response under
+func (c *Client) cachedRead(endpoint string, body interface{}, isWrite bool) (json.RawMessage, string) {
+	if isWrite || c.readCache == nil || endpoint != "/db/api/querysql" {
+		return nil, ""
+	}
+	key, ok := c.readKey(endpoint, body)
+	if !ok {
+		return nil, ""
+	}
+	if raw, hit := c.readCache.get(key); hit {
+		return raw, ""
+	}
+	return nil, key
+}
+
+// cacheRead stores the response of a SQL query under key
+func (c *Client) cacheRead(key string, body interface{}, raw json.RawMessage) {
+	var tables []string
+	if req, ok := body.(*suresql.SQLRequest); ok {
+		for _, query := range requestQueries(req) {
+			tables = append(tables, readTables(query)...)
+		}
+	}
+	c.readCache.put(key, raw, tables)
+}
+
+// InvalidateCache removes the cached reads of the tables matching pattern (path.Match syntax,
+// ie: "users" or "audit_*"), "" or "*" clears the whole cache. Returns the number of entries removed.
+// Writes through this client already invalidate the tables they touch (best-effort, a statement
+// whose table cannot be parsed clears the whole cache), call this after writes made elsewhere.
+func (c *Client) InvalidateCache(pattern string) int {
+	return c.readCache.invalidate(pattern)
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFailedWriteInvalidatesCache(t *testing.T) {
+	var reads int64
+	c := newStubClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/db/api/querysql":
+			atomic.AddInt64(&reads, 1)
+			return okResponse(req, `[{"records":[{"TableName":"users","Data":{"id":1}}]}]`), nil
+		case "/db/api/sql":
+			// the server may have applied the write before failing
+			return stubResponse(req, http.StatusInternalServerError, "application/json", `{"status":500,"message":"write timed out"}`), nil
+		}
+		return stubResponse(req, http.StatusNotFound, "text/plain", "not found"), nil
+	}))
+	c.readCache = newReadCache(10, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.SelectOneSQL("SELECT * FROM users"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt64(&reads); got != 1 {
+		t.Fatalf("reads = %d, want the second read served from the cache", got)
+	}
+
+	if res := c.ExecOneSQL("UPDATE users SET name = 'x'"); res.Error == nil {
+		t.Fatal("expected the write to fail")
+	}
+	if _, err := c.SelectOneSQL("SELECT * FROM users"); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt64(&reads); got != 2 {
+		t.Errorf("reads = %d, want the failed write to invalidate the cached read", got)
+	}
+}
@@ -0,0 +1,295 @@
+package client
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	orm "github.com/medatechnology/simpleorm"
+	"github.com/medatechnology/suresql"
+)
+
+//------------------------------------------------------------------
+// SCHEMA TYPE COERCION
+//------------------------------------------------------------------
+
+// timestampLayouts are the formats tried when coercing TIMESTAMP/DATETIME/DATE columns
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// RefreshSchemaCache reloads the column types used by SchemaTypeCoercion, call it after
+// creating or altering tables
+func (c *Client) RefreshSchemaCache() {
+	types := make(map[string]map[string]string)
+	for _, item := range c.GetSchema(false, false) {
+		if !strings.EqualFold(item.ObjectType, "table") {
+			continue
+		}
+		columns, err := ParseCreateTable(item.SQLCommand)
+		if err != nil {
+			continue
+		}
+		columnTypes := make(map[string]string, len(columns))
+		for _, col := range columns {
+			columnTypes[strings.ToLower(col.Name)] = col.Type
+		}
+		types[strings.ToLower(item.ObjectName)] = columnTypes
+	}
+
+	c.schemaMutex.Lock()
+	c.schemaTypes = types
+	c.schemaMutex.Unlock()
+}
+
+// columnTypes returns the declared column types of the table, the schema is loaded on first use
+func (c *Client) columnTypes(tableName string) map[string]string {
+	c.schemaMutex.RLock()
+	loaded := c.schemaTypes != nil
+	types := c.schemaTypes[strings.ToLower(tableName)]
+	c.schemaMutex.RUnlock()
+	if loaded {
+		return types
+	}
+
+	c.RefreshSchemaCache()
+	c.schemaMutex.RLock()
+	defer c.schemaMutex.RUnlock()
+	return c.schemaTypes[strings.ToLower(tableName)]
+}
+
+// coerceResponse converts the record values of query responses to their declared column types.
+// The table comes from the record itself or, for /db/api/query, from the request.
+func (c *Client) coerceResponse(resp interface{}, body interface{}) {
+	tableName := ""
+	if req, ok := body.(*suresql.QueryRequest); ok {
+		tableName = req.Table
+	}
+	switch r := resp.(type) {
+	case *suresql.QueryResponse:
+		c.coerceRecords(r.Records, tableName)
+	case *suresql.QueryResponseSQL:
+		for _, q := range *r {
+			c.coerceRecords(q.Records, tableName)
+		}
+	}
+}
+
+// coerceRecords converts the values in place, records of unknown tables are left as is
+func (c *Client) coerceRecords(records []orm.DBRecord, tableName string) {
+	for _, rec := range records {
+		table := rec.TableName
+		if table == "" {
+			table = tableName
+		}
+		if table == "" {
+			continue
+		}
+		types := c.columnTypes(table)
+		if len(types) == 0 {
+			continue
+		}
+		for col, val := range rec.Data {
+			if declared, exists := types[strings.ToLower(col)]; exists {
+				rec.Data[col] = coerceValue(val, declared, c.Config.timeFormat())
+			}
+		}
+	}
+}
+
+// coerceValue converts a JSON decoded value to the Go type of the declared SQL type:
+// int64 for INTEGER, bool for BOOLEAN, time.Time for TIMESTAMP/DATETIME/DATE (parsed with
+// timeLayout first, then timestampLayouts). Values that don't convert cleanly are returned unchanged.
+func coerceValue(val interface{}, declared string, timeLayout string) interface{} {
+	switch {
+	case strings.Contains(declared, "BOOL"):
+		switch v := val.(type) {
+		case float64:
+			if v == 0 || v == 1 {
+				return v == 1
+			}
+		case string:
+			switch strings.ToLower(v) {
+			case "1", "true", "t":
+				return true
+			case "0", "false", "f":
+				return false
+			}
+		}
+	case strings.Contains(declared, "INT"):
+		if v, ok := val.(float64); ok && v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+	case strings.Contains(declared, "TIMESTAMP"), strings.Contains(declared, "DATE"):
+		if v, ok := val.(string); ok {
+			if t, err := time.Parse(timeLayout, v); err == nil {
+				return t
+			}
+			for _, layout := range timestampLayouts {
+				if t, err := time.Parse(layout, v); err == nil {
+					return t
+				}
+			}
+		}
+	}
+	return val
+}
+
+//------------------------------------------------------------------
+// TIME PARAMETER BINDING
+//------------------------------------------------------------------
+
+// timeFormat returns the layout time.Time parameters are sent with, DEFAULT_TIME_FORMAT if not set
+func (config *ClientConfig) timeFormat() string {
+	if config.TimeFormat == "" {
+		return DEFAULT_TIME_FORMAT
+	}
+	return config.TimeFormat
+}
+
+// bindTimes returns the request body with every time.Time (or *time.Time) formatted with the
+// TimeFormat: the values of parameterized SQL, the data of inserted records and the values of
+// query conditions. The body is only copied when it has time values, the caller's request is
+// never modified.
+func (config *ClientConfig) bindTimes(data interface{}) interface{} {
+	layout := config.timeFormat()
+	switch req := data.(type) {
+	case *suresql.SQLRequest:
+		var paramSQL []orm.ParametereizedSQL
+		for i, param := range req.ParamSQL {
+			values, changed := bindTimeValues(param.Values, layout)
+			if !changed {
+				continue
+			}
+			if paramSQL == nil {
+				paramSQL = append([]orm.ParametereizedSQL(nil), req.ParamSQL...)
+			}
+			paramSQL[i] = orm.ParametereizedSQL{Query: param.Query, Values: values}
+		}
+		if paramSQL != nil {
+			bound := *req
+			bound.ParamSQL = paramSQL
+			return &bound
+		}
+	case *suresql.InsertRequest:
+		var records []orm.DBRecord
+		for i, rec := range req.Records {
+			values, changed := bindTimeData(rec.Data, layout)
+			if !changed {
+				continue
+			}
+			if records == nil {
+				records = append([]orm.DBRecord(nil), req.Records...)
+			}
+			records[i] = orm.DBRecord{TableName: rec.TableName, Data: values}
+		}
+		if records != nil {
+			bound := *req
+			bound.Records = records
+			return &bound
+		}
+	case *suresql.QueryRequest:
+		if condition, changed := bindTimeCondition(req.Condition, layout); changed {
+			bound := *req
+			bound.Condition = condition
+			return &bound
+		}
+	}
+	return data
+}
+
+// bindTimeValue formats a time.Time, a non nil *time.Time or a slice of them (ie: for IN) with
+// layout. Returns false and the value unchanged for anything else.
+func bindTimeValue(value interface{}, layout string) (interface{}, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(layout), true
+	case *time.Time:
+		if v != nil {
+			return v.Format(layout), true
+		}
+	case []time.Time:
+		formatted := make([]string, len(v))
+		for i, t := range v {
+			formatted[i] = t.Format(layout)
+		}
+		return formatted, true
+	case []interface{}:
+		return bindTimeValues(v, layout)
+	}
+	return value, false
+}
+
+// bindTimeValues formats the time values of the slice, which is copied only if it has any
+func bindTimeValues(values []interface{}, layout string) ([]interface{}, bool) {
+	var bound []interface{}
+	for i, value := range values {
+		formatted, changed := bindTimeValue(value, layout)
+		if !changed {
+			continue
+		}
+		if bound == nil {
+			bound = append([]interface{}(nil), values...)
+		}
+		bound[i] = formatted
+	}
+	if bound == nil {
+		return values, false
+	}
+	return bound, true
+}
+
+// bindTimeData formats the time values of the record data, which is copied only if it has any
+func bindTimeData(data map[string]interface{}, layout string) (map[string]interface{}, bool) {
+	var bound map[string]interface{}
+	for key, value := range data {
+		formatted, changed := bindTimeValue(value, layout)
+		if !changed {
+			continue
+		}
+		if bound == nil {
+			bound = make(map[string]interface{}, len(data))
+			for k, v := range data {
+				bound[k] = v
+			}
+		}
+		bound[key] = formatted
+	}
+	if bound == nil {
+		return data, false
+	}
+	return bound, true
+}
+
+// bindTimeCondition formats the time values of the condition and its nested conditions, which are
+// copied only if they have any
+func bindTimeCondition(condition *orm.Condition, layout string) (*orm.Condition, bool) {
+	if condition == nil {
+		return nil, false
+	}
+	value, changed := bindTimeValue(condition.Value, layout)
+	var nested []orm.Condition
+	for i := range condition.Nested {
+		boundNested, nestedChanged := bindTimeCondition(&condition.Nested[i], layout)
+		if !nestedChanged {
+			continue
+		}
+		if nested == nil {
+			nested = append([]orm.Condition(nil), condition.Nested...)
+		}
+		nested[i] = *boundNested
+	}
+	if !changed && nested == nil {
+		return condition, false
+	}
+	bound := *condition
+	bound.Value = value
+	if nested != nil {
+		bound.Nested = nested
+	}
+	return &bound, true
+}
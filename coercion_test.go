@@ -0,0 +1,69 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	orm "github.com/medatechnology/simpleorm"
+	"github.com/medatechnology/suresql"
+)
+
+func TestTimeParameterRoundTrip(t *testing.T) {
+	const layout = "2006-01-02 15:04:05"
+	happenedAt := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+
+	// a fake server keeping the value of the last insert and returning it on select
+	var mutex sync.Mutex
+	var stored interface{}
+	c := newStubClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch req.URL.Path {
+		case "/db/api/sql":
+			var body suresql.SQLRequest
+			raw, _ := io.ReadAll(req.Body)
+			if err := json.Unmarshal(raw, &body); err != nil {
+				return nil, err
+			}
+			stored = body.ParamSQL[0].Values[0]
+			return okResponse(req, `{"results":[{}],"rows_affected":1}`), nil
+		case "/db/api/querysql":
+			records, _ := json.Marshal(suresql.QueryResponseSQL{{Records: orm.DBRecords{
+				{TableName: "events", Data: map[string]interface{}{"happened_at": stored}},
+			}}})
+			return okResponse(req, string(records)), nil
+		}
+		return stubResponse(req, http.StatusNotFound, "application/json", `{"status":404,"message":"not found"}`), nil
+	}))
+	c.Config.TimeFormat = layout
+	c.Config.SchemaTypeCoercion = true
+	c.schemaTypes = map[string]map[string]string{"events": {"happened_at": "DATETIME"}}
+
+	params := []interface{}{happenedAt}
+	res := c.ExecOneSQLParameterized(orm.ParametereizedSQL{Query: "INSERT INTO events (happened_at) VALUES (?)", Values: params})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if stored != "2024-03-05 14:30:00" {
+		t.Errorf("server received %v, want the time in TimeFormat", stored)
+	}
+	if _, ok := params[0].(time.Time); !ok {
+		t.Errorf("the caller's values were modified: %T", params[0])
+	}
+
+	records, err := c.SelectOneSQL("SELECT happened_at FROM events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := records[0].Data["happened_at"].(time.Time)
+	if !ok {
+		t.Fatalf("happened_at is %T, want time.Time", records[0].Data["happened_at"])
+	}
+	if !got.Equal(happenedAt) {
+		t.Errorf("happened_at = %s, want %s", got, happenedAt)
+	}
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"strings"
+
+	orm "github.com/medatechnology/simpleorm"
+)
+
+//------------------------------------------------------------------
+// SQL COMMENTS / QUERY HINTS
+//------------------------------------------------------------------
+
+// CommentedClient runs raw SQL methods with a /* comment */ prepended to every statement,
+// so the statements can be traced back to the code path in the server's query log.
+// It shares everything (pools, tokens, config) with the Client it was created from.
+//
+// Only the raw SQL methods below are tagged. The condition and ORM methods (SelectManyWithCondition,
+// Insert*, Update*, Delete*, ...) are not: most of them send a table and records or a condition, and
+// the server builds the SQL itself, so there is no client-side SQL to put the comment on.
+type CommentedClient struct {
+	client *Client
+	prefix string
+}
+
+// WithComment returns a CommentedClient that tags the SQL of the next calls with comment.
+// Usage:
+//
+//	db.WithComment("billing:monthly-report").SelectOneSQL("SELECT ...")
+func (c *Client) WithComment(comment string) *CommentedClient {
+	prefix := ""
+	if comment = SanitizeSQLComment(comment); comment != "" {
+		prefix = "/* " + comment + " */ "
+	}
+	return &CommentedClient{client: c, prefix: prefix}
+}
+
+// SanitizeSQLComment makes comment safe to put inside /* */ so it can't close the comment
+// early (*/ injection) or open a nested one
+func SanitizeSQLComment(comment string) string {
+	comment = strings.ReplaceAll(comment, "\x00", "")
+	for strings.Contains(comment, "*/") || strings.Contains(comment, "/*") {
+		comment = strings.ReplaceAll(comment, "*/", "* /")
+		comment = strings.ReplaceAll(comment, "/*", "/ *")
+	}
+	return strings.TrimSpace(comment)
+}
+
+func (cc *CommentedClient) sql(sql string) string {
+	return cc.prefix + sql
+}
+
+func (cc *CommentedClient) sqls(sqlStatements []string) []string {
+	result := make([]string, len(sqlStatements))
+	for i, sql := range sqlStatements {
+		result[i] = cc.prefix + sql
+	}
+	return result
+}
+
+func (cc *CommentedClient) paramSQL(paramSQL orm.ParametereizedSQL) orm.ParametereizedSQL {
+	paramSQL.Query = cc.prefix + paramSQL.Query
+	return paramSQL
+}
+
+func (cc *CommentedClient) paramSQLs(paramSQLs []orm.ParametereizedSQL) []orm.ParametereizedSQL {
+	result := make([]orm.ParametereizedSQL, len(paramSQLs))
+	for i, paramSQL := range paramSQLs {
+		result[i] = cc.paramSQL(paramSQL)
+	}
+	return result
+}
+
+// SelectOneSQL is Client.SelectOneSQL with the comment
+func (cc *CommentedClient) SelectOneSQL(sql string) (orm.DBRecords, error) {
+	return cc.client.SelectOneSQL(cc.sql(sql))
+}
+
+// SelectManySQL is Client.SelectManySQL with the comment
+func (cc *CommentedClient) SelectManySQL(sqlStatements []string) ([]orm.DBRecords, error) {
+	return cc.client.SelectManySQL(cc.sqls(sqlStatements))
+}
+
+// SelectOnlyOneSQL is Client.SelectOnlyOneSQL with the comment
+func (cc *CommentedClient) SelectOnlyOneSQL(sql string) (orm.DBRecord, error) {
+	return cc.client.SelectOnlyOneSQL(cc.sql(sql))
+}
+
+// SelectOneSQLParameterized is Client.SelectOneSQLParameterized with the comment
+func (cc *CommentedClient) SelectOneSQLParameterized(paramSQL orm.ParametereizedSQL) (orm.DBRecords, error) {
+	return cc.client.SelectOneSQLParameterized(cc.paramSQL(paramSQL))
+}
+
+// SelectManySQLParameterized is Client.SelectManySQLParameterized with the comment
+func (cc *CommentedClient) SelectManySQLParameterized(paramSQLs []orm.ParametereizedSQL) ([]orm.DBRecords, error) {
+	return cc.client.SelectManySQLParameterized(cc.paramSQLs(paramSQLs))
+}
+
+// SelectOnlyOneSQLParameterized is Client.SelectOnlyOneSQLParameterized with the comment
+func (cc *CommentedClient) SelectOnlyOneSQLParameterized(paramSQL orm.ParametereizedSQL) (orm.DBRecord, error) {
+	return cc.client.SelectOnlyOneSQLParameterized(cc.paramSQL(paramSQL))
+}
+
+// ExecOneSQL is Client.ExecOneSQL with the comment
+func (cc *CommentedClient) ExecOneSQL(sql string) orm.BasicSQLResult {
+	return cc.client.ExecOneSQL(cc.sql(sql))
+}
+
+// ExecOneSQLParameterized is Client.ExecOneSQLParameterized with the comment
+func (cc *CommentedClient) ExecOneSQLParameterized(paramSQL orm.ParametereizedSQL) orm.BasicSQLResult {
+	return cc.client.ExecOneSQLParameterized(cc.paramSQL(paramSQL))
+}
+
+// ExecManySQL is Client.ExecManySQL with the comment
+func (cc *CommentedClient) ExecManySQL(sqlStatements []string) ([]orm.BasicSQLResult, error) {
+	return cc.client.ExecManySQL(cc.sqls(sqlStatements))
+}
+
+// ExecManySQLParameterized is Client.ExecManySQLParameterized with the comment
+func (cc *CommentedClient) ExecManySQLParameterized(paramSQLs []orm.ParametereizedSQL) ([]orm.BasicSQLResult, error) {
+	return cc.client.ExecManySQLParameterized(cc.paramSQLs(paramSQLs))
+}
@@ -0,0 +1,305 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	orm "github.com/medatechnology/simpleorm"
+)
+
+//------------------------------------------------------------------
+// CONDITION TRANSLATION
+//------------------------------------------------------------------
+
+// ConditionToWhere converts a condition into a WHERE clause (without the WHERE keyword) and its values,
+// like orm.Condition.ToWhereString, with support for operators that don't take a single placeholder:
+//   - "IN" / "NOT IN": Value is a slice, one ? per element. An empty IN matches nothing (1=0)
+//     and an empty NOT IN matches everything (1=1).
+//   - "BETWEEN" / "NOT BETWEEN": Value is a two element slice, producing "field BETWEEN ? AND ?".
+//   - "IS NULL" / "IS NOT NULL": Value is ignored and no placeholder is produced.
+//
+// Usage:
+//
+//	where, values, err := ConditionToWhere(&orm.Condition{Field: "id", Operator: "IN", Value: []int{1, 2, 3}})
+//	// where = "id IN (?, ?, ?)", values = [1 2 3]
+func ConditionToWhere(condition *orm.Condition) (string, []interface{}, error) {
+	if err := ValidateCondition(condition); err != nil {
+		return "", nil, err
+	}
+	return conditionToWhere(condition)
+}
+
+// conditionToWhere is ConditionToWhere for an already validated condition
+func conditionToWhere(condition *orm.Condition) (string, []interface{}, error) {
+	if condition == nil {
+		return "", nil, nil
+	}
+	if condition.Field != "" {
+		return fieldToWhere(condition)
+	}
+
+	var clauses []string
+	var args []interface{}
+	for i := range condition.Nested {
+		subClause, subArgs, err := conditionToWhere(&condition.Nested[i])
+		if err != nil {
+			return "", nil, err
+		}
+		clauses = append(clauses, fmt.Sprintf("(%s)", subClause))
+		args = append(args, subArgs...)
+	}
+	return strings.Join(clauses, fmt.Sprintf(" %s ", strings.ToUpper(condition.Logic))), args, nil
+}
+
+// ErrInvalidCondition is returned (wrapped with the reason) by ValidateCondition
+var ErrInvalidCondition = errors.New("invalid condition")
+
+// comparisonOperators are the operators taking a single value, besides the ones handled by fieldToWhere
+var comparisonOperators = map[string]bool{
+	"=": true, "==": true, "!=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true,
+	"LIKE": true, "NOT LIKE": true, "GLOB": true, "NOT GLOB": true, "IS": true, "IS NOT": true,
+}
+
+// ValidateCondition checks a condition (and all its Nested conditions) before it is turned into SQL,
+// so a mistake fails locally with a clear message instead of a generic server error. Rules:
+//   - a condition with a Field needs a known operator and no Nested conditions
+//   - "IN" / "NOT IN" need a slice value, "BETWEEN" / "NOT BETWEEN" a two element slice
+//   - the other operators need a single (non slice) value
+//   - a group (no Field) with a Logic needs children, more than one child needs AND or OR Logic
+//
+// A nil condition, or one with only OrderBy/GroupBy/Limit/Offset, is valid and matches every row.
+// ConditionToWhere and the Select*WithCondition methods call it, errors wrap ErrInvalidCondition.
+func ValidateCondition(condition *orm.Condition) error {
+	if condition == nil {
+		return nil
+	}
+	if condition.Limit < UNLIMITED {
+		return fmt.Errorf("%w: negative limit %d", ErrInvalidCondition, condition.Limit)
+	}
+	if condition.Offset < 0 {
+		return fmt.Errorf("%w: negative offset %d", ErrInvalidCondition, condition.Offset)
+	}
+	// a condition without anything to filter is the "all rows" condition
+	if condition.Field == "" && condition.Operator == "" && condition.Value == nil && len(condition.Nested) == 0 {
+		return nil
+	}
+	return validateConditionNode(condition, "condition")
+}
+
+// validateConditionNode validates one condition, path (ie: "condition.nested[1]") locates it in the error
+func validateConditionNode(condition *orm.Condition, path string) error {
+	if condition.Field == "" {
+		return validateConditionGroup(condition, path)
+	}
+	if len(condition.Nested) > 0 {
+		return fmt.Errorf("%w: %s has both field %s and nested conditions", ErrInvalidCondition, path, condition.Field)
+	}
+
+	operator := normalizeOperator(condition.Operator)
+	switch operator {
+	case "":
+		return fmt.Errorf("%w: %s on %s has no operator", ErrInvalidCondition, path, condition.Field)
+	case "IN", "NOT IN":
+		if !isSliceValue(condition.Value) {
+			return fmt.Errorf("%w: %s operator on %s requires a slice value, got %T", ErrInvalidCondition, operator, condition.Field, condition.Value)
+		}
+	case "BETWEEN", "NOT BETWEEN":
+		if !isSliceValue(condition.Value) || len(flattenValues(condition.Value)) != 2 {
+			return fmt.Errorf("%w: %s operator on %s requires a two element slice value", ErrInvalidCondition, operator, condition.Field)
+		}
+	case "IS NULL", "IS NOT NULL":
+	default:
+		if !comparisonOperators[operator] {
+			return fmt.Errorf("%w: %s has unknown operator %q on %s", ErrInvalidCondition, path, condition.Operator, condition.Field)
+		}
+		if isSliceValue(condition.Value) {
+			return fmt.Errorf("%w: %s operator on %s does not take a slice value, use IN", ErrInvalidCondition, operator, condition.Field)
+		}
+	}
+	return nil
+}
+
+// validateConditionGroup validates a condition without a Field, which only combines its Nested conditions
+func validateConditionGroup(condition *orm.Condition, path string) error {
+	if condition.Operator != "" || condition.Value != nil {
+		return fmt.Errorf("%w: %s has an operator or value but no field", ErrInvalidCondition, path)
+	}
+	logic := strings.ToUpper(strings.TrimSpace(condition.Logic))
+	if logic != "" && logic != "AND" && logic != "OR" {
+		return fmt.Errorf("%w: %s has unknown logic %q, use AND or OR", ErrInvalidCondition, path, condition.Logic)
+	}
+	if len(condition.Nested) == 0 {
+		if logic != "" {
+			return fmt.Errorf("%w: nested group with %s logic has no children (%s)", ErrInvalidCondition, logic, path)
+		}
+		return fmt.Errorf("%w: %s has neither a field nor nested conditions", ErrInvalidCondition, path)
+	}
+	if len(condition.Nested) > 1 && logic == "" {
+		return fmt.Errorf("%w: %s combines %d nested conditions without AND or OR logic", ErrInvalidCondition, path, len(condition.Nested))
+	}
+	for i := range condition.Nested {
+		if err := validateConditionNode(&condition.Nested[i], fmt.Sprintf("%s.nested[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isSliceValue reports whether value is a slice or array, []byte is a single (blob) value
+func isSliceValue(value interface{}) bool {
+	rv := reflect.ValueOf(value)
+	return (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8
+}
+
+// fieldToWhere translates a single (non nested) condition
+func fieldToWhere(condition *orm.Condition) (string, []interface{}, error) {
+	operator := normalizeOperator(condition.Operator)
+	switch operator {
+	case "IN", "NOT IN":
+		values := flattenValues(condition.Value)
+		if len(values) == 0 {
+			if operator == "IN" {
+				return "1=0", nil, nil
+			}
+			return "1=1", nil, nil
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+		return fmt.Sprintf("%s %s (%s)", condition.Field, operator, placeholders), values, nil
+	case "BETWEEN", "NOT BETWEEN":
+		values := flattenValues(condition.Value)
+		if len(values) != 2 {
+			return "", nil, fmt.Errorf("%s on %s needs exactly 2 values, got %d", operator, condition.Field, len(values))
+		}
+		return fmt.Sprintf("%s %s ? AND ?", condition.Field, operator), values, nil
+	case "IS NULL", "IS NOT NULL":
+		return fmt.Sprintf("%s %s", condition.Field, operator), nil, nil
+	}
+	return fmt.Sprintf("%s %s ?", condition.Field, condition.Operator), []interface{}{condition.Value}, nil
+}
+
+// normalizeOperator upper cases the operator and collapses the whitespace, ie: "not  in" -> "NOT IN"
+func normalizeOperator(operator string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(operator), " "))
+}
+
+// flattenValues turns a slice or array value into []interface{}, any other value is a single element.
+// []byte is treated as a single (blob) value.
+func flattenValues(value interface{}) []interface{} {
+	if value == nil {
+		return nil
+	}
+	if values, ok := value.([]interface{}); ok {
+		return values
+	}
+	rv := reflect.ValueOf(value)
+	if (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return []interface{}{value}
+	}
+	values := make([]interface{}, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values
+}
+
+// ErrInvalidField is returned (wrapped with the field) for a projection field that is not a column name
+var ErrInvalidField = errors.New("invalid field")
+
+// validateFields checks the fields of a projection are plain column names (letters, digits and
+// underscores, optionally qualified with the table, ie: "users.name"), they go as is in the SELECT list
+func validateFields(fields []string) error {
+	for _, field := range fields {
+		parts := strings.Split(field, ".")
+		if len(parts) > 2 {
+			return fmt.Errorf("%w: %q", ErrInvalidField, field)
+		}
+		for _, part := range parts {
+			if !isColumnName(part) {
+				return fmt.Errorf("%w: %q", ErrInvalidField, field)
+			}
+		}
+	}
+	return nil
+}
+
+// isColumnName checks name is a non empty identifier of ASCII letters, digits and underscores
+// that does not start with a digit
+func isColumnName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// needsClientSQL reports whether the condition uses an operator the server's condition translation
+// doesn't handle, so the query has to be built on the client and sent as parameterized SQL
+func needsClientSQL(condition *orm.Condition) bool {
+	if condition == nil {
+		return false
+	}
+	if condition.Field != "" {
+		switch normalizeOperator(condition.Operator) {
+		case "IN", "NOT IN", "BETWEEN", "NOT BETWEEN", "IS NULL", "IS NOT NULL":
+			return true
+		}
+		return false
+	}
+	for i := range condition.Nested {
+		if needsClientSQL(&condition.Nested[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionToSelect builds SELECT * FROM table WHERE ... GROUP BY ... ORDER BY ... LIMIT ... OFFSET ...
+// using ConditionToWhere, the same way orm.Condition.ToSelectString does
+func conditionToSelect(tableName string, condition *orm.Condition) (orm.ParametereizedSQL, error) {
+	return conditionToSelectFields(tableName, condition, nil)
+}
+
+// conditionToSelectFields is conditionToSelect with only the given columns selected, all if empty.
+// The fields must have been checked with validateFields.
+func conditionToSelectFields(tableName string, condition *orm.Condition, fields []string) (orm.ParametereizedSQL, error) {
+	whereClause, values, err := ConditionToWhere(condition)
+	if err != nil {
+		return orm.ParametereizedSQL{}, err
+	}
+
+	columns := "*"
+	if len(fields) > 0 {
+		columns = strings.Join(fields, ", ")
+	}
+	var sb strings.Builder
+	sb.WriteString("SELECT " + columns + " FROM " + tableName)
+	if strings.TrimSpace(whereClause) != "" {
+		sb.WriteString(" WHERE " + whereClause)
+	}
+	if condition != nil {
+		if len(condition.GroupBy) > 0 {
+			sb.WriteString(" GROUP BY " + strings.Join(condition.GroupBy, ", "))
+		}
+		if len(condition.OrderBy) > 0 {
+			sb.WriteString(" ORDER BY " + strings.Join(condition.OrderBy, ", "))
+		}
+		limit := condition.Limit
+		// if offset has value but limit is not, then use default limit
+		if condition.Offset > 0 && limit < 1 {
+			limit = orm.DEFAULT_PAGINATION_LIMIT
+		}
+		if limit > 0 {
+			sb.WriteString(fmt.Sprintf(" LIMIT %d", limit))
+			if condition.Offset > 0 {
+				sb.WriteString(fmt.Sprintf(" OFFSET %d", condition.Offset))
+			}
+		}
+	}
+	return orm.ParametereizedSQL{Query: sb.String(), Values: values}, nil
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	orm "github.com/medatechnology/simpleorm"
+)
+
+func TestConditionToWhereBetweenAndNull(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition *orm.Condition
+		want      string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "between",
+			condition: &orm.Condition{Field: "created_at", Operator: "BETWEEN", Value: []interface{}{"2024-01-01", "2024-12-31"}},
+			want:      "created_at BETWEEN ? AND ?",
+			wantArgs:  []interface{}{"2024-01-01", "2024-12-31"},
+		},
+		{
+			name:      "not between, lower case operator",
+			condition: &orm.Condition{Field: "age", Operator: "not between", Value: []int{18, 30}},
+			want:      "age NOT BETWEEN ? AND ?",
+			wantArgs:  []interface{}{18, 30},
+		},
+		{
+			name:      "is null",
+			condition: &orm.Condition{Field: "deleted_at", Operator: "IS NULL"},
+			want:      "deleted_at IS NULL",
+		},
+		{
+			name:      "is not null",
+			condition: &orm.Condition{Field: "email", Operator: "IS NOT NULL"},
+			want:      "email IS NOT NULL",
+		},
+		{
+			name: "nested groups",
+			condition: &orm.Condition{Logic: "AND", Nested: []orm.Condition{
+				{Field: "deleted_at", Operator: "IS NULL"},
+				{Logic: "OR", Nested: []orm.Condition{
+					{Field: "age", Operator: "BETWEEN", Value: []interface{}{18, 30}},
+					{Field: "email", Operator: "IS NOT NULL"},
+				}},
+			}},
+			want:     "(deleted_at IS NULL) AND ((age BETWEEN ? AND ?) OR (email IS NOT NULL))",
+			wantArgs: []interface{}{18, 30},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args, err := ConditionToWhere(tt.condition)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if where != tt.want {
+				t.Errorf("where = %q, want %q", where, tt.want)
+			}
+			if len(args) != len(tt.wantArgs) || (len(args) > 0 && !reflect.DeepEqual(args, tt.wantArgs)) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestConditionToWhereBetweenNeedsTwoValues(t *testing.T) {
+	for _, value := range []interface{}{nil, 5, []int{1}, []int{1, 2, 3}} {
+		_, _, err := ConditionToWhere(&orm.Condition{Field: "age", Operator: "BETWEEN", Value: value})
+		if !errors.Is(err, ErrInvalidCondition) {
+			t.Errorf("BETWEEN with %v: err = %v, want ErrInvalidCondition", value, err)
+		}
+	}
+}
+
+func TestSelectWithBetweenAndNullSendsSQL(t *testing.T) {
+	var mutex sync.Mutex
+	var sent string
+	c := newStubClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/db/api/querysql" {
+			body, _ := io.ReadAll(req.Body)
+			mutex.Lock()
+			sent = string(body)
+			mutex.Unlock()
+		}
+		return okResponse(req, `[{"records":[{"TableName":"users","Data":{"id":1}}],"count":1}]`), nil
+	}))
+
+	records, err := c.SelectManyWithCondition("users", &orm.Condition{Logic: "AND", Nested: []orm.Condition{
+		{Field: "deleted_at", Operator: "IS NULL"},
+		{Field: "age", Operator: "BETWEEN", Value: []interface{}{18, 30}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !strings.Contains(sent, "WHERE (deleted_at IS NULL) AND (age BETWEEN ? AND ?)") {
+		t.Errorf("querysql body = %s", sent)
+	}
+}
@@ -2,12 +2,14 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/medatechnology/goutil/object"
@@ -32,6 +34,13 @@ func NewHTTPClient(config *HTTPClientConfig) *http.Client {
 	if timeout == 0 {
 		timeout = DEFAULT_TIMEOUT
 	}
+	// Custom transport (ie: httptest server, stub or mock), used as is
+	if config.Transport != nil {
+		return &http.Client{
+			Timeout:   timeout,
+			Transport: config.Transport,
+		}
+	}
 	return &http.Client{
 		Timeout: timeout,
 		Transport: &http.Transport{
@@ -46,13 +55,15 @@ func NewHTTPClient(config *HTTPClientConfig) *http.Client {
 			MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
 			MaxConnsPerHost:       config.MaxConnsPerHost,
 			IdleConnTimeout:       config.IdleConnTimeout,
+			// a custom Dial disables HTTP/2 unless it is forced
+			ForceAttemptHTTP2: config.HTTP2,
 		}}
 }
 
 // Create new connection object, not yet connected to the url
 func NewConnection(config *ClientConfig, url, nodeID, mode string, leader bool, token suresql.TokenTable) *Connection {
 	// Use config's HTTP client configuration or create a default one
-	client := NewHTTPClient(config.HTTPClientConfig)
+	client := NewHTTPClient(config.HTTPClientConfig.forNode(nodeID))
 	return NewConnectionWithClient(config, url, nodeID, mode, leader, token, client)
 }
 
@@ -91,13 +102,21 @@ func (c *Client) getOrCreateNodeHTTPClient(nodeID string) *http.Client {
 	c.readPool.mutex.Lock()
 	defer c.readPool.mutex.Unlock()
 
-	// Check if we already have a client for this node
+	// Check if we already have a client for this node, the write pool keeps it when
+	// the node has no read connections left
 	if client, exists := c.readPool.nodeHTTPClients[nodeID]; exists {
 		return client
 	}
+	c.writePool.mutex.RLock()
+	client, exists := c.writePool.nodeHTTPClients[nodeID]
+	c.writePool.mutex.RUnlock()
+	if exists {
+		c.readPool.nodeHTTPClients[nodeID] = client
+		return client
+	}
 
 	// Create a new HTTP client with the specified configuration
-	client := NewHTTPClient(nil)
+	client = NewHTTPClient(c.Config.HTTPClientConfig.forNode(nodeID))
 
 	// Store the client for future use
 	if c.readPool.nodeHTTPClients == nil {
@@ -117,7 +136,7 @@ func (c *Client) getOrCreateNodeHTTPClient(nodeID string) *http.Client {
 }
 
 // Create new connection then connect it (to get token)
-func (c *Client) createAndConnectNewConnection(url, nodeID, mode string, leader bool) (*Connection, error) {
+func (c *Client) createAndConnectNewConnection(ctx context.Context, url, nodeID, mode string, leader bool) (*Connection, error) {
 	var conn *Connection
 
 	if c.PoolConfig.NodeUseMultiClient {
@@ -130,7 +149,12 @@ func (c *Client) createAndConnectNewConnection(url, nodeID, mode string, leader
 	}
 	// conn := NewConnection(&c.Config, url, nodeID, mode, leader, suresql.TokenTable{})
 	// fmt.Println("Creating new connection: ", url, nodeID, mode, leader)
-	err := conn.newOrRefreshToken(&c.Config, CALL_CONNECT)
+	if c.Config.tokenOnly {
+		// connected with ConnectWithToken, the given token is used by every connection
+		conn.setToken(c.leaderConn.token())
+		return conn, nil
+	}
+	err := conn.newOrRefreshToken(ctx, &c.Config, CALL_CONNECT)
 	if err != nil {
 		return nil, err
 	}
@@ -175,10 +199,10 @@ func (c *Client) createAndConnectNewConnection(url, nodeID, mode string, leader
 // }
 
 // Preparing standard request, using APIKEY and CLIENTID
-func (c *Connection) createHttpRequest(method, endpoint string, data interface{}, config *ClientConfig) (*http.Request, error) {
+func (c *Connection) createHttpRequest(ctx context.Context, method, endpoint string, data interface{}, config *ClientConfig) (*http.Request, error) {
 	var body io.Reader
 	if data != nil {
-		jsonData, err := json.Marshal(data)
+		jsonData, err := config.codec().Marshal(config.bindTimes(data))
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal request data: %w", err)
 		}
@@ -186,7 +210,7 @@ func (c *Connection) createHttpRequest(method, endpoint string, data interface{}
 	}
 
 	fullUrl := c.URL + endpoint
-	req, err := http.NewRequest(method, fullUrl, body)
+	req, err := http.NewRequestWithContext(ctx, method, fullUrl, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -195,36 +219,91 @@ func (c *Connection) createHttpRequest(method, endpoint string, data interface{}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("API_KEY", config.APIKey)
 	req.Header.Set("CLIENT_ID", config.ClientID)
+	if key := idempotencyKeyFromContext(ctx); key != "" {
+		req.Header.Set(IDEMPOTENCY_KEY_HEADER, key)
+	}
+	if requestID := requestIDFromContext(ctx); requestID != "" {
+		req.Header.Set(REQUEST_ID_HEADER, requestID)
+	}
 	return req, err
 }
 
 // Making HTTP call
-func (c *Connection) sendHttpRequest(method, endpoint string, data interface{}, config *ClientConfig, withToken bool) (*http.Response, error) {
+func (c *Connection) sendHttpRequest(ctx context.Context, method, endpoint string, data interface{}, config *ClientConfig, withToken bool) (*http.Response, error) {
 	// prepare standard request
-	req, err := c.createHttpRequest(method, endpoint, data, config)
+	req, err := c.createHttpRequest(ctx, method, endpoint, data, config)
 	if err != nil {
 		return nil, err
 	}
 
 	// Set authorization if token provided
-	if withToken && c.Token.Token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.Token.Token)
+	if token := c.token().Token; withToken && token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
 	// Do the actual HTTP request
 	return c.HTTPClient.Do(req)
 }
 
+// closeResponseBody drains and closes the response body, safe to call with nil response (ie: on transport errors).
+// The body must be fully read before Close, otherwise net/http cannot reuse the keep-alive connection.
+// At most DEFAULT_MAX_DRAIN_BYTES are drained, for a longer body (ie: over MaxResponseBytes) dropping
+// the connection is cheaper than reading the rest.
+func closeResponseBody(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, DEFAULT_MAX_DRAIN_BYTES))
+	resp.Body.Close()
+}
+
+// rawStandardResponse mirrors suresql.StandardResponse but keeps Data as raw bytes,
+// so it can be decoded directly into the final type without a second marshal/unmarshal
+type rawStandardResponse struct {
+	Status  int             `json:"status"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
 // decode the response into StandardResponse which has status and then check if it's not OK
 // If it's OK then return just the Data part.
-func (c *Connection) getAndCheckResponseData(resp *http.Response) (interface{}, error) {
-	defer resp.Body.Close()
+func (c *Connection) getAndCheckResponseData(resp *http.Response, config *ClientConfig) (interface{}, error) {
+	raw, err := c.getAndCheckResponseRaw(resp, config)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRawData(raw, config)
+}
+
+// Same as getAndCheckResponseData but return the Data part as raw (undecoded) JSON.
+// Only status and message are decoded to check the response.
+func (c *Connection) getAndCheckResponseRaw(resp *http.Response, config *ClientConfig) (json.RawMessage, error) {
+	if resp == nil {
+		return nil, errors.New("no response received")
+	}
+	defer closeResponseBody(resp)
 	// if resp.StatusCode != http.StatusOK {
 	// 	return nil, fmt.Errorf("request error: %s", resp.Status)
 	// }
-	var result suresql.StandardResponse
-	err := json.NewDecoder(resp.Body).Decode(&result)
+	var result rawStandardResponse
+	maxBytes := config.HTTPClientConfig.maxResponseBytes()
+	// read one byte more than allowed to know the limit was exceeded
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if int64(len(respBody)) > maxBytes {
+		return nil, fmt.Errorf("%w of %d bytes (HTTP %s)", ErrResponseTooLarge, maxBytes, resp.Status)
+	}
+	if err := throttledError(resp, respBody); err != nil {
+		return nil, err
+	}
+	// proxies and gateways answer with HTML or plain text error pages, don't try to decode those
+	if (resp.StatusCode < 200 || resp.StatusCode >= 300) && !isJSONResponse(resp, respBody) {
+		return nil, fmt.Errorf("request error: HTTP %s: %s", resp.Status, bodySnippet(respBody))
+	}
+	err = config.codec().Unmarshal(respBody, &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response (HTTP %s): %w: %s", resp.Status, err, bodySnippet(respBody))
 	}
 
 	if result.Status != http.StatusOK {
@@ -234,10 +313,54 @@ func (c *Connection) getAndCheckResponseData(resp *http.Response) (interface{},
 	return result.Data, nil
 }
 
+// ErrResponseTooLarge is returned when a response body is bigger than HTTPClientConfig.MaxResponseBytes
+var ErrResponseTooLarge = errors.New("response exceeded max size")
+
+// maxResponseBytes returns MaxResponseBytes or the default when not set
+func (config *HTTPClientConfig) maxResponseBytes() int64 {
+	if config == nil || config.MaxResponseBytes <= 0 {
+		return DEFAULT_MAX_RESPONSE_BYTES
+	}
+	return config.MaxResponseBytes
+}
+
+// isJSONResponse checks the Content-Type, or the body itself when the Content-Type is missing
+func isJSONResponse(resp *http.Response, body []byte) bool {
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		return strings.Contains(strings.ToLower(contentType), "json")
+	}
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// bodySnippet returns the body on a single line, truncated to DEFAULT_ERROR_BODY_SNIPPET_LENGTH
+func bodySnippet(body []byte) string {
+	snippet := strings.Join(strings.Fields(string(body)), " ")
+	if snippet == "" {
+		return "(empty body)"
+	}
+	if len(snippet) > DEFAULT_ERROR_BODY_SNIPPET_LENGTH {
+		return snippet[:DEFAULT_ERROR_BODY_SNIPPET_LENGTH] + "..."
+	}
+	return snippet
+}
+
+// decodeRawData decodes raw Data part of the response into interface{} (maps, slices, etc)
+func decodeRawData(raw json.RawMessage, config *ClientConfig) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var data interface{}
+	if err := config.codec().Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode response data: %w", err)
+	}
+	return data, nil
+}
+
 // Just repetitive check for sending http request withToken==true, then it will check first if token exist
 func (c *Connection) getAndCheckToken(withToken bool) error {
 	if withToken {
-		if c.Token.Token == "" {
+		if c.token().Token == "" {
 			return fmt.Errorf("authentication required but no token available for %s", c.NodeID)
 		}
 	}
@@ -328,52 +451,47 @@ func (c *Connection) getAndCheckToken(withToken bool) error {
 // 	return conn, nil
 // }
 
-// For existing connection (maybe when call send it failed) try to renew the token by:
-// 1. First try to refresh using refresh token, if succeed then exit.
-// 2. If refresh failed, try to renew by calling /connect
-func (c *Connection) tryRefreshAndRenew(config *ClientConfig) error {
-	err := c.newOrRefreshToken(config, true)
-	if err != nil {
-		// this means refresh failed, then re-connect again
-		err = c.newOrRefreshToken(config, false)
-	}
-	return err
-}
-
 // Can be used to get new token (using /connect) or refresh token (using /refresh)
 // for existing connection. It can be new connection, or existing but make sure it already
 // have information such as URL
 // If refresh==true then it's refresh, if refresh==false then it's creating new token
 // refreshConnection attempts to refresh a connection's token
-func (c *Connection) newOrRefreshToken(config *ClientConfig, refresh bool) error {
+func (c *Connection) newOrRefreshToken(ctx context.Context, config *ClientConfig, refresh bool) error {
 	var resp *http.Response
 	var err error
 
+	// token calls are not part of the (idempotent) write that triggered them
+	ctx = contextWithIdempotencyKey(ctx, "")
+
 	if refresh {
 		// if refresh called /db/refresh
-		if c.Token.Refresh == "" {
+		refreshToken := c.token().Refresh
+		if refreshToken == "" {
 			return errors.New("no refresh token available for connection")
 		}
 		refreshReq := map[string]string{
-			"refresh_token": c.Token.Refresh,
+			"refresh_token": refreshToken,
 		}
 
-		resp, err = c.sendHttpRequest("POST", "/db/refresh", refreshReq, config, NO_TOKEN)
+		resp, err = c.sendHttpRequest(ctx, "POST", "/db/refresh", refreshReq, config, NO_TOKEN)
 		if err != nil {
-			resp.Body.Close()
+			closeResponseBody(resp)
 			return fmt.Errorf("refresh request failed: %w", err)
 		}
 	} else {
+		if config.tokenOnly {
+			return errors.New("connected with a token, cannot log in again without credentials")
+		}
 		// if new token called /db/connect
-		resp, err = c.sendHttpRequest("POST", "/db/connect", userCredentialsFromConfig(config), config, NO_TOKEN)
+		resp, err = c.sendHttpRequest(ctx, "POST", "/db/connect", userCredentialsFromConfig(config), config, NO_TOKEN)
 		if err != nil {
-			// resp.Body.Close()
+			closeResponseBody(resp)
 			return fmt.Errorf("connect (new token) request failed: %w", err)
 		}
 	}
 
 	// Process response (and also check)
-	data, err := c.getAndCheckResponseData(resp)
+	data, err := c.getAndCheckResponseData(resp, config)
 	if err != nil {
 		// any error, wether server error or unautorized, try again by using connect
 		// return fmt.Errorf("failed to decode refresh response: %w", err)
@@ -386,9 +504,8 @@ func (c *Connection) newOrRefreshToken(config *ClientConfig, refresh bool) error
 		return err
 	}
 
-	c.Token = tokenObj
-	c.LastRefresh = time.Now()
-	fmt.Printf("Connection: %s=%s, get new token:%s\n", c.NodeID, c.URL, c.Token.Token)
+	c.setToken(tokenObj)
+	fmt.Printf("Connection: %s=%s, get new token:%s\n", c.NodeID, c.URL, tokenObj.Token)
 	return nil
 }
 
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/medatechnology/suresql"
+)
+
+// deadURL returns the URL of a local port nothing listens on
+func deadURL(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "http://" + ln.Addr().String()
+	ln.Close()
+	return url
+}
+
+func TestTokenRequestDialFailure(t *testing.T) {
+	config := NewClientConfig(WithServerURL(deadURL(t)))
+	conn := NewConnection(&config, "", "", "", true, suresql.TokenTable{Token: "token", Refresh: "refresh"})
+
+	for _, refresh := range []bool{CALL_REFRESH, CALL_CONNECT} {
+		if err := conn.newOrRefreshToken(context.Background(), &config, refresh); err == nil {
+			t.Errorf("newOrRefreshToken(refresh=%v) to a dead server returned no error", refresh)
+		}
+	}
+}
+
+func TestRequestDialFailure(t *testing.T) {
+	c, err := NewClient(NewClientConfig(WithServerURL(deadURL(t))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.leaderConn.Token = suresql.TokenTable{Token: "token", Refresh: "refresh"}
+
+	_, err = c.doRequestToPool(context.Background(), c.leaderConn, "POST", "/db/api/sql", nil, WITH_TOKEN, AUTO_REFRESH, FALLBACK_LEADER)
+	if err == nil {
+		t.Fatal("request to a dead server returned no error")
+	}
+}
+
+// trackedBody is a response body that records whether it was read to the end and closed
+type trackedBody struct {
+	*strings.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func (b *trackedBody) drained() bool {
+	return b.closed && b.Len() == 0
+}
+
+func TestResponseBodiesAreDrained(t *testing.T) {
+	// JSON followed by padding, a decoder could stop before the end
+	padding := strings.Repeat(" ", 4096)
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"success", http.StatusOK, "application/json", `{"status":200,"message":"OK","data":{}}` + padding},
+		{"server error", http.StatusOK, "application/json", `{"status":500,"message":"failed"}` + padding},
+		{"invalid JSON", http.StatusOK, "application/json", `{"status":` + padding},
+		{"HTML error page", http.StatusBadGateway, "text/html", "<html>bad gateway</html>" + padding},
+		{"throttled", http.StatusTooManyRequests, "application/json", `{"status":429,"message":"slow down"}` + padding},
+	}
+	config := NewClientConfig()
+	conn := &Connection{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &trackedBody{Reader: strings.NewReader(tt.body)}
+			resp := &http.Response{
+				StatusCode: tt.status,
+				Status:     http.StatusText(tt.status),
+				Header:     http.Header{"Content-Type": {tt.contentType}},
+				Body:       body,
+			}
+			conn.getAndCheckResponseRaw(resp, &config)
+			if !body.drained() {
+				t.Errorf("body not drained: %d bytes left, closed=%v", body.Len(), body.closed)
+			}
+		})
+	}
+}
+
+func TestUnauthorizedBodyIsDrained(t *testing.T) {
+	var mutex sync.Mutex
+	var bodies []*trackedBody
+	c := newStubClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		resp := okResponse(req, `{"token":"new-token","refresh_token":"new-refresh"}`)
+		if req.URL.Path != "/db/refresh" && len(bodies) == 0 {
+			resp = stubResponse(req, http.StatusUnauthorized, "application/json", `{"status":401,"message":"token expired"}`)
+		}
+		content, _ := io.ReadAll(resp.Body)
+		body := &trackedBody{Reader: strings.NewReader(string(content) + strings.Repeat(" ", 4096))}
+		bodies = append(bodies, body)
+		resp.Body = body
+		return resp, nil
+	}))
+
+	if _, err := c.doRequestToPool(context.Background(), c.leaderConn, "POST", "/db/api/sql", nil, WITH_TOKEN, AUTO_REFRESH, NO_FALLBACK); err != nil {
+		t.Fatal(err)
+	}
+	for i, body := range bodies {
+		if !body.drained() {
+			t.Errorf("body %d not drained: %d bytes left, closed=%v", i, body.Len(), body.closed)
+		}
+	}
+}
+
+func TestNonJSONErrorResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		want        []string
+	}{
+		{
+			name:        "plain text 503",
+			status:      http.StatusServiceUnavailable,
+			contentType: "text/plain",
+			body:        "upstream connect error\nor disconnect/reset before headers",
+			want:        []string{"503", "upstream connect error or disconnect/reset before headers"},
+		},
+		{
+			name:        "HTML 502",
+			status:      http.StatusBadGateway,
+			contentType: "text/html",
+			body:        "<html><body><h1>502 Bad Gateway</h1></body></html>",
+			want:        []string{"HTTP 502", "<h1>502 Bad Gateway</h1>"},
+		},
+		{
+			name:        "long body is truncated",
+			status:      http.StatusBadGateway,
+			contentType: "text/plain",
+			body:        strings.Repeat("x", 5000),
+			want:        []string{"HTTP 502", strings.Repeat("x", DEFAULT_ERROR_BODY_SNIPPET_LENGTH) + "..."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newStubClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				return stubResponse(req, tt.status, tt.contentType, tt.body), nil
+			}))
+			_, err := c.doRequestToPool(context.Background(), c.leaderConn, "POST", "/db/api/sql", nil, WITH_TOKEN, AUTO_REFRESH, NO_FALLBACK)
+			if err == nil {
+				t.Fatal("no error for a non-JSON error response")
+			}
+			if strings.Contains(err.Error(), "invalid character") {
+				t.Errorf("body was decoded as JSON: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("err = %q, want it to contain %q", err, want)
+				}
+			}
+			if strings.Contains(err.Error(), strings.Repeat("x", DEFAULT_ERROR_BODY_SNIPPET_LENGTH+1)) {
+				t.Errorf("body snippet is not truncated: %d characters", len(err.Error()))
+			}
+		})
+	}
+}
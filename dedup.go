@@ -0,0 +1,75 @@
+package client
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+)
+
+//------------------------------------------------------------------
+// READ DEDUPLICATION (SINGLEFLIGHT)
+//------------------------------------------------------------------
+
+// flightGroup shares one in-flight call between concurrent callers of the same key,
+// same semantic as golang.org/x/sync/singleflight. The zero value is ready to use.
+type flightGroup[T any] struct {
+	mutex sync.Mutex
+	calls map[string]*flightCall[T]
+}
+
+type flightCall[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// do runs fn once for all concurrent callers of key, shared is true for the callers that
+// got the result of another caller's call
+func (g *flightGroup[T]) do(key string, fn func() (T, error)) (value T, err error, shared bool) {
+	g.mutex.Lock()
+	if call, exists := g.calls[key]; exists {
+		g.mutex.Unlock()
+		<-call.done
+		return call.value, call.err, true
+	}
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[T])
+	}
+	call := &flightCall[T]{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mutex.Unlock()
+
+	defer func() {
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+		close(call.done)
+	}()
+	call.value, call.err = fn()
+	return call.value, call.err, false
+}
+
+// readKey identifies a read request by endpoint and body (query + parameters),
+// returns false if the body cannot be encoded
+func (c *Client) readKey(endpoint string, body interface{}) (string, bool) {
+	encoded, err := c.Config.codec().Marshal(body)
+	if err != nil {
+		return "", false
+	}
+	return endpoint + "\x00" + string(encoded), true
+}
+
+// dedupRead runs fetch through the read flight group when ReadDeduplication is enabled.
+// Callers sharing a call get the same raw response (each decodes its own copy) and the same
+// error, including a cancellation of the ctx of the caller that started the call.
+func (c *Client) dedupRead(endpoint string, body interface{}, fetch func() (json.RawMessage, error)) (json.RawMessage, error) {
+	key, ok := c.readKey(endpoint, body)
+	if !ok {
+		return fetch()
+	}
+	raw, err, shared := c.readFlights.do(key, fetch)
+	if shared {
+		atomic.AddInt64(&c.dedupedReads, 1)
+	}
+	return raw, err
+}
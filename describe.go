@@ -0,0 +1,255 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+//------------------------------------------------------------------
+// TABLE INTROSPECTION
+//------------------------------------------------------------------
+
+// ColumnInfo describes a column as declared in the CREATE TABLE statement
+type ColumnInfo struct {
+	Name          string
+	Type          string // declared type in upper case, ie: "INTEGER", "DECIMAL(10,2)", empty if none
+	Affinity      string // SQLite type affinity: INTEGER, TEXT, BLOB, REAL or NUMERIC
+	NotNull       bool
+	PrimaryKey    bool
+	AutoIncrement bool
+	Unique        bool
+	HasDefault    bool
+	Default       string // default value as SQL expression, ie: "0", "'active'", "CURRENT_TIMESTAMP"
+}
+
+// DescribeTable returns the columns of a table by parsing its CREATE TABLE statement from the schema
+// Usage:
+//
+//	columns, err := db.DescribeTable("users")
+func (c *Client) DescribeTable(name string) ([]ColumnInfo, error) {
+	for _, item := range c.GetSchema(false, false) {
+		if strings.EqualFold(item.ObjectType, "table") && strings.EqualFold(item.ObjectName, name) {
+			return ParseCreateTable(item.SQLCommand)
+		}
+	}
+	return nil, fmt.Errorf("table %s not found in schema", name)
+}
+
+// ParseCreateTable parses the column definitions of a CREATE TABLE statement.
+// Table constraints (PRIMARY KEY (a, b), UNIQUE (a)) are applied to their columns.
+func ParseCreateTable(sql string) ([]ColumnInfo, error) {
+	start := strings.IndexByte(sql, '(')
+	end := strings.LastIndexByte(sql, ')')
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("no column definitions in: %s", sql)
+	}
+
+	var columns []ColumnInfo
+	var tableConstraints [][]string
+	for _, def := range splitTopLevel(sql[start+1:end], ',') {
+		tokens := tokenizeSQL(def)
+		if len(tokens) == 0 {
+			continue
+		}
+		switch strings.ToUpper(tokens[0]) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			tableConstraints = append(tableConstraints, tokens)
+			continue
+		}
+		columns = append(columns, parseColumnDef(tokens))
+	}
+
+	for _, tokens := range tableConstraints {
+		// skip "CONSTRAINT name"
+		if strings.EqualFold(tokens[0], "CONSTRAINT") && len(tokens) > 2 {
+			tokens = tokens[2:]
+		}
+		kind := strings.ToUpper(tokens[0])
+		if kind != "PRIMARY" && kind != "UNIQUE" {
+			continue
+		}
+		var names []string
+		for _, tok := range tokens {
+			if strings.HasPrefix(tok, "(") {
+				for _, n := range splitTopLevel(tok[1:len(tok)-1], ',') {
+					if fields := tokenizeSQL(n); len(fields) > 0 {
+						names = append(names, unquoteIdentifier(fields[0]))
+					}
+				}
+				break
+			}
+		}
+		for i := range columns {
+			for _, n := range names {
+				if !strings.EqualFold(columns[i].Name, n) {
+					continue
+				}
+				if kind == "PRIMARY" {
+					columns[i].PrimaryKey = true
+				} else if len(names) == 1 {
+					// a multi-column UNIQUE doesn't make each column unique
+					columns[i].Unique = true
+				}
+			}
+		}
+	}
+	return columns, nil
+}
+
+// parseColumnDef parses "name type constraints..." tokens
+func parseColumnDef(tokens []string) ColumnInfo {
+	col := ColumnInfo{Name: unquoteIdentifier(tokens[0])}
+
+	// the type is everything up to the first constraint keyword
+	i := 1
+	var typeParts []string
+	for ; i < len(tokens); i++ {
+		if isColumnConstraintKeyword(tokens[i]) {
+			break
+		}
+		typeParts = append(typeParts, strings.ToUpper(tokens[i]))
+	}
+	col.Type = strings.ReplaceAll(strings.Join(typeParts, " "), " (", "(")
+	col.Affinity = typeAffinity(col.Type)
+
+	for ; i < len(tokens); i++ {
+		switch strings.ToUpper(tokens[i]) {
+		case "PRIMARY":
+			col.PrimaryKey = true
+		case "AUTOINCREMENT":
+			col.AutoIncrement = true
+		case "UNIQUE":
+			col.Unique = true
+		case "NOT":
+			if i+1 < len(tokens) && strings.EqualFold(tokens[i+1], "NULL") {
+				col.NotNull = true
+				i++
+			}
+		case "DEFAULT":
+			if i+1 < len(tokens) {
+				col.HasDefault = true
+				col.Default = tokens[i+1]
+				i++
+				// signed numbers are tokenized as "-" "1"
+				if (col.Default == "-" || col.Default == "+") && i+1 < len(tokens) {
+					col.Default += tokens[i+1]
+					i++
+				}
+			}
+		}
+	}
+	return col
+}
+
+func isColumnConstraintKeyword(token string) bool {
+	switch strings.ToUpper(token) {
+	case "CONSTRAINT", "PRIMARY", "NOT", "NULL", "UNIQUE", "CHECK", "DEFAULT", "COLLATE", "REFERENCES", "GENERATED", "AS":
+		return true
+	}
+	return false
+}
+
+// typeAffinity applies the SQLite rules to find the affinity of a declared type
+func typeAffinity(declared string) string {
+	switch {
+	case strings.Contains(declared, "INT"):
+		return "INTEGER"
+	case strings.Contains(declared, "CHAR"), strings.Contains(declared, "CLOB"), strings.Contains(declared, "TEXT"):
+		return "TEXT"
+	case declared == "", strings.Contains(declared, "BLOB"):
+		return "BLOB"
+	case strings.Contains(declared, "REAL"), strings.Contains(declared, "FLOA"), strings.Contains(declared, "DOUB"):
+		return "REAL"
+	}
+	return "NUMERIC"
+}
+
+// splitTopLevel splits s by sep, ignoring separators inside parentheses or quotes
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth := 0
+	var quote byte
+	last := 0
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '[':
+			quote = ']'
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case ch == sep && depth == 0:
+			parts = append(parts, s[last:i])
+			last = i + 1
+		}
+	}
+	return append(parts, s[last:])
+}
+
+// tokenizeSQL splits a definition into words, quoted identifiers/strings and parenthesized groups
+func tokenizeSQL(s string) []string {
+	var tokens []string
+	for i := 0; i < len(s); {
+		ch := s[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '\'' || ch == '"' || ch == '`' || ch == '[':
+			closing := ch
+			if ch == '[' {
+				closing = ']'
+			}
+			end := strings.IndexByte(s[i+1:], closing)
+			if end < 0 {
+				tokens = append(tokens, s[i:])
+				return tokens
+			}
+			tokens = append(tokens, s[i:i+end+2])
+			i += end + 2
+		case ch == '(':
+			depth := 0
+			j := i
+			for ; j < len(s); j++ {
+				if s[j] == '(' {
+					depth++
+				} else if s[j] == ')' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			tokens = append(tokens, s[i:min(j+1, len(s))])
+			i = j + 1
+		case ch == '-' || ch == '+':
+			tokens = append(tokens, string(ch))
+			i++
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n\r('\"`[", rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return tokens
+}
+
+// unquoteIdentifier removes "", “, [] quotes of an identifier
+func unquoteIdentifier(name string) string {
+	if len(name) >= 2 {
+		first, last := name[0], name[len(name)-1]
+		if (first == '"' && last == '"') || (first == '`' && last == '`') || (first == '[' && last == ']') {
+			return name[1 : len(name)-1]
+		}
+	}
+	return name
+}
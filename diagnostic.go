@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	orm "github.com/medatechnology/simpleorm"
+	"github.com/medatechnology/suresql"
+)
+
+//------------------------------------------------------------------
+// DIAGNOSTICS (PER NODE QUERIES)
+//------------------------------------------------------------------
+// ADVANCED: these bypass the pool routing on purpose, use them to debug the cluster
+// (ie: replication lag between nodes), not for the application queries.
+
+// AllNodeIDs returns the IDs of every node that has connections in the read or write pool,
+// including drained nodes, sorted. Use it to run the same query on each node with SelectOnNode.
+func (c *Client) AllNodeIDs() []string {
+	seen := make(map[string]bool)
+	var nodeIDs []string
+	for _, pool := range []*ConnectionPool{c.readPool, c.writePool} {
+		for _, conn := range pool.GetAllConnections() {
+			if !seen[conn.NodeID] {
+				seen[conn.NodeID] = true
+				nodeIDs = append(nodeIDs, conn.NodeID)
+			}
+		}
+	}
+	sort.Strings(nodeIDs)
+	return nodeIDs
+}
+
+// SelectOnNode runs a SELECT on the given node only, instead of the round-robin node. There is no
+// fallback to the leader, no read cache or deduplication, and the request is not counted by the pool
+// scaling or the per-node in-flight limit. Returns an error if the node has no connections and
+// orm.ErrSQLNoRows if nothing matches.
+// ADVANCED: for diagnostics only, ie: comparing a row on every node
+//
+//	for _, nodeID := range db.AllNodeIDs() {
+//		records, err := db.SelectOnNode(nodeID, "SELECT * FROM users WHERE id = 42")
+//	}
+func (c *Client) SelectOnNode(nodeID string, sql string) (orm.DBRecords, error) {
+	conn, err := c.readPool.GetConnectionForNode(nodeID)
+	if err != nil {
+		// ie: a write only node
+		conn, err = c.writePool.GetConnectionForNode(nodeID)
+		if err != nil {
+			return nil, fmt.Errorf("no connections available for node %s", nodeID)
+		}
+	}
+
+	ctx, done := c.inflight.register(context.Background())
+	defer done()
+	req := &suresql.SQLRequest{Statements: []string{sql}}
+	rawData, err := c.sendRequestToPoolRaw(ctx, conn, "POST", "/db/api/querysql", req, WITH_TOKEN, AUTO_REFRESH, NO_FALLBACK)
+	if err != nil {
+		return nil, err
+	}
+	var response suresql.QueryResponseSQL
+	if len(rawData) > 0 {
+		if err = c.Config.codec().Unmarshal(rawData, &response); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal SQL response: %w", err)
+		}
+	}
+	if c.Config.SchemaTypeCoercion {
+		c.coerceResponse(&response, req)
+	}
+	// let user know this is not error, just no rows found
+	if len(response) == 0 || len(response[0].Records) == 0 {
+		return nil, orm.ErrSQLNoRows
+	}
+	return response[0].Records, nil
+}
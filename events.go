@@ -0,0 +1,98 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+//------------------------------------------------------------------
+// POOL EVENT HISTORY
+//------------------------------------------------------------------
+
+// Pool event types
+const (
+	POOL_EVENT_SCALE_UP      = "scale_up"
+	POOL_EVENT_SCALE_DOWN    = "scale_down"
+	POOL_EVENT_LEADER_CHANGE = "leader_change"
+	POOL_EVENT_NODE_JOINED   = "node_joined"
+	POOL_EVENT_NODE_LEFT     = "node_left"
+	POOL_EVENT_NODE_DRAINED  = "node_drained"
+	POOL_EVENT_NODE_UNDRAIN  = "node_undrained"
+	POOL_EVENT_SATURATED     = "saturated"
+)
+
+// PoolEvent is one entry of the pool history, NodeID is empty for events not tied to a node
+type PoolEvent struct {
+	Time   time.Time
+	Type   string
+	NodeID string
+	Detail string
+}
+
+// eventRing keeps the last size pool events, oldest are overwritten. A nil eventRing is
+// disabled, all methods are no-ops.
+type eventRing struct {
+	mutex  sync.Mutex
+	events []PoolEvent
+	next   int  // index the next event is written to
+	full   bool // true once next wrapped around
+}
+
+// newEventRing returns nil (disabled) if size is not set
+func newEventRing(size int) *eventRing {
+	if size <= 0 {
+		return nil
+	}
+	return &eventRing{events: make([]PoolEvent, size)}
+}
+
+func (r *eventRing) add(eventType, nodeID, detail string) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	r.events[r.next] = PoolEvent{Time: time.Now(), Type: eventType, NodeID: nodeID, Detail: detail}
+	r.next++
+	if r.next == len(r.events) {
+		r.next = 0
+		r.full = true
+	}
+	r.mutex.Unlock()
+}
+
+// since returns the events after the given time, oldest first
+func (r *eventRing) since(since time.Time) []PoolEvent {
+	if r == nil {
+		return nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	start, count := 0, r.next
+	if r.full {
+		start, count = r.next, len(r.events)
+	}
+	result := make([]PoolEvent, 0, count)
+	for i := 0; i < count; i++ {
+		event := r.events[(start+i)%len(r.events)]
+		if event.Time.After(since) {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// recordPoolEvent appends an event to the pool history if it is enabled
+func (c *Client) recordPoolEvent(eventType, nodeID, format string, args ...interface{}) {
+	if c.events == nil {
+		return
+	}
+	c.events.add(eventType, nodeID, fmt.Sprintf(format, args...))
+}
+
+// GetPoolEvents returns the pool events (scale up/down, leader changes, nodes joining, leaving
+// or drained) recorded after since, oldest first. Pass time.Time{} to get the whole history.
+// Returns nil if the history is disabled, see WithEventHistory.
+func (c *Client) GetPoolEvents(since time.Time) []PoolEvent {
+	return c.events.since(since)
+}
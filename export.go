@@ -0,0 +1,73 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	orm "github.com/medatechnology/simpleorm"
+	"github.com/medatechnology/suresql"
+)
+
+//------------------------------------------------------------------
+// EXPORT METHODS
+//------------------------------------------------------------------
+
+// ExportJSONL writes every row of the query to w as one JSON object per line (JSON lines),
+// see ExportJSONLContext
+// Usage:
+//
+//	err := db.ExportJSONL(file, "SELECT * FROM orders WHERE created_at > '2024-01-01'")
+func (c *Client) ExportJSONL(w io.Writer, sql string) error {
+	return c.ExportJSONLContext(context.Background(), w, sql)
+}
+
+// ExportJSONLContext writes every row of the query to w as one JSON object per line.
+// The server has no cursor, so the query is fetched in pages of DEFAULT_EXPORT_PAGE_SIZE rows
+// (SELECT * FROM (sql) LIMIT ... OFFSET ...) and w is flushed after each page, big results are never
+// held in memory at once. Give the query an ORDER BY so the pages are stable. Rows are encoded
+// with the configured JSONCodec, so numbers stay numbers. When ctx is cancelled the export stops
+// after the current page and returns ctx.Err(), the rows already written are flushed.
+func (c *Client) ExportJSONLContext(ctx context.Context, w io.Writer, sql string) error {
+	query := strings.TrimRight(strings.TrimSpace(sql), ";")
+	writer := bufio.NewWriter(w)
+
+	for offset := 0; ; offset += DEFAULT_EXPORT_PAGE_SIZE {
+		if err := ctx.Err(); err != nil {
+			writer.Flush()
+			return err
+		}
+		req := &suresql.SQLRequest{
+			ParamSQL: []orm.ParametereizedSQL{{
+				Query: fmt.Sprintf("SELECT * FROM (%s) LIMIT %d OFFSET %d", query, DEFAULT_EXPORT_PAGE_SIZE, offset),
+			}},
+		}
+		response, err := sendRequestContext[suresql.QueryResponseSQL](ctx, c, "POST", "/db/api/querysql", req, RequestTypeSQLQuery, AUTO_REFRESH, FALLBACK_LEADER)
+		if err != nil {
+			writer.Flush()
+			return err
+		}
+		if len(response) == 0 {
+			break
+		}
+
+		for i, rec := range response[0].Records {
+			line, err := c.Config.codec().Marshal(rec.Data)
+			if err != nil {
+				writer.Flush()
+				return fmt.Errorf("failed to encode row %d: %w", offset+i, err)
+			}
+			writer.Write(line)
+			writer.WriteByte('\n')
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if len(response[0].Records) < DEFAULT_EXPORT_PAGE_SIZE {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+//------------------------------------------------------------------
+// FAILOVER POLICY
+//------------------------------------------------------------------
+
+// FailoverPolicy returns the node IDs to try, in order, after a read failed on failedNodeID (for a
+// request with FALLBACK_LEADER). leaderNodeID is the current leader (empty if unknown) and followers
+// are the other nodes of the read pool in round-robin order, without the failed, leader and drained
+// nodes. Returning leaderNodeID means the leader connection. Unknown or repeated nodes and the failed
+// node are skipped. Writes always fall back to the leader.
+type FailoverPolicy func(failedNodeID, leaderNodeID string, followers []string) []string
+
+// FailoverLeader falls back to the leader only, this is the default
+func FailoverLeader() FailoverPolicy {
+	return func(failedNodeID, leaderNodeID string, followers []string) []string {
+		return []string{leaderNodeID}
+	}
+}
+
+// FailoverFollowersFirst tries up to maxFollowers other followers (all of them if 0) before the leader,
+// so a flaky follower does not send all its read traffic to the leader
+func FailoverFollowersFirst(maxFollowers int) FailoverPolicy {
+	return func(failedNodeID, leaderNodeID string, followers []string) []string {
+		if maxFollowers > 0 && len(followers) > maxFollowers {
+			followers = followers[:maxFollowers]
+		}
+		return append(append([]string(nil), followers...), leaderNodeID)
+	}
+}
+
+// FailoverOrder tries the given nodes in that order, nodes without connections are skipped.
+// Add the leader node ID to the list to fall back to the leader at all.
+func FailoverOrder(nodeIDs ...string) FailoverPolicy {
+	return func(failedNodeID, leaderNodeID string, followers []string) []string {
+		return nodeIDs
+	}
+}
+
+type requestTypeCtx struct{}
+
+// contextWithRequestType keeps the request type for the failover, the endpoint alone does not tell a
+// write through /db/api/querysql (ie: INSERT ... RETURNING) from a read
+func contextWithRequestType(ctx context.Context, reqType RequestType) context.Context {
+	return context.WithValue(ctx, requestTypeCtx{}, reqType)
+}
+
+func requestTypeFromContext(ctx context.Context, endpoint string) RequestType {
+	if reqType, ok := ctx.Value(requestTypeCtx{}).(RequestType); ok {
+		return reqType
+	}
+	return requestTypeOf(endpoint)
+}
+
+// failoverConnections returns the connections to retry a request that failed on conn, in order
+func (c *Client) failoverConnections(ctx context.Context, conn *Connection, endpoint string) []*Connection {
+	policy := c.Config.FailoverPolicy
+	if policy == nil || requestTypeFromContext(ctx, endpoint).isWrite() {
+		policy = FailoverLeader()
+	}
+
+	leaderNodeID := c.leaderNode()
+	var followers []string
+	for _, nodeID := range c.readPool.nodeIDs() {
+		if nodeID != conn.NodeID && nodeID != leaderNodeID {
+			followers = append(followers, nodeID)
+		}
+	}
+
+	var connections []*Connection
+	tried := make(map[string]bool)
+	for _, nodeID := range policy(conn.NodeID, leaderNodeID, followers) {
+		// the leader connection is still worth a try when a pooled connection to the leader failed
+		if tried[nodeID] || (nodeID == conn.NodeID && nodeID != leaderNodeID) {
+			continue
+		}
+		tried[nodeID] = true
+		if nodeID == leaderNodeID {
+			connections = append(connections, c.getLeaderConnection())
+			continue
+		}
+		if next, err := c.readPool.GetConnectionForNode(nodeID); err == nil {
+			connections = append(connections, next)
+		}
+	}
+	return connections
+}
+
+// failover retries a request that failed on conn with the connections of the FailoverPolicy
+func (c *Client) failover(ctx context.Context, conn *Connection, method, endpoint string, body interface{}, withToken, autorefresh bool, cause error) (json.RawMessage, error) {
+	err := fmt.Errorf("api-call failed, err: %w", cause)
+	for _, next := range c.failoverConnections(ctx, conn, endpoint) {
+		data, errF := c.doRequestToPool(ctx, next, method, endpoint, body, withToken, autorefresh, NO_FALLBACK)
+		if errF == nil {
+			return data, nil
+		}
+		if next == c.leaderConn {
+			err = fmt.Errorf("api-call fallback to leader failed, err:%w", errF)
+		} else {
+			err = fmt.Errorf("api-call fallback to node %s failed, err:%w", next.NodeID, errF)
+		}
+	}
+	return nil, err
+}
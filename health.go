@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//------------------------------------------------------------------
+// HEALTH CHECK
+//------------------------------------------------------------------
+
+// HealthStatus is the overall classification of a HealthReport
+type HealthStatus string
+
+const (
+	HealthHealthy   HealthStatus = "healthy"   // leader and all nodes are reachable
+	HealthDegraded  HealthStatus = "degraded"  // leader is reachable but some nodes are not (or have no connections)
+	HealthUnhealthy HealthStatus = "unhealthy" // leader is unreachable
+)
+
+// NodeHealth is the reachability and pool state of a single node
+type NodeHealth struct {
+	NodeID           string
+	URL              string
+	IsLeader         bool
+	Reachable        bool
+	Latency          time.Duration
+	Error            string
+	ReadConnections  int
+	WriteConnections int
+	Draining         bool
+}
+
+// HealthReport bundles reachability, leader and pool information, ie: for a /healthz handler
+type HealthReport struct {
+	Status           HealthStatus
+	Reasons          []string // why the status is not healthy
+	CheckedAt        time.Time
+	Leader           string // leader URL reported by the cluster
+	LeaderChanged    bool   // leader differs from the one in the cached status
+	Nodes            map[string]NodeHealth
+	FullNodeCoverage bool // every node has at least one pooled connection
+}
+
+// Ping checks the leader is reachable and the token is valid
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.getStatusWithoutLock(ctx)
+	return err
+}
+
+// HealthCheck pings the leader and every known node (in parallel, using their pooled connections)
+// and classifies the result: Unhealthy when the leader is unreachable, Degraded when some nodes
+// are unreachable or have no connections, Healthy otherwise.
+func (c *Client) HealthCheck(ctx context.Context) HealthReport {
+	report := HealthReport{
+		Status:    HealthHealthy,
+		CheckedAt: time.Now(),
+		Nodes:     make(map[string]NodeHealth),
+	}
+	cached := c.getStatus()
+
+	status, err := c.getStatusWithoutLock(ctx)
+	if err != nil {
+		report.Status = HealthUnhealthy
+		report.Reasons = append(report.Reasons, fmt.Sprintf("leader unreachable: %v", err))
+		if cached == nil {
+			return report
+		}
+		// still report the pools of the nodes we know about
+		status = *cached
+	} else {
+		report.Leader = status.Leader
+		report.LeaderChanged = cached != nil && cached.Leader != "" && cached.Leader != status.Leader
+		c.trackLeader(&status)
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for nodeID, node := range nodesFromStatus(&status) {
+		readConns := c.readPool.GetAllConnectionsForNode(nodeID)
+		writeConns := c.writePool.GetAllConnectionsForNode(nodeID)
+		health := NodeHealth{
+			NodeID:           nodeID,
+			URL:              node.URL,
+			IsLeader:         node.IsLeader,
+			ReadConnections:  len(readConns),
+			WriteConnections: len(writeConns),
+			Draining:         c.IsNodeDraining(nodeID),
+		}
+
+		// use any pooled connection of the node to ping it
+		conns := append(readConns, writeConns...)
+		if len(conns) == 0 {
+			health.Error = "no connection to node"
+			report.Nodes[nodeID] = health
+			continue
+		}
+		wg.Add(1)
+		go func(conn *Connection, health NodeHealth) {
+			defer wg.Done()
+			start := time.Now()
+			_, err := c.doRequestToPool(ctx, conn, "GET", "/db/api/status", nil, WITH_TOKEN, AUTO_REFRESH, NO_FALLBACK)
+			health.Latency = time.Since(start)
+			health.Reachable = err == nil
+			if err != nil {
+				health.Error = err.Error()
+			}
+			mutex.Lock()
+			report.Nodes[health.NodeID] = health
+			mutex.Unlock()
+		}(conns[0], health)
+	}
+	wg.Wait()
+
+	report.FullNodeCoverage = true
+	for nodeID, health := range report.Nodes {
+		if health.ReadConnections+health.WriteConnections == 0 {
+			report.FullNodeCoverage = false
+		}
+		if health.Reachable {
+			continue
+		}
+		if health.IsLeader && report.Status != HealthUnhealthy {
+			report.Status = HealthUnhealthy
+			report.Reasons = append(report.Reasons, fmt.Sprintf("leader node %s unreachable: %s", nodeID, health.Error))
+			continue
+		}
+		if report.Status == HealthHealthy {
+			report.Status = HealthDegraded
+		}
+		report.Reasons = append(report.Reasons, fmt.Sprintf("node %s unreachable: %s", nodeID, health.Error))
+	}
+	return report
+}
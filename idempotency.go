@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	orm "github.com/medatechnology/simpleorm"
+	"github.com/medatechnology/suresql"
+)
+
+//------------------------------------------------------------------
+// IDEMPOTENCY KEYS
+//------------------------------------------------------------------
+
+// IDEMPOTENCY_KEY_HEADER is sent with write requests that have an idempotency key.
+//
+// Server-side contract: the server must remember the key (together with the authenticated user)
+// and the response of the first request with that key for at least as long as the client may retry
+// (a few HTTP timeouts). When a request arrives with a key it has already processed it must
+// return the stored response instead of executing the statements again. A key that is still being
+// processed should be answered with 409 Conflict. Servers that ignore the header simply execute
+// every request, so retried inserts may be duplicated.
+const IDEMPOTENCY_KEY_HEADER = "Idempotency-Key"
+
+type idempotencyKeyCtx struct{}
+
+// contextWithIdempotencyKey attaches the key to all requests (including retries) made with ctx,
+// an empty key removes it
+func contextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+func idempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyCtx{}).(string)
+	return key
+}
+
+// newIdempotencyKey returns a random UUID (version 4)
+func newIdempotencyKey() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// ExecManySQLParameterizedIdempotent executes parameterized SQL statements with the given
+// idempotency key, so the call can be safely repeated (ie: after a timeout) with the same key.
+// See IDEMPOTENCY_KEY_HEADER for what the server has to support.
+func (c *Client) ExecManySQLParameterizedIdempotent(key string, paramSQLs []orm.ParametereizedSQL) ([]orm.BasicSQLResult, error) {
+	req := &suresql.SQLRequest{
+		ParamSQL: paramSQLs,
+	}
+	ctx := contextWithIdempotencyKey(context.Background(), key)
+	response, err := sendRequestContext[suresql.SQLResponse](ctx, c, "POST", "/db/api/sql", req, RequestTypeSQLExec, AUTO_REFRESH, FALLBACK_LEADER)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Results) == 0 {
+		return nil, errors.New("no results returned")
+	}
+	return response.Results, nil
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"sync"
+)
+
+//------------------------------------------------------------------
+// IN-FLIGHT REQUEST REGISTRY
+//------------------------------------------------------------------
+
+// inflightRegistry keeps the cancel funcs of the requests in progress, keyed by an internal
+// request ID. The zero value is ready to use.
+type inflightRegistry struct {
+	mutex   sync.Mutex
+	nextID  uint64
+	cancels map[uint64]context.CancelFunc
+}
+
+// register returns a cancellable ctx for a request and the func to call when the request is done
+func (r *inflightRegistry) register(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	r.mutex.Lock()
+	if r.cancels == nil {
+		r.cancels = make(map[uint64]context.CancelFunc)
+	}
+	r.nextID++
+	id := r.nextID
+	r.cancels[id] = cancel
+	r.mutex.Unlock()
+
+	return ctx, func() {
+		r.mutex.Lock()
+		delete(r.cancels, id)
+		r.mutex.Unlock()
+		cancel()
+	}
+}
+
+// cancelAll cancels every registered request and returns how many there were
+func (r *inflightRegistry) cancelAll() int {
+	r.mutex.Lock()
+	cancels := r.cancels
+	r.cancels = nil
+	r.mutex.Unlock()
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return len(cancels)
+}
+
+// CancelAll is the emergency stop: it cancels the context of every request in progress, so they
+// return right away with an error wrapping context.Canceled (including requests waiting for a
+// connection or a rate limit token). Requests started afterwards are not affected, and writes the
+// server already received may still be applied. Returns the number of cancelled requests.
+// Unlike Close, nothing is released or waited for.
+func (c *Client) CancelAll() int {
+	return c.inflight.cancelAll()
+}
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//------------------------------------------------------------------
+// LATENCY HISTOGRAMS
+//------------------------------------------------------------------
+
+// latencyHistogram counts request durations into fixed buckets, the last bucket is the overflow
+type latencyHistogram struct {
+	counts []int64
+	count  int64
+	sum    time.Duration
+	max    time.Duration
+}
+
+// latencyRecorder keeps a histogram per endpoint and per node, all sharing the same buckets
+type latencyRecorder struct {
+	mutex     sync.Mutex
+	bounds    []time.Duration
+	endpoints map[string]*latencyHistogram
+	nodes     map[string]*latencyHistogram
+	errors    map[string]int64 // failed requests per endpoint
+}
+
+func newLatencyRecorder(bounds []time.Duration) *latencyRecorder {
+	if len(bounds) == 0 {
+		bounds = DEFAULT_LATENCY_BUCKETS
+	}
+	sorted := make([]time.Duration, len(bounds))
+	copy(sorted, bounds)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return &latencyRecorder{
+		bounds:    sorted,
+		endpoints: make(map[string]*latencyHistogram),
+		nodes:     make(map[string]*latencyHistogram),
+		errors:    make(map[string]int64),
+	}
+}
+
+// record adds one request duration for the endpoint and the node
+func (r *latencyRecorder) record(endpoint, nodeID string, elapsed time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.histogram(r.endpoints, endpoint).observe(r.bounds, elapsed)
+	r.histogram(r.nodes, nodeID).observe(r.bounds, elapsed)
+}
+
+// recordError counts a failed request of the endpoint
+func (r *latencyRecorder) recordError(endpoint string) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.errors[endpoint]++
+}
+
+func (r *latencyRecorder) histogram(histograms map[string]*latencyHistogram, key string) *latencyHistogram {
+	h, exists := histograms[key]
+	if !exists {
+		h = &latencyHistogram{counts: make([]int64, len(r.bounds)+1)}
+		histograms[key] = h
+	}
+	return h
+}
+
+func (h *latencyHistogram) observe(bounds []time.Duration, elapsed time.Duration) {
+	idx := sort.Search(len(bounds), func(i int) bool { return elapsed <= bounds[i] })
+	h.counts[idx]++
+	h.count++
+	h.sum += elapsed
+	if elapsed > h.max {
+		h.max = elapsed
+	}
+}
+
+// percentile estimates the q-th (0..1) percentile by interpolating inside the bucket it falls in
+func (h *latencyHistogram) percentile(bounds []time.Duration, q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	target := q * float64(h.count)
+	var cumulative int64
+	for i, count := range h.counts {
+		if count == 0 || float64(cumulative+count) < target {
+			cumulative += count
+			continue
+		}
+		// overflow bucket has no upper bound, best estimate is the max seen
+		if i == len(bounds) {
+			return h.max
+		}
+		lower := time.Duration(0)
+		if i > 0 {
+			lower = bounds[i-1]
+		}
+		upper := min(bounds[i], h.max)
+		if upper < lower {
+			return upper
+		}
+		fraction := (target - float64(cumulative)) / float64(count)
+		return lower + time.Duration(fraction*float64(upper-lower))
+	}
+	return h.max
+}
+
+func (h *latencyHistogram) stats(bounds []time.Duration) LatencyStats {
+	stats := LatencyStats{
+		Count:   h.count,
+		Max:     h.max,
+		P50:     h.percentile(bounds, 0.50),
+		P95:     h.percentile(bounds, 0.95),
+		P99:     h.percentile(bounds, 0.99),
+		Buckets: make([]LatencyBucket, 0, len(h.counts)),
+	}
+	if h.count > 0 {
+		stats.Mean = h.sum / time.Duration(h.count)
+	}
+	for i, count := range h.counts {
+		bucket := LatencyBucket{Count: count}
+		if i < len(bounds) {
+			bucket.UpperBound = bounds[i]
+		}
+		stats.Buckets = append(stats.Buckets, bucket)
+	}
+	return stats
+}
+
+// snapshot returns the stats of all histograms
+func (r *latencyRecorder) snapshot() LatencyMetrics {
+	metrics := LatencyMetrics{
+		Endpoints: make(map[string]LatencyStats),
+		Nodes:     make(map[string]LatencyStats),
+	}
+	if r == nil {
+		return metrics
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for endpoint, h := range r.endpoints {
+		metrics.Endpoints[endpoint] = h.stats(r.bounds)
+	}
+	for nodeID, h := range r.nodes {
+		metrics.Nodes[nodeID] = h.stats(r.bounds)
+	}
+	return metrics
+}
+
+func (r *latencyRecorder) reset() {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.endpoints = make(map[string]*latencyHistogram)
+	r.nodes = make(map[string]*latencyHistogram)
+	r.errors = make(map[string]int64)
+}
+
+// endpointSnapshot returns the requests, errors and latency per endpoint
+func (r *latencyRecorder) endpointSnapshot() map[string]EndpointMetrics {
+	metrics := make(map[string]EndpointMetrics)
+	if r == nil {
+		return metrics
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for endpoint, h := range r.endpoints {
+		metrics[endpoint] = EndpointMetrics{
+			Endpoint: endpoint,
+			Type:     requestTypeOf(endpoint),
+			Requests: h.count,
+			Errors:   r.errors[endpoint],
+			Latency:  h.stats(r.bounds),
+		}
+	}
+	return metrics
+}
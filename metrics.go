@@ -1,6 +1,7 @@
 package client
 
 import (
+	"sync/atomic"
 	"time"
 )
 
@@ -43,16 +44,18 @@ func (c *Client) GetPoolMetrics() PoolMetrics {
 
 	// Calculate per-node metrics
 	now := time.Now()
+	idleTimeout := c.hot().idleTimeout
+	status := c.getStatus()
 
 	for nodeID := range nodeIDs {
 
 		// Get node info
 		var url, mode string
-		if c.status != nil && nodeID == c.status.NodeID {
+		if status != nil && nodeID == status.NodeID {
 			url = c.Config.ServerURL
-			mode = c.status.Mode
-		} else if c.status != nil {
-			for _, peer := range c.status.Peers {
+			mode = status.Mode
+		} else if status != nil {
+			for _, peer := range status.Peers {
 				if peer.NodeID == nodeID {
 					url = peer.URL
 					mode = peer.Mode
@@ -69,7 +72,7 @@ func (c *Client) GetPoolMetrics() PoolMetrics {
 		// Count idle connections
 		idleCount := 0
 		for _, conn := range allConns {
-			if now.Sub(conn.LastUsed) > c.PoolConfig.IdleTimeout {
+			if now.Sub(conn.LastUsed) > idleTimeout {
 				idleCount++
 			}
 		}
@@ -109,7 +112,12 @@ func (c *Client) GetPoolMetrics() PoolMetrics {
 			LastScaleDown:      statsRead.LastScaleDown,
 			ScaleUpEvents:      statsRead.ScaleUpEvents + statsWrite.ScaleUpEvents,
 			ScaleDownEvents:    statsRead.ScaleDownEvents + statsWrite.ScaleDownEvents,
+			Draining:           c.IsNodeDraining(nodeID),
 		}
+		nodeMetrics.InFlightRequests, nodeMetrics.RejectedRequests, nodeMetrics.QueuedRequests = c.bulkhead.stats(nodeID)
+		nodeMetrics.Protocol = c.nodeProtocol(nodeID)
+		nodeMetrics.Saturated = statsRead.saturated || statsWrite.saturated
+		c.tokenMetrics(&nodeMetrics, allConns)
 
 		statsRead.HistoryMutex.Unlock()
 		statsWrite.HistoryMutex.Unlock()
@@ -126,6 +134,14 @@ func (c *Client) GetPoolMetrics() PoolMetrics {
 	metrics.RequestsPerSecond = float64(totalRecentRequests) / 60.0
 	metrics.ScaleUpEvents = totalScaleUpEvents
 	metrics.ScaleDownEvents = totalScaleDownEvents
+	metrics.ThrottledReads = c.readLimiter.Throttled()
+	metrics.ThrottledWrites = c.writeLimiter.Throttled()
+	metrics.AcquireWaits = atomic.LoadInt64(&c.acquireWaits)
+	metrics.AcquireTimeouts = atomic.LoadInt64(&c.acquireTimeouts)
+	metrics.AcquireWaiters = c.bulkhead.waiting()
+	metrics.DedupedReads = atomic.LoadInt64(&c.dedupedReads)
+	metrics.AuthCalls = atomic.LoadInt64(&c.authCalls)
+	metrics.ServerThrottles = atomic.LoadInt64(&c.serverThrottles)
 
 	return metrics
 }
@@ -143,7 +159,7 @@ func (c *Client) ConnectionStats() map[string]interface{} {
 			"mode":         c.leaderConn.Mode,
 			"last_used":    c.leaderConn.LastUsed,
 			"created":      c.leaderConn.Created,
-			"last_refresh": c.leaderConn.LastRefresh,
+			"last_refresh": c.leaderConn.lastRefresh(),
 		}
 	}
 
@@ -182,7 +198,7 @@ func (c *Client) ConnectionStats() map[string]interface{} {
 				"mode":         conn.Mode,
 				"last_used":    conn.LastUsed,
 				"created":      conn.Created,
-				"last_refresh": conn.LastRefresh,
+				"last_refresh": conn.lastRefresh(),
 			})
 		}
 
@@ -195,22 +211,22 @@ func (c *Client) ConnectionStats() map[string]interface{} {
 				"mode":         conn.Mode,
 				"last_used":    conn.LastUsed,
 				"created":      conn.Created,
-				"last_refresh": conn.LastRefresh,
+				"last_refresh": conn.lastRefresh(),
 			})
 		}
 
-		// Get node usage stats. TODO: add the write as well!
+		// Get node usage stats, "usage" is the read pool (kept for backward compatibility)
+		c.scalingMutex.Lock()
+		readStats, readExists := c.statsPerNodeRead[nodeID]
+		writeStats, writeExists := c.statsPerNodeWrite[nodeID]
+		c.scalingMutex.Unlock()
 		usage := make(map[string]interface{})
-		if stats, exists := c.statsPerNodeRead[nodeID]; exists {
-			stats.HistoryMutex.Lock()
-			usage = map[string]interface{}{
-				"active_requests":   stats.ActiveRequests,
-				"last_scale_up":     stats.LastScaleUp,
-				"last_scale_down":   stats.LastScaleDown,
-				"scale_up_events":   stats.ScaleUpEvents,
-				"scale_down_events": stats.ScaleDownEvents,
-			}
-			stats.HistoryMutex.Unlock()
+		if readExists {
+			usage = usageStatsMap(readStats)
+		}
+		writeUsage := make(map[string]interface{})
+		if writeExists {
+			writeUsage = usageStatsMap(writeStats)
 		}
 
 		nodeStats[nodeID] = map[string]interface{}{
@@ -219,25 +235,50 @@ func (c *Client) ConnectionStats() map[string]interface{} {
 			"read_connections":  readPoolInfo,
 			"write_connections": writePoolInfo,
 			"usage":             usage,
+			"write_usage":       writeUsage,
+			"draining":          c.IsNodeDraining(nodeID),
+			"protocol":          c.nodeProtocol(nodeID),
 		}
 	}
 
 	stats["node_pools"] = nodeStats
 
 	// Add pool configuration
+	hot := c.hot()
 	stats["pool_config"] = map[string]interface{}{
 		"min_pool_size":       c.PoolConfig.MinPoolSize,
-		"scale_up_threshold":  c.PoolConfig.ScaleUpThreshold,
-		"idle_timeout":        c.PoolConfig.IdleTimeout.String(),
-		"scale_down_interval": c.PoolConfig.ScaleDownInterval.String(),
-		"connection_ttl":      c.PoolConfig.ConnectionTTL.String(),
-		"scale_up_batch_size": c.PoolConfig.ScaleUpBatchSize,
+		"scale_up_threshold":  hot.scaleUpThreshold,
+		"idle_timeout":        hot.idleTimeout.String(),
+		"scale_down_interval": hot.scaleDownInterval.String(),
+		"connection_ttl":      hot.connectionTTL.String(),
+		"scale_up_batch_size": hot.scaleUpBatchSize,
 		"usage_window_size":   c.PoolConfig.UsageWindowSize,
 	}
 
 	return stats
 }
 
+// nodeProtocol returns the HTTP protocol negotiated with the node, empty before the first response
+func (c *Client) nodeProtocol(nodeID string) string {
+	if proto, ok := c.nodeProtocols.Load(nodeID); ok {
+		return proto.(string)
+	}
+	return ""
+}
+
+// usageStatsMap returns the usage part of ConnectionStats for a node's read or write stats
+func usageStatsMap(stats *ConnectionStats) map[string]interface{} {
+	stats.HistoryMutex.Lock()
+	defer stats.HistoryMutex.Unlock()
+	return map[string]interface{}{
+		"active_requests":   stats.ActiveRequests,
+		"last_scale_up":     stats.LastScaleUp,
+		"last_scale_down":   stats.LastScaleDown,
+		"scale_up_events":   stats.ScaleUpEvents,
+		"scale_down_events": stats.ScaleDownEvents,
+	}
+}
+
 // GetPoolHealth returns a simplified health status of the connection pool
 func (c *Client) GetPoolHealth() map[string]interface{} {
 	health := make(map[string]interface{})
@@ -253,7 +294,7 @@ func (c *Client) GetPoolHealth() map[string]interface{} {
 
 	// Calculate active requests
 	activeRequests := 0
-	for _, stats := range c.statsPerNodeRead {
+	for _, stats := range c.allNodeStats(IS_READ) {
 		stats.HistoryMutex.Lock()
 		activeRequests += stats.ActiveRequests
 		stats.HistoryMutex.Unlock()
@@ -288,8 +329,8 @@ func (c *Client) GetPoolHealth() map[string]interface{} {
 
 	// Calculate node coverage
 	nodeCount := 0
-	if c.status != nil {
-		nodeCount = 1 + len(c.status.Peers) // Leader + peers
+	if status := c.getStatus(); status != nil {
+		nodeCount = 1 + len(status.Peers) // Leader + peers
 
 		// Get unique nodes with connections
 		nodesWithConnections := make(map[string]bool)
@@ -324,11 +365,12 @@ func (c *Client) GetNodePoolMetrics(nodeID string) (NodePoolMetrics, bool) {
 
 	// Get node info
 	var url, mode string
-	if c.status != nil && nodeID == c.status.NodeID {
+	status := c.getStatus()
+	if status != nil && nodeID == status.NodeID {
 		url = c.Config.ServerURL
-		mode = c.status.Mode
-	} else if c.status != nil {
-		for _, peer := range c.status.Peers {
+		mode = status.Mode
+	} else if status != nil {
+		for _, peer := range status.Peers {
 			if peer.NodeID == nodeID {
 				url = peer.URL
 				mode = peer.Mode
@@ -339,11 +381,12 @@ func (c *Client) GetNodePoolMetrics(nodeID string) (NodePoolMetrics, bool) {
 
 	// Count idle connections
 	now := time.Now()
+	idleTimeout := c.hot().idleTimeout
 	idleCount := 0
 	allConns := append(readConns, writeConns...)
 
 	for _, conn := range allConns {
-		if now.Sub(conn.LastUsed) > c.PoolConfig.IdleTimeout {
+		if now.Sub(conn.LastUsed) > idleTimeout {
 			idleCount++
 		}
 	}
@@ -370,9 +413,32 @@ func (c *Client) GetNodePoolMetrics(nodeID string) (NodePoolMetrics, bool) {
 		LastScaleDown:      stats.LastScaleDown,
 		ScaleUpEvents:      stats.ScaleUpEvents,
 		ScaleDownEvents:    stats.ScaleDownEvents,
+		Draining:           c.IsNodeDraining(nodeID),
 	}
+	metrics.InFlightRequests, metrics.RejectedRequests, metrics.QueuedRequests = c.bulkhead.stats(nodeID)
+	metrics.Protocol = c.nodeProtocol(nodeID)
+	metrics.Saturated = stats.saturated
+	c.tokenMetrics(&metrics, allConns)
 
 	stats.HistoryMutex.Unlock()
 
 	return metrics, true
 }
+
+// GetLatencyMetrics returns request latency percentiles per endpoint and per node since start
+// (or since the last ResetLatencyMetrics)
+func (c *Client) GetLatencyMetrics() LatencyMetrics {
+	return c.latency.snapshot()
+}
+
+// GetEndpointMetrics returns the requests, errors and latency per API endpoint (ie: /db/api/querysql
+// for SQL reads, /db/api/sql for SQL writes) since start (or since the last ResetLatencyMetrics),
+// so the read/write mix of the traffic can be seen
+func (c *Client) GetEndpointMetrics() map[string]EndpointMetrics {
+	return c.latency.endpointSnapshot()
+}
+
+// ResetLatencyMetrics clears all latency histograms, ie: to measure a single load test run
+func (c *Client) ResetLatencyMetrics() {
+	c.latency.reset()
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+)
+
+//------------------------------------------------------------------
+// MIDDLEWARE CHAIN
+//------------------------------------------------------------------
+
+// Handler sends a request to the server and returns the Data part of the standard response
+// as raw JSON. The body is the request payload before it is encoded.
+type Handler func(ctx context.Context, method, endpoint string, body interface{}) (json.RawMessage, error)
+
+// Middleware wraps a Handler to add cross-cutting behavior (signing, metrics, mocking, caching).
+// A middleware can short-circuit by returning without calling next.
+// Usage:
+//
+//	logger := func(next client.Handler) client.Handler {
+//	  return func(ctx context.Context, method, endpoint string, body interface{}) (json.RawMessage, error) {
+//	    start := time.Now()
+//	    data, err := next(ctx, method, endpoint, body)
+//	    log.Println(method, endpoint, time.Since(start), err)
+//	    return data, err
+//	  }
+//	}
+//	config := client.NewClientConfig(client.WithMiddleware(logger))
+type Middleware func(next Handler) Handler
+
+// chainMiddlewares wraps the handler so the first registered middleware is the outermost one
+func chainMiddlewares(middlewares []Middleware, handler Handler) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
@@ -1,36 +1,101 @@
 package client
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
-	"path/filepath"
+	"path"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	orm "github.com/medatechnology/simpleorm"
+	"github.com/medatechnology/suresql"
 )
 
 const MIGRATION_TABLE = "_client_migrations"
 
+// Guard directive of a migration file, see Migrate
+const MIGRATION_GUARD_DIRECTIVE = "-- guard:"
+
+// Migration lock, only one instance applies migrations at a time
+const (
+	MIGRATION_LOCK_TABLE              = "_client_migrations_lock"
+	DEFAULT_MIGRATION_LOCK_TIMEOUT    = 1 * time.Minute  // how long Migrate waits for the lock
+	DEFAULT_MIGRATION_LOCK_STALE      = 15 * time.Minute // a lock older than this was left by a dead instance
+	DEFAULT_MIGRATION_LOCK_RETRY_WAIT = 1 * time.Second
+)
+
+// ErrMigrationLocked is returned by Migrate when another instance held the migration lock for LockTimeout
+var ErrMigrationLocked = errors.New("migrations are locked by another instance")
+
 // MigrationService handles database migrations
 type MigrationService struct {
 	client *Client
+
+	LockTimeout    time.Duration // How long Migrate waits for another instance to finish, DEFAULT_MIGRATION_LOCK_TIMEOUT
+	LockStaleAfter time.Duration // A lock older than this is taken over, DEFAULT_MIGRATION_LOCK_STALE
+
+	// Execute the statements of a file one by one instead of sending the whole file at once,
+	// for servers that only run the first statement of a call, see splitSQLStatements
+	SplitStatements bool
 }
 
 // NewMigrationService creates a new migration service
 func NewMigrationService(client *Client) *MigrationService {
-	return &MigrationService{client: client}
+	return &MigrationService{
+		client:         client,
+		LockTimeout:    DEFAULT_MIGRATION_LOCK_TIMEOUT,
+		LockStaleAfter: DEFAULT_MIGRATION_LOCK_STALE,
+	}
 }
 
 // Migrate scans the provided directory for .sql files and applies them
 // if they haven't been applied yet.
+//
+// A migration can be made conditional with guard directives in the comment lines at the top of
+// the file (before the first SQL line), one query per line:
+//
+//	-- guard: if <query>      run the migration only if the query returns at least one row
+//	-- guard: unless <query>  run the migration only if the query returns no rows
+//
+// ie: to add a column only where it is missing
+//
+//	-- guard: unless SELECT 1 FROM pragma_table_info('users') WHERE name = 'email'
+//	ALTER TABLE users ADD COLUMN email TEXT;
+//
+// With several guards all of them must pass. The queries run on the leader. A migration whose
+// guards don't pass is skipped but still recorded as applied, so it is not evaluated again.
+//
+// Instances starting together (ie: a rolling deploy) don't race: the pending migrations are applied
+// while holding a lock row in MIGRATION_LOCK_TABLE, the other instances wait up to LockTimeout
+// (then fail with ErrMigrationLocked) and find the migrations applied. The lock is released when
+// Migrate returns, even on a panic. The holder refreshes the lock while migrating, so a lock older
+// than LockStaleAfter was left by an instance that died and is taken over.
 func (m *MigrationService) Migrate(dir string) error {
+	return m.MigrateFS(os.DirFS(dir), ".")
+}
+
+// MigrateFS is Migrate reading the .sql files of dir in fsys, ie: migrations embedded in the binary
+// with go:embed, so they don't have to ship as separate files. dir uses forward slashes ("." for the root).
+// Usage:
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+//	err := ms.MigrateFS(migrations, "migrations")
+func (m *MigrationService) MigrateFS(fsys fs.FS, dir string) (err error) {
 	// 1. Ensure migration table exists
-	err := m.ensureMigrationTable()
+	err = m.ensureMigrationTable()
 	if err != nil {
 		return fmt.Errorf("failed to ensure migration table: %w", err)
 	}
 
 	// 2. Read migration files
-	files, err := m.readMigrationFiles(dir)
+	files, err := m.readMigrationFiles(fsys, dir)
 	if err != nil {
 		return fmt.Errorf("failed to read migration files: %w", err)
 	}
@@ -40,13 +105,24 @@ func (m *MigrationService) Migrate(dir string) error {
 		return nil
 	}
 
-	// 3. Get applied migrations
+	// 3. Wait for the other instances, only the lock holder reads and applies the pending migrations
+	release, err := m.acquireLock()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errR := release(); errR != nil && err == nil {
+			err = fmt.Errorf("failed to release migration lock: %w", errR)
+		}
+	}()
+
+	// 4. Get applied migrations
 	applied, err := m.getAppliedMigrations()
 	if err != nil {
 		return fmt.Errorf("failed to get applied migrations: %w", err)
 	}
 
-	// 4. Apply pending migrations
+	// 5. Apply pending migrations
 	for _, file := range files {
 		// Check if already applied
 		if _, exists := applied[file.Name]; exists {
@@ -54,17 +130,169 @@ func (m *MigrationService) Migrate(dir string) error {
 		}
 
 		fmt.Printf("Applying migration: %s... ", file.Name)
-		err := m.applyMigration(file)
+		skipped, err := m.applyMigration(file)
 		if err != nil {
 			fmt.Printf("FAILED\n")
 			return fmt.Errorf("failed to apply migration %s: %w", file.Name, err)
 		}
+		if skipped {
+			fmt.Printf("SKIPPED (guard)\n")
+			continue
+		}
 		fmt.Printf("OK\n")
 	}
 
 	return nil
 }
 
+// acquireLock takes the migration lock, waiting up to LockTimeout for another instance to release it.
+// The returned func releases it.
+func (m *MigrationService) acquireLock() (func() error, error) {
+	createSQL := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			owner TEXT NOT NULL,
+			locked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`, MIGRATION_LOCK_TABLE)
+	if res := m.client.ExecOneSQL(createSQL); res.Error != nil {
+		return nil, fmt.Errorf("failed to ensure migration lock table: %w", res.Error)
+	}
+
+	owner := migrationLockOwner()
+	deadline := time.Now().Add(m.LockTimeout)
+	for {
+		// take over the lock of an instance that died while migrating
+		if m.LockStaleAfter > 0 {
+			m.client.ExecOneSQLParameterized(orm.ParametereizedSQL{
+				Query:  fmt.Sprintf("DELETE FROM %s WHERE locked_at < datetime('now', ?)", MIGRATION_LOCK_TABLE),
+				Values: []interface{}{fmt.Sprintf("-%d seconds", int(m.LockStaleAfter.Seconds()))},
+			})
+		}
+
+		res := m.client.ExecOneSQLParameterized(orm.ParametereizedSQL{
+			Query:  fmt.Sprintf("INSERT OR IGNORE INTO %s (id, owner) VALUES (1, ?)", MIGRATION_LOCK_TABLE),
+			Values: []interface{}{owner},
+		})
+		if res.Error != nil {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", res.Error)
+		}
+		if res.RowsAffected == 1 {
+			stop := m.keepLock(owner)
+			return func() error {
+				stop()
+				res := m.client.ExecOneSQLParameterized(orm.ParametereizedSQL{
+					Query:  fmt.Sprintf("DELETE FROM %s WHERE id = 1 AND owner = ?", MIGRATION_LOCK_TABLE),
+					Values: []interface{}{owner},
+				})
+				return res.Error
+			}, nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("%w (waited %s)", ErrMigrationLocked, m.LockTimeout)
+		}
+		fmt.Println("Waiting for another instance to finish the migrations...")
+		time.Sleep(min(DEFAULT_MIGRATION_LOCK_RETRY_WAIT, time.Until(deadline)))
+	}
+}
+
+// keepLock refreshes locked_at every LockStaleAfter/3 while the migrations run, so a migration running
+// longer than LockStaleAfter is not taken over by another instance. The returned func stops it.
+func (m *MigrationService) keepLock(owner string) func() {
+	if m.LockStaleAfter <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(max(m.LockStaleAfter/3, time.Second))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				m.client.ExecOneSQLParameterized(orm.ParametereizedSQL{
+					Query:  fmt.Sprintf("UPDATE %s SET locked_at = CURRENT_TIMESTAMP WHERE id = 1 AND owner = ?", MIGRATION_LOCK_TABLE),
+					Values: []interface{}{owner},
+				})
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// migrationLockOwner identifies the lock holder, the host and process for whoever looks at the table
+func migrationLockOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), newIdempotencyKey())
+}
+
+// States of a MigrationStatusRow
+const (
+	MIGRATION_PENDING      = "pending"
+	MIGRATION_APPLIED      = "applied"
+	MIGRATION_FILE_MISSING = "applied, file missing"
+)
+
+// MigrationStatusRow is the state of one migration, see MigrationStatus
+type MigrationStatusRow struct {
+	Name      string
+	State     string    // MIGRATION_PENDING, MIGRATION_APPLIED or MIGRATION_FILE_MISSING
+	AppliedAt time.Time // Zero if pending
+}
+
+// MigrationStatus lists the migrations of the directory and the applied ones whose file is not there
+// anymore (MIGRATION_FILE_MISSING), in the order Migrate applies them, with when they were applied.
+// It only reads, the migration table is not created if missing.
+// Usage:
+//
+//	rows, err := ms.MigrationStatus("./migrations")
+//	for _, row := range rows {
+//		fmt.Printf("%-40s %-22s %s\n", row.Name, row.State, row.AppliedAt.Format(time.RFC3339))
+//	}
+func (m *MigrationService) MigrationStatus(dir string) ([]MigrationStatusRow, error) {
+	return m.MigrationStatusFS(os.DirFS(dir), ".")
+}
+
+// MigrationStatusFS is MigrationStatus for the migrations of dir in fsys, see MigrateFS
+func (m *MigrationService) MigrationStatusFS(fsys fs.FS, dir string) ([]MigrationStatusRow, error) {
+	files, err := m.readMigrationFiles(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration files: %w", err)
+	}
+	applied, err := m.getAppliedMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	rows := make([]MigrationStatusRow, 0, len(files)+len(applied))
+	onDisk := make(map[string]bool, len(files))
+	for _, file := range files {
+		onDisk[file.Name] = true
+		row := MigrationStatusRow{Name: file.Name, State: MIGRATION_PENDING}
+		if appliedAt, exists := applied[file.Name]; exists {
+			row.State, row.AppliedAt = MIGRATION_APPLIED, appliedAt
+		}
+		rows = append(rows, row)
+	}
+	for name, appliedAt := range applied {
+		if !onDisk[name] {
+			rows = append(rows, MigrationStatusRow{Name: name, State: MIGRATION_FILE_MISSING, AppliedAt: appliedAt})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return migrationLess(rows[i].Name, rows[j].Name)
+	})
+	return rows, nil
+}
+
 // ensureMigrationTable creates the tracking table if it doesn't exist
 func (m *MigrationService) ensureMigrationTable() error {
 	sql := fmt.Sprintf(`
@@ -88,9 +316,37 @@ type migrationFile struct {
 	Content string
 }
 
-// readMigrationFiles reads and strictly sorts SQL files
-func (m *MigrationService) readMigrationFiles(dir string) ([]migrationFile, error) {
-	entries, err := os.ReadDir(dir)
+// migrationVersion returns the numeric version prefix of a migration file name, the digits before
+// the first underscore or dot (ie: 12 for "12_add_users.sql"). False if there is none.
+func migrationVersion(name string) (uint64, bool) {
+	prefix := name
+	if i := strings.IndexAny(name, "_."); i >= 0 {
+		prefix = name[:i]
+	}
+	version, err := strconv.ParseUint(prefix, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return version, true
+}
+
+// migrationLess orders the migration files by numeric version, so "2_x.sql" comes before "10_x.sql"
+// without zero padding. Files with a version come first, files without one are ordered by name.
+func migrationLess(a, b string) bool {
+	versionA, okA := migrationVersion(a)
+	versionB, okB := migrationVersion(b)
+	switch {
+	case okA && okB && versionA != versionB:
+		return versionA < versionB
+	case okA != okB:
+		return okA
+	}
+	return a < b
+}
+
+// readMigrationFiles reads and strictly sorts the SQL files of dir in fsys
+func (m *MigrationService) readMigrationFiles(fsys fs.FS, dir string) ([]migrationFile, error) {
+	entries, err := fs.ReadDir(fsys, dir)
 	if err != nil {
 		return nil, err
 	}
@@ -99,83 +355,245 @@ func (m *MigrationService) readMigrationFiles(dir string) ([]migrationFile, erro
 	for _, entry := range entries {
 		name := strings.ToLower(entry.Name())
 		if !entry.IsDir() && strings.HasSuffix(name, ".sql") && !strings.HasSuffix(name, ".down.sql") {
-			path := filepath.Join(dir, entry.Name())
-			content, err := os.ReadFile(path)
+			filePath := path.Join(dir, entry.Name())
+			content, err := fs.ReadFile(fsys, filePath)
 			if err != nil {
 				return nil, err
 			}
 
 			files = append(files, migrationFile{
 				Name:    entry.Name(),
-				Path:    path,
+				Path:    filePath,
 				Content: string(content),
 			})
 		}
 	}
 
-	// Sort by name (versions should be prefixed like 00001, 00002)
 	sort.Slice(files, func(i, j int) bool {
-		return files[i].Name < files[j].Name
+		return migrationLess(files[i].Name, files[j].Name)
 	})
 
+	// two files with the same version would apply in an arbitrary order
+	for i := 1; i < len(files); i++ {
+		previous, okP := migrationVersion(files[i-1].Name)
+		version, ok := migrationVersion(files[i].Name)
+		if ok && okP && version == previous {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, files[i-1].Name, files[i].Name)
+		}
+	}
+
 	return files, nil
 }
 
-// getAppliedMigrations returns a set of applied migration names
-func (m *MigrationService) getAppliedMigrations() (map[string]bool, error) {
-	// Structure to match query result
-	type MigrationRecord struct {
-		Name string `json:"name"`
-	}
-
-	sql := fmt.Sprintf("SELECT name FROM %s", MIGRATION_TABLE)
-	// We use SelectManySQL to get raw records map, or if we had a struct we could use that.
-	// SelectManySQL returns []orm.DBRecords.
-	
-	// Since we don't have a ready-made struct mapped in the library for this internal table,
-	// let's use SelectManySQL (generic) and parse manually.
-	
-	// Note: SelectManySQL returns ([]orm.DBRecords, error)
-	// orm.DBRecords is []orm.DBRecord
-	// orm.DBRecord is struct { Data map[string]interface{} }
-	
-	result, err := m.client.SelectOneSQL(sql)
+// getAppliedMigrations returns the applied migration names with when they were applied
+func (m *MigrationService) getAppliedMigrations() (map[string]time.Time, error) {
+	// on the leader, not cached: a follower may not have the migrations another instance just applied
+	req := &suresql.SQLRequest{Statements: []string{fmt.Sprintf("SELECT name, applied_at FROM %s", MIGRATION_TABLE)}}
+	response, err := sendRequest[suresql.QueryResponseSQL](m.client, "POST", "/db/api/querysql", req, RequestTypeSQLWriteQuery, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
-		// If table doesn't exist yet (edge case where ensure failed but didn't error?), return empty
-        // But ensureMigrationTable should have handled it.
-        // Actually, if no rows found (empty table), it returns ErrSQLNoRows.
-        if err.Error() == "sql: no rows in result set" || strings.Contains(err.Error(), "no rows") {
-             return map[string]bool{}, nil
-        }
-		return nil, nil // Assume empty if error is "no rows" 
+		// no table yet when only reading the status
+		if strings.Contains(strings.ToLower(err.Error()), "no such table") {
+			return map[string]time.Time{}, nil
+		}
+		return nil, err
 	}
 
-	applied := make(map[string]bool)
-	for _, rec := range result {
+	applied := make(map[string]time.Time)
+	if len(response) == 0 {
+		return applied, nil
+	}
+	for _, rec := range response[0].Records {
 		if name, ok := rec.Data["name"].(string); ok {
-			applied[name] = true
+			applied[name] = parseAppliedAt(rec.Data["applied_at"])
 		}
 	}
 	return applied, nil
 }
 
-// applyMigration executes the SQL content and records it
-func (m *MigrationService) applyMigration(file migrationFile) error {
-	// 1. Execute the migration SQL
-    // We execute it as a single batch if possible, or statement by statement?
-    // ExecOneSQL takes a string. Ideally transactions support.
-    
-	res := m.client.ExecOneSQL(file.Content)
-	if res.Error != nil {
-		return res.Error
+// parseAppliedAt reads the applied_at column (UTC CURRENT_TIMESTAMP), zero time if it can't be parsed
+func parseAppliedAt(value interface{}) time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case string:
+		for _, layout := range timestampLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// applyMigration executes the SQL content and records it, skipped is true when the guards
+// of the file did not pass (the migration is recorded without being executed)
+func (m *MigrationService) applyMigration(file migrationFile) (skipped bool, err error) {
+	// 1. Evaluate the guards
+	guards, err := parseMigrationGuards(file.Content)
+	if err != nil {
+		return false, err
+	}
+	run, err := m.guardsPass(guards)
+	if err != nil {
+		return false, err
 	}
 
-	// 2. Record it
+	// 2. Execute the migration SQL
+	// As a single batch, or statement by statement with SplitStatements. Ideally transactions support.
+	if run && m.SplitStatements {
+		statements := splitSQLStatements(file.Content)
+		for i, statement := range statements {
+			res := m.client.ExecOneSQL(statement)
+			if res.Error != nil {
+				// the previous statements are applied, the file is not recorded
+				return false, fmt.Errorf("statement %d of %d failed: %w", i+1, len(statements), res.Error)
+			}
+		}
+	} else if run {
+		res := m.client.ExecOneSQL(file.Content)
+		if res.Error != nil {
+			return false, res.Error
+		}
+	}
+
+	// 3. Record it
 	insertSQL := fmt.Sprintf("INSERT INTO %s (name) VALUES ('%s')", MIGRATION_TABLE, file.Name)
-	res = m.client.ExecOneSQL(insertSQL)
+	res := m.client.ExecOneSQL(insertSQL)
 	if res.Error != nil {
-		return fmt.Errorf("failed to record migration: %v", res.Error)
+		return false, fmt.Errorf("failed to record migration: %v", res.Error)
 	}
 
-	return nil
+	return !run, nil
+}
+
+// migrationGuard is a guard directive, the migration runs if the query returning rows is runIfRows
+type migrationGuard struct {
+	runIfRows bool
+	query     string
+}
+
+// parseMigrationGuards reads the guard directives of the comment lines at the top of a migration file
+func parseMigrationGuards(content string) ([]migrationGuard, error) {
+	var guards []migrationGuard
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		if !strings.HasPrefix(strings.ToLower(line), MIGRATION_GUARD_DIRECTIVE) {
+			continue
+		}
+		directive := strings.TrimSpace(line[len(MIGRATION_GUARD_DIRECTIVE):])
+		keyword, query, _ := strings.Cut(directive, " ")
+		query = strings.TrimSpace(query)
+		switch strings.ToLower(keyword) {
+		case "if":
+			guards = append(guards, migrationGuard{runIfRows: true, query: query})
+		case "unless":
+			guards = append(guards, migrationGuard{runIfRows: false, query: query})
+		default:
+			return nil, fmt.Errorf("invalid guard %q, use \"%s if <query>\" or \"%s unless <query>\"", line, MIGRATION_GUARD_DIRECTIVE, MIGRATION_GUARD_DIRECTIVE)
+		}
+		if query == "" {
+			return nil, fmt.Errorf("guard %q has no query", line)
+		}
+	}
+	return guards, nil
+}
+
+// splitSQLStatements splits SQL on the semicolons ending statements. Semicolons inside quoted strings
+// and identifiers (single, double or back quotes and brackets, see skipSQLText), comments (-- and /* */)
+// and CREATE TRIGGER ... BEGIN ... END bodies don't split. Comments are dropped and so are the statements left empty.
+func splitSQLStatements(content string) []string {
+	var statements []string
+	var current, word strings.Builder
+	var words []string // first words of the current statement, to detect a CREATE TRIGGER
+	inTrigger, triggerBody := false, false
+	caseDepth := 0
+
+	// endWord keeps track of the keywords that decide if a semicolon ends the statement
+	endWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := strings.ToUpper(word.String())
+		word.Reset()
+		if len(words) < 4 {
+			words = append(words, w)
+			// CREATE [TEMP|TEMPORARY] TRIGGER
+			inTrigger = inTrigger || (words[0] == "CREATE" && w == "TRIGGER")
+		}
+		switch {
+		case !inTrigger:
+		case w == "BEGIN" && !triggerBody:
+			triggerBody = true
+		case w == "CASE" && triggerBody:
+			caseDepth++
+		case w == "END" && triggerBody && caseDepth > 0:
+			caseDepth--
+		case w == "END" && triggerBody:
+			triggerBody = false
+		}
+	}
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); strings.TrimSpace(strings.TrimSuffix(statement, ";")) != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+		words = words[:0]
+		inTrigger, triggerBody, caseDepth = false, false, 0
+	}
+
+	for i := 0; i < len(content); i++ {
+		ch := content[i]
+		kind, end, _ := skipSQLText(content, i)
+		switch {
+		case kind == sqlQuoted:
+			endWord()
+			current.WriteString(content[i : end+1])
+			i = end
+		case kind == sqlComment:
+			// dropped, a block comment still separates the words around it (a line comment keeps its newline)
+			endWord()
+			if ch == '/' {
+				current.WriteByte(' ')
+			}
+			i = end
+		case ch == ';':
+			endWord()
+			current.WriteByte(ch)
+			if !triggerBody {
+				flush()
+			}
+		case ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9'):
+			word.WriteByte(ch)
+			current.WriteByte(ch)
+		default:
+			endWord()
+			current.WriteByte(ch)
+		}
+	}
+	endWord()
+	flush()
+	return statements
+}
+
+// guardsPass runs the guard queries, false as soon as one of them does not pass
+func (m *MigrationService) guardsPass(guards []migrationGuard) (bool, error) {
+	for _, guard := range guards {
+		// on the leader, a follower may not have the previous migrations yet
+		req := &suresql.SQLRequest{Statements: []string{guard.query}}
+		response, err := sendRequest[suresql.QueryResponseSQL](m.client, "POST", "/db/api/querysql", req, RequestTypeSQLWriteQuery, AUTO_REFRESH, FALLBACK_LEADER)
+		if err != nil {
+			return false, fmt.Errorf("guard query failed: %w", err)
+		}
+		hasRows := len(response) > 0 && len(response[0].Records) > 0
+		if hasRows != guard.runIfRows {
+			return false, nil
+		}
+	}
+	return true, nil
 }
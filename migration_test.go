@@ -0,0 +1,67 @@
+package client
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadMigrationFilesOrdersByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/10_add_index.sql":      {Data: []byte("CREATE INDEX i ON users (name);")},
+		"migrations/2_add_users.sql":       {Data: []byte("CREATE TABLE users (name TEXT);")},
+		"migrations/2_add_users.down.sql":  {Data: []byte("DROP TABLE users;")},
+		"migrations/001_init.sql":          {Data: []byte("SELECT 1;")},
+		"migrations/0003_seed.sql":         {Data: []byte("INSERT INTO users VALUES ('a');")},
+		"migrations/100.sql":               {Data: []byte("SELECT 100;")},
+		"migrations/zz_cleanup.sql":        {Data: []byte("SELECT 2;")},
+		"migrations/after_everything.sql":  {Data: []byte("SELECT 3;")},
+		"migrations/notes.txt":             {Data: []byte("not a migration")},
+		"migrations/20_nested/ignored.sql": {Data: []byte("SELECT 4;")},
+	}
+	files, err := (&MigrationService{}).readMigrationFiles(fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	want := []string{"001_init.sql", "2_add_users.sql", "0003_seed.sql", "10_add_index.sql", "100.sql", "after_everything.sql", "zz_cleanup.sql"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("order = %v, want %v", names, want)
+	}
+}
+
+func TestReadMigrationFilesRejectsDuplicateVersions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_create_users.sql":  {Data: []byte("SELECT 1;")},
+		"01_create_items.sql": {Data: []byte("SELECT 1;")},
+		"2_seed.sql":          {Data: []byte("SELECT 2;")},
+	}
+	_, err := (&MigrationService{}).readMigrationFiles(fsys, ".")
+	if err == nil || !strings.Contains(err.Error(), "duplicate migration version 1") {
+		t.Errorf("err = %v, want a duplicate version error", err)
+	}
+}
+
+func TestSplitSQLStatements(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"CREATE TABLE a (x TEXT); INSERT INTO a VALUES ('a;b');", []string{"CREATE TABLE a (x TEXT);", "INSERT INTO a VALUES ('a;b');"}},
+		{"-- comment ; here\nSELECT 1; /* block ; */ SELECT 2;", []string{"SELECT 1;", "SELECT 2;"}},
+		{"SELECT [a;b], `c;d`, \"e;f\" FROM t; SELECT 'it''s;';", []string{"SELECT [a;b], `c;d`, \"e;f\" FROM t;", "SELECT 'it''s;';"}},
+		{"CREATE TRIGGER tr AFTER INSERT ON a BEGIN UPDATE b SET x = CASE WHEN 1 THEN 2 END; END; SELECT 3",
+			[]string{"CREATE TRIGGER tr AFTER INSERT ON a BEGIN UPDATE b SET x = CASE WHEN 1 THEN 2 END; END;", "SELECT 3"}},
+		{"SELECT a/*x*/FROM t;SELECT--c\nb;", []string{"SELECT a FROM t;", "SELECT\nb;"}},
+		{"SELECT 1;/* unterminated ; ", []string{"SELECT 1;"}},
+	}
+	for _, tt := range tests {
+		if got := splitSQLStatements(tt.content); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("splitSQLStatements(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
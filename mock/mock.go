@@ -0,0 +1,155 @@
+// Package mock provides an in-memory transport and a ready to use client for testing code
+// that depends on *client.Client without a running SureSQL server.
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	client "github.com/medatechnology/gosuresql"
+	orm "github.com/medatechnology/simpleorm"
+	"github.com/medatechnology/suresql"
+)
+
+const (
+	MOCK_SERVER_URL = "http://suresql.mock"
+	MOCK_NODE_ID    = "mock"
+)
+
+// Response is a fixture with explicit status and message. Any other value used as a fixture
+// is treated as the Data part of a successful (200) response.
+type Response struct {
+	Status  int
+	Message string
+	Data    interface{}
+}
+
+// Call is a request received by the Transport
+type Call struct {
+	Method   string
+	Endpoint string
+	Body     []byte
+}
+
+// Transport is an http.RoundTripper that answers with fixtures keyed by endpoint.
+// Keys can be "METHOD /endpoint" (ie: "POST /db/api/query") or just "/endpoint" for any method.
+// Unknown endpoints get a 404 standard response.
+type Transport struct {
+	mutex     sync.Mutex
+	responses map[string]interface{}
+	calls     []Call
+}
+
+// NewTransport creates the transport with the given fixtures. Default fixtures for
+// /db/connect, /db/refresh and /db/api/status are added if not provided.
+func NewTransport(responses map[string]interface{}) *Transport {
+	t := &Transport{responses: make(map[string]interface{})}
+	token := suresql.TokenTable{Token: "mock-token", Refresh: "mock-refresh-token"}
+	t.responses["/db/connect"] = token
+	t.responses["/db/refresh"] = token
+	t.responses["/db/api/status"] = orm.NodeStatusStruct{
+		StatusStruct: orm.StatusStruct{
+			URL:      MOCK_SERVER_URL,
+			NodeID:   MOCK_NODE_ID,
+			IsLeader: true,
+			Mode:     "rw",
+			MaxPool:  1,
+		},
+	}
+	for endpoint, response := range responses {
+		t.responses[endpoint] = response
+	}
+	return t
+}
+
+// Set adds or replaces the fixture of an endpoint
+func (t *Transport) Set(endpoint string, response interface{}) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.responses[endpoint] = response
+}
+
+// Calls returns a copy of all requests received so far
+func (t *Transport) Calls() []Call {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	calls := make([]Call, len(t.calls))
+	copy(calls, t.calls)
+	return calls
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+	}
+
+	t.mutex.Lock()
+	t.calls = append(t.calls, Call{Method: req.Method, Endpoint: req.URL.Path, Body: body})
+	fixture, exists := t.responses[req.Method+" "+req.URL.Path]
+	if !exists {
+		fixture, exists = t.responses[req.URL.Path]
+	}
+	t.mutex.Unlock()
+
+	resp := suresql.StandardResponse{Status: http.StatusOK, Message: "OK"}
+	switch f := fixture.(type) {
+	case nil:
+		if !exists {
+			resp = suresql.StandardResponse{Status: http.StatusNotFound, Message: "mock: no fixture for " + req.URL.Path}
+		}
+	case Response:
+		resp = suresql.StandardResponse{Status: f.Status, Message: f.Message, Data: f.Data}
+	case *Response:
+		resp = suresql.StandardResponse{Status: f.Status, Message: f.Message, Data: f.Data}
+	default:
+		resp.Data = f
+	}
+
+	respBody, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        http.StatusText(resp.Status),
+		StatusCode:    resp.Status,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
+
+// NewMockClient returns a connected client that answers from the fixtures instead of a server
+// Usage:
+//
+//	db, err := mock.NewMockClient(map[string]interface{}{
+//	  "POST /db/api/query": suresql.QueryResponse{Records: orm.DBRecords{{TableName: "users", Data: map[string]interface{}{"id": 1}}}},
+//	})
+func NewMockClient(responses map[string]interface{}) (*client.Client, error) {
+	return NewMockClientFromTransport(NewTransport(responses))
+}
+
+// NewMockClientFromTransport returns a connected client using the given mock transport,
+// useful to assert the received calls afterwards
+func NewMockClientFromTransport(transport *Transport) (*client.Client, error) {
+	config := client.NewClientConfig(
+		client.WithServerURL(MOCK_SERVER_URL),
+		client.AllowInsecure(), // nothing leaves the process, the transport answers
+		client.WithHTTPClientConfig(client.NewHTTPClientConfig(client.WithTransport(transport))),
+		client.WithPoolConfig(client.NewPoolConfig(client.WithScaleUpBatchSize(1), client.WithMaxPoolSize(1))),
+	)
+	db, err := client.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Connect("", ""); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
@@ -3,10 +3,12 @@
 package client
 
 import (
+	"encoding/json"
 	"net/http"
 	"os"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	utils "github.com/medatechnology/goutil"
@@ -32,6 +34,23 @@ const (
 	DEFAULT_MAX_IDLE_CONNECTIONS_PER_HOST = 100
 	DEFAULT_MAX_CONNECTIONS_PER_HOST      = 1000
 	DEFAULT_IDLE_CONNECTION_TIMEOUT       = 90 * time.Second
+	DEFAULT_MAX_RESPONSE_BYTES            = 256 << 20 // 256MB, larger responses fail with ErrResponseTooLarge
+	DEFAULT_MAX_DRAIN_BYTES               = 64 << 10  // 64KB, a longer unread body is closed without draining it
+	DEFAULT_PRIMARY_KEY_COLUMN            = "id"
+	DEFAULT_TIME_FORMAT                   = time.RFC3339Nano // layout of time.Time parameters, same as encoding/json
+	DEFAULT_STATUS_CACHE_TTL              = 2 * time.Second  // how long Status, Leader and Peers reuse the last status
+	DEFAULT_STREAM_FLUSH_INTERVAL         = 1 * time.Second
+	DEFAULT_MAX_SQL_PARAMETERS            = 999  // SQLite default limit of host parameters per statement
+	DEFAULT_SLOW_QUERY_THRESHOLD          = 0    // disabled
+	DEFAULT_SLOW_QUERY_SQL_LENGTH         = 500  // SQL in QueryInfo is truncated to this many characters
+	DEFAULT_MAX_LEADER_REDIRECTS          = 2    // retries of a write rejected by a non-leader node
+	DEFAULT_ERROR_BODY_SNIPPET_LENGTH     = 200  // body of a non-JSON error response is truncated to this many characters
+	DEFAULT_EXPORT_PAGE_SIZE              = 1000 // rows fetched per request when exporting a query
+	DEFAULT_STREAM_PAGE_SIZE              = 1000 // rows fetched per request by SelectManyWithConditionStream
+	DEFAULT_UPSERT_BATCH_SIZE             = 500  // statements per request of UpsertManyTableStructs
+	DEFAULT_THROTTLE_RETRIES              = 5    // retries of a chunk rejected with 429/503 by InsertManyInChunks
+	DEFAULT_THROTTLE_BACKOFF              = 500 * time.Millisecond
+	DEFAULT_MAX_THROTTLE_BACKOFF          = 30 * time.Second
 
 	//-----------------------------------------------------------------------------
 	// Connection pool constants
@@ -46,22 +65,30 @@ const (
 	DEFAULT_CONNECTION_TTL          = 1 * time.Hour
 	DEFAULT_SCALE_UP_BATCH_SIZE     = 3
 	DEFAULT_USAGE_WINDOW_SIZE       = 100
+	DEFAULT_TOPOLOGY_REFRESH        = 0 // disabled, status is only fetched on Connect
+	DEFAULT_CONNECT_CONCURRENCY     = 5 // how many connections are created in parallel
+	DEFAULT_ACQUIRE_TIMEOUT         = 0 // disabled, fail immediately when the pool is empty
+	DEFAULT_ACQUIRE_RETRY_INTERVAL  = 100 * time.Millisecond
+	DEFAULT_SCALE_UP_INTERVAL       = 10 * time.Second
+	DEFAULT_SCALE_UP_JITTER         = 0 // disabled
+	DEFAULT_SATURATION_WINDOW       = 30 * time.Second
 
 	// Request types
 	RequestTypeQuery RequestType = iota
 	RequestTypeSQLExec
 	RequestTypeSQLQuery
 	RequestTypeInsert
-
-	// Response formats
-	ResponseFormatSingleRecord ResponseFormat = iota
-	ResponseFormatMultiRecord
-	ResponseFormatSQLResult
-	ResponseFormatMultiSQLResult
-	ResponseFormatMultiRecordSets
-	ResponseFormatSingleRecordOnly
+	RequestTypeOther         // connect, refresh, status, schema...
+	RequestTypeSQLWriteQuery // write returning rows (ie: INSERT ... RETURNING), sent to /db/api/querysql through the write pool
 )
 
+// DEFAULT_LATENCY_BUCKETS are the upper bounds of the request latency histogram buckets
+var DEFAULT_LATENCY_BUCKETS = []time.Duration{
+	5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	1 * time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
 //-----------------------------------------------------------------------------
 // Original request type definitions
 //-----------------------------------------------------------------------------
@@ -69,8 +96,47 @@ const (
 // RequestType defines the type of request being made
 type RequestType int
 
-// ResponseFormat defines the expected response format
-type ResponseFormat int
+// String returns the name of the request type
+func (t RequestType) String() string {
+	switch t {
+	case RequestTypeQuery:
+		return "query"
+	case RequestTypeSQLExec:
+		return "sql_exec"
+	case RequestTypeSQLQuery:
+		return "sql_query"
+	case RequestTypeInsert:
+		return "insert"
+	case RequestTypeSQLWriteQuery:
+		return "sql_write_query"
+	}
+	return "other"
+}
+
+// isWrite reports whether requests of this type change data, they go to the write pool and get an
+// idempotency key (see IdempotentWrites) while reads go to the read pool and can be deduplicated or cached
+func (t RequestType) isWrite() bool {
+	switch t {
+	case RequestTypeSQLExec, RequestTypeInsert, RequestTypeSQLWriteQuery:
+		return true
+	}
+	return false
+}
+
+// requestTypeOf returns the request type of an API endpoint
+func requestTypeOf(endpoint string) RequestType {
+	switch endpoint {
+	case "/db/api/query":
+		return RequestTypeQuery
+	case "/db/api/sql":
+		return RequestTypeSQLExec
+	case "/db/api/querysql":
+		return RequestTypeSQLQuery
+	case "/db/api/insert":
+		return RequestTypeInsert
+	}
+	return RequestTypeOther
+}
 
 //-----------------------------------------------------------------------------
 // Connection pool configuration types
@@ -90,6 +156,20 @@ type PoolConfig struct {
 	// New field for HTTP client creation policy
 	NodeUseMultiClient bool // If true, create one HTTP client per connection (original behavior)
 	// If false, share one HTTP client per node (new optimized behavior)
+	TopologyRefreshInterval time.Duration // How often to re-fetch cluster status for added/removed nodes, 0 disables it
+	ConnectConcurrency      int           // Maximum number of connections created in parallel when filling a pool
+	AcquireTimeout          time.Duration // How long to wait for a connection when the pool is empty, 0 fails immediately
+	MaxInFlightPerNode      int           // Maximum concurrent requests per node, 0 is unlimited
+	MaxInFlightBlock        bool          // Wait for a free slot when all nodes are at MaxInFlightPerNode instead of failing
+	FairAcquire             bool          // Serve requests waiting for a free slot in arrival order (FIFO)
+	ScaleUpInterval         time.Duration // Minimum time between scale-ups of the same node triggered by requests
+	ScaleUpJitter           time.Duration // Maximum random delay before a scale-up creates connections, 0 disables it
+	EventHistorySize        int           // How many pool events GetPoolEvents keeps, 0 disables the history
+	SaturationWindow        time.Duration // How long a node at max pool size must stay over ScaleUpThreshold to be saturated
+
+	// Built by NewPoolConfig, its booleans already include the environment and win over it in NewClient.
+	// In a PoolConfig built by hand false means not set, like the zero values of the other fields.
+	resolved bool
 }
 
 // HTTPClientConfig defines configuration for HTTP client settings
@@ -104,6 +184,10 @@ type HTTPClientConfig struct {
 	MaxIdleConnsPerHost   int
 	MaxConnsPerHost       int
 	IdleConnTimeout       time.Duration
+	MaxResponseBytes      int64                        // Responses larger than this fail with ErrResponseTooLarge instead of being decoded
+	HTTP2                 bool                         // Attempt HTTP/2 (negotiated with TLS ALPN, so only for https:// servers)
+	Transport             http.RoundTripper            // Optional custom transport (ie: proxy, tracing, testing), when set the fields above except Timeout are no-ops
+	NodeTransports        map[string]http.RoundTripper // Optional custom transport per node ID, takes precedence over Transport
 }
 
 //-----------------------------------------------------------------------------
@@ -123,6 +207,12 @@ type Connection struct {
 	LastUsed    time.Time          // When this connection was last used
 	LastRefresh time.Time          // When token was last refreshed
 	Created     time.Time          // When this connection was created
+	pinned      int32              // Number of sessions pinned to this connection, see Client.Session
+
+	// One token renewal at a time, see Client.renewToken
+	refreshMutex sync.Mutex
+	// Guards Token and LastRefresh, renewals replace them while other requests use the connection
+	tokenMutex sync.RWMutex
 }
 
 // ConnectionStats tracks usage statistics for a specific node
@@ -138,6 +228,9 @@ type ConnectionStats struct {
 	LastCleanup        time.Time   // Last time we checked for idle connections
 	ScaleUpEvents      int         // Counter for scale-up events
 	ScaleDownEvents    int         // Counter for scale-down events
+	scalingUp          bool        // A scale-up triggered by requests is in progress
+	atMaxSince         time.Time   // Since when the node is over the threshold and can't scale further
+	saturated          bool        // Stayed at max pool size for SaturationWindow, OnPoolSaturated was fired
 }
 
 // ConnectionPool manages a pool of connections with node-level round-robin support
@@ -151,6 +244,7 @@ type ConnectionPool struct {
 	maxPool               int                      // Max read pool
 	maxWritePool          int                      // Max write pool (usually 1 for atomic)
 	nodeHTTPClients       map[string]*http.Client  // New field for HTTP client management
+	drainedNodes          map[string]bool          // Nodes taken out of rotation for maintenance
 }
 
 // PoolMetrics provides statistics for the connection pool
@@ -161,6 +255,14 @@ type PoolMetrics struct {
 	ScaleUpEvents      int                        // Number of scale-up events since start
 	ScaleDownEvents    int                        // Number of scale-down events since start
 	RequestsPerSecond  float64                    // Approximate RPS based on recent history
+	ThrottledReads     int64                      // Read requests that waited for the rate limiter
+	ThrottledWrites    int64                      // Write requests that waited for the rate limiter
+	AcquireWaits       int64                      // Requests that waited for a connection because the pool was empty
+	AcquireTimeouts    int64                      // Requests that gave up waiting for a connection
+	AcquireWaiters     int                        // Requests currently waiting in the FIFO queue for a free slot
+	DedupedReads       int64                      // Reads that shared the response of an identical in-flight read
+	AuthCalls          int64                      // Token refresh (/db/refresh) and login (/db/connect) calls made by the pooled connections
+	ServerThrottles    int64                      // Responses where the server asked to slow down (HTTP 429/503)
 }
 
 // NodePoolMetrics provides statistics for a single node's connection pool
@@ -176,6 +278,54 @@ type NodePoolMetrics struct {
 	LastScaleDown      time.Time
 	ScaleUpEvents      int
 	ScaleDownEvents    int
+	Draining           bool   // Node is drained, no new requests are routed to it
+	InFlightRequests   int    // Requests holding a MaxInFlightPerNode slot
+	RejectedRequests   int64  // Times the node was skipped because it was at MaxInFlightPerNode
+	QueuedRequests     int64  // Times a request waited for a free slot
+	Protocol           string // HTTP protocol of the last response from the node, ie: "HTTP/1.1" or "HTTP/2.0"
+	Saturated          bool   // Node is at max pool size and over ScaleUpThreshold for PoolConfig.SaturationWindow
+
+	// Token renewals of the node's connections since start, a rising RefreshFailures
+	// usually means an auth or configuration problem
+	RefreshAttempts  int64     // Expired tokens renewed with /db/refresh
+	RefreshSuccesses int64     // Renewals done by /db/refresh
+	ConnectFallbacks int64     // Renewals where /db/refresh failed and /db/connect was called
+	RefreshFailures  int64     // Renewals where /db/connect failed too, the request got an auth error
+	LastTokenRefresh time.Time // Most recent token of the node's connections, see Connection.LastRefresh
+}
+
+// LatencyMetrics provides request latency per endpoint and per node ID
+type LatencyMetrics struct {
+	Endpoints map[string]LatencyStats
+	Nodes     map[string]LatencyStats
+}
+
+// EndpointMetrics provides the requests, errors and latency of one API endpoint. Every HTTP
+// attempt is counted, so a token refresh retry or a fallback to leader counts twice.
+type EndpointMetrics struct {
+	Endpoint string
+	Type     RequestType
+	Requests int64
+	Errors   int64
+	Latency  LatencyStats
+}
+
+// LatencyStats provides the latency distribution of requests, percentiles are estimated from the buckets
+type LatencyStats struct {
+	Count   int64
+	Mean    time.Duration
+	P50     time.Duration
+	P95     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+	Buckets []LatencyBucket
+}
+
+// LatencyBucket is the number of requests that took at most UpperBound (and more than the previous bucket).
+// The last bucket has UpperBound 0 and counts everything above the biggest bound.
+type LatencyBucket struct {
+	UpperBound time.Duration
+	Count      int64
 }
 
 //-----------------------------------------------------------------------------
@@ -184,14 +334,76 @@ type NodePoolMetrics struct {
 
 // ClientConfig holds configuration for a SureSQL client
 type ClientConfig struct {
-	ServerURL        string
-	APIKey           string
-	ClientID         string
-	Username         string
-	Password         string
-	HTTPTimeout      time.Duration
-	PoolConfig       *PoolConfig       // Optional pool configuration
-	HTTPClientConfig *HTTPClientConfig // Optional HTTP client configuration
+	ServerURL           string
+	APIKey              string
+	ClientID            string
+	Username            string
+	Password            string
+	HTTPTimeout         time.Duration
+	PoolConfig          *PoolConfig       // Optional pool configuration
+	HTTPClientConfig    *HTTPClientConfig // Optional HTTP client configuration
+	PrimaryKeyColumn    string            // Column used by FindByID, default is "id"
+	StreamFlushInterval time.Duration     // InsertStream flushes a partial batch after this interval
+	JSONCodec           JSONCodec         // Optional JSON encoder/decoder, default is encoding/json
+	Middlewares         []Middleware      // Request middlewares, run in registration order
+	ReadRateLimit       RateLimit         // Client-side rate limit for read requests, disabled if RPS is 0
+	WriteRateLimit      RateLimit         // Client-side rate limit for write requests, disabled if RPS is 0
+	SlowQueryThreshold  time.Duration     // Requests taking longer than this fire OnSlowQuery, disabled if 0
+	OnSlowQuery         func(info QueryInfo)
+	LatencyBuckets      []time.Duration                           // Upper bounds of the latency histogram buckets, default is DEFAULT_LATENCY_BUCKETS
+	OnLeaderChange      func(oldLeader, newLeader string)         // Called with the leader URLs when a new leader is detected
+	IdempotentWrites    bool                                      // Send a generated idempotency key with every write, see IDEMPOTENCY_KEY_HEADER
+	DefaultQueryLimit   int                                       // LIMIT added to SelectMany/SelectManyWithCondition without one, 0 disables it
+	SchemaTypeCoercion  bool                                      // Convert record values to the declared column types of the cached schema
+	LeaderURLOverride   string                                    // URL used for the leader node instead of the one advertised in the cluster status
+	NodeURLRewriter     func(nodeID, advertisedURL string) string // Maps every advertised node URL to the URL the client connects to
+	ReadDeduplication   bool                                      // Concurrent identical reads share one request, see WithReadDeduplication
+	ReadCacheSize       int                                       // Maximum cached SQL query responses, the cache is disabled if 0
+	ReadCacheTTL        time.Duration                             // How long a cached SQL query response is used, the cache is disabled if 0
+	AllowInsecure       bool                                      // Allow plain http:// to a non-localhost server without a warning
+	RequireHTTPS        bool                                      // Fail NewClient for plain http:// to a non-localhost server instead of warning
+	BeforeRequest       func(info RequestInfo)                    // Called before every HTTP request, see WithBeforeRequest
+	AfterResponse       func(info RequestInfo, status int, elapsed time.Duration, err error)
+
+	ConfigWatchInterval time.Duration // Poll .env.client this often and ReloadConfig when it changed, disabled if 0
+
+	FailoverPolicy FailoverPolicy // Nodes a failed read with FALLBACK_LEADER is retried on, default is the leader only
+
+	VerifyWritePool bool // Connect fails with ErrWritePoolUnusable when no write pool connection accepts a write probe
+
+	TimeFormat string // Layout of time.Time parameters and of timestamp columns read back, DEFAULT_TIME_FORMAT if empty
+
+	OnPoolSaturated func(nodeID string, activeRequests, maxPool int) // Called when a node's pool can't scale further, see WithPoolSaturatedHook
+
+	StatusCacheTTL time.Duration // How long Status, Leader and Peers reuse the last status, DEFAULT_STATUS_CACHE_TTL if 0, disabled if negative
+
+	tokenOnly bool // connected with ConnectWithToken, never log in with the credentials
+}
+
+// JSONCodec is the JSON encoder/decoder used for request and response bodies.
+// Plug in a faster library (ie: jsoniter, goccy/go-json) using WithJSONCodec.
+type JSONCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// StdJSONCodec is the default JSONCodec using encoding/json
+type StdJSONCodec struct{}
+
+func (StdJSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (StdJSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// codec returns the configured JSONCodec or the default encoding/json one
+func (config *ClientConfig) codec() JSONCodec {
+	if config.JSONCodec == nil {
+		return StdJSONCodec{}
+	}
+	return config.JSONCodec
 }
 
 //-----------------------------------------------------------------------------
@@ -215,16 +427,87 @@ type Client struct {
 
 	// Dynamic pool scaling fields
 	PoolConfig        PoolConfig
+	settings          atomic.Pointer[hotConfig] // Settings changed by ReloadConfig, see hotConfig
 	statsPerNodeRead  map[string]*ConnectionStats
 	statsPerNodeWrite map[string]*ConnectionStats
 	scalingMutex      sync.Mutex
 
+	// Connection acquisition waits and timeouts, updated atomically
+	acquireWaits    int64
+	acquireTimeouts int64
+
+	// In-flight reads shared by identical concurrent requests, and how many requests shared one
+	readFlights  flightGroup[json.RawMessage]
+	dedupedReads int64
+
+	// Logins (/db/connect) shared by the connections of a node, and the auth calls made
+	loginFlights flightGroup[suresql.TokenTable]
+	authCalls    int64
+
+	// Refreshes (/db/refresh) shared by the connections holding the same refresh token, and the
+	// last refresh token rotated by the server with the token replacing it, see refreshShared
+	refreshFlights flightGroup[suresql.TokenTable]
+	rotationMutex  sync.Mutex
+	rotatedRefresh string
+	rotatedToken   suresql.TokenTable
+
+	// Token renewal counters per node ID, *tokenStats
+	tokenStatsPerNode sync.Map
+
+	// Responses where the server was overloaded (429/503), updated atomically
+	serverThrottles int64
+
+	// Cached SQL query responses, nil if disabled
+	readCache *readCache
+
+	// Cancel funcs of the requests in progress, for CancelAll
+	inflight inflightRegistry
+
+	// Pool event history for GetPoolEvents, nil if disabled
+	events *eventRing
+
+	// Per-node in-flight limit, nil if unlimited
+	bulkhead *bulkhead
+
+	// Request latency histograms per endpoint and node
+	latency *latencyRecorder
+
+	// Client-side rate limiters, nil if disabled
+	readLimiter  *rateLimiter
+	writeLimiter *rateLimiter
+
 	// Cached cluster status information
-	status *orm.NodeStatusStruct
+	status      *orm.NodeStatusStruct
+	statusMutex sync.RWMutex
+
+	// Current leader tracked from status calls, guarded by statusMutex
+	leaderURL    string
+	leaderNodeID string
+
+	// Last response of Status for StatusCacheTTL, guarded by statusMutex
+	statusCache    *orm.NodeStatusStruct
+	statusCachedAt time.Time
+
+	// HTTP protocol of the last response per node ID, ie: "HTTP/2.0"
+	nodeProtocols sync.Map
+
+	// Declared column types per table for SchemaTypeCoercion, nil until loaded
+	schemaTypes map[string]map[string]string
+	schemaMutex sync.RWMutex
 
 	// Cleanup timer for idle connections
 	cleanupTimer *time.Timer
 	cleanupDone  chan struct{}
+
+	// Topology refresh timer for cluster membership changes
+	topologyTimer *time.Timer
+	topologyDone  chan struct{}
+
+	// Stops the .env.client watcher, nil if not running
+	configWatchDone chan struct{}
+
+	// Set to 1 by Close, updated atomically, see ErrClientClosed
+	closed int32
 }
 
 //-----------------------------------------------------------------------------
@@ -321,24 +604,119 @@ func WithNodeUseMultiClient(useMulti bool) PoolConfigOption {
 	}
 }
 
+// WithTopologyRefreshInterval sets how often the cluster topology is refreshed, 0 disables it
+func WithTopologyRefreshInterval(interval time.Duration) PoolConfigOption {
+	return func(config *PoolConfig) {
+		config.TopologyRefreshInterval = interval
+	}
+}
+
+// WithConnectConcurrency sets how many connections can be created in parallel
+func WithConnectConcurrency(limit int) PoolConfigOption {
+	return func(config *PoolConfig) {
+		config.ConnectConcurrency = limit
+	}
+}
+
+// WithAcquireTimeout sets how long to wait for a connection when the pool is empty
+func WithAcquireTimeout(timeout time.Duration) PoolConfigOption {
+	return func(config *PoolConfig) {
+		config.AcquireTimeout = timeout
+	}
+}
+
+// WithMaxInFlightPerNode limits concurrent requests per node, when all nodes are full
+// the request waits for a free slot if block is true, otherwise it fails right away
+func WithMaxInFlightPerNode(limit int, block bool) PoolConfigOption {
+	return func(config *PoolConfig) {
+		config.MaxInFlightPerNode = limit
+		config.MaxInFlightBlock = block
+	}
+}
+
+// WithFairAcquire sets whether requests blocked by MaxInFlightPerNode get the free slots in arrival
+// order (FIFO) instead of racing for them
+func WithFairAcquire(fair bool) PoolConfigOption {
+	return func(config *PoolConfig) {
+		config.FairAcquire = fair
+	}
+}
+
+// WithScaleUpInterval sets the minimum time between request triggered scale-ups of a node
+func WithScaleUpInterval(interval time.Duration) PoolConfigOption {
+	return func(config *PoolConfig) {
+		config.ScaleUpInterval = interval
+	}
+}
+
+// WithScaleUpJitter sets the maximum random delay before a scale-up creates connections
+func WithScaleUpJitter(jitter time.Duration) PoolConfigOption {
+	return func(config *PoolConfig) {
+		config.ScaleUpJitter = jitter
+	}
+}
+
+// WithEventHistory sets how many pool events are kept for GetPoolEvents, 0 disables the history
+func WithEventHistory(size int) PoolConfigOption {
+	return func(config *PoolConfig) {
+		config.EventHistorySize = size
+	}
+}
+
+// WithSaturationWindow sets how long a node at max pool size must stay over the scale up threshold
+// before it is reported as saturated (see ClientConfig.OnPoolSaturated)
+func WithSaturationWindow(window time.Duration) PoolConfigOption {
+	return func(config *PoolConfig) {
+		config.SaturationWindow = window
+	}
+}
+
+// mergePoolBool returns the user's value of a boolean if user was built by NewPoolConfig (so a false
+// from WithMaxInFlightPerNode or WithFairAcquire turns off a true from the environment), otherwise
+// only a true overrides
+func mergePoolBool(user *PoolConfig, userValue, value bool) bool {
+	if user.resolved {
+		return userValue
+	}
+	return userValue || value
+}
+
 // NewPoolConfig creates a pool configuration with the specified options
 func NewPoolConfig(options ...PoolConfigOption) *PoolConfig {
 	timeout := utils.GetEnvInt("SURESQL_POOL_IDLE_TIMEOUT", 0)
 	interval := utils.GetEnvInt("SURESQL_SCALE_DOWN_INTERVAL", 0)
 	ttl := utils.GetEnvInt("SURESQL_CONNECTION_TTL", 0)
 	tmpBool, _ := strconv.ParseBool(os.Getenv("SURESQL_NODE_USE_MULTI_CLIENT"))
-
-	config := PoolConfig {
-		MinPoolSize:       utils.GetEnvInt("SURESQL_POOL_MINIMUM", DEFAULT_MINIMUM_POOL_SIZE),
-		MaxPoolSize:       utils.GetEnvInt("SURESQL_POOL_MAXIMUM", DEFAULT_MAXIMUM_POOL_SIZE),
-		MaxWritePoolSize:  utils.GetEnvInt("SURESQL_WRITE_POOL_MAXIMUM", DEFAULT_MAXIMUM_WRITE_POOL_SIZE),
-		ScaleUpThreshold:  utils.GetEnvInt("SURESQL_SCALE_UP_THRESHOLD", DEFAULT_SCALE_UP_TRESHOLD),
-		IdleTimeout:       ValueOrDefault(time.Duration(timeout)*time.Minute, DEFAULT_IDLE_TIMEOUT, DurationBiggerThanZero),
-		ScaleDownInterval: ValueOrDefault(time.Duration(interval)*time.Minute, DEFAULT_SCALE_DOWN_INTERVAL, DurationBiggerThanZero),
-		ConnectionTTL:     ValueOrDefault(time.Duration(ttl)*time.Minute, DEFAULT_CONNECTION_TTL, DurationBiggerThanZero),
-		ScaleUpBatchSize:  utils.GetEnvInt("SURESQL_SCALE_UP_BATCH", DEFAULT_SCALE_UP_BATCH_SIZE),
-		UsageWindowSize:   utils.GetEnvInt("SURESQL_USAGE_WINDOW", DEFAULT_USAGE_WINDOW_SIZE),
-		NodeUseMultiClient: tmpBool,
+	topologyRefresh := utils.GetEnvInt("SURESQL_TOPOLOGY_REFRESH_INTERVAL", DEFAULT_TOPOLOGY_REFRESH) // in seconds
+	acquireTimeout := utils.GetEnvInt("SURESQL_ACQUIRE_TIMEOUT", DEFAULT_ACQUIRE_TIMEOUT)             // in milliseconds
+	saturationWindow := utils.GetEnvInt("SURESQL_POOL_SATURATION_WINDOW", 0)                          // in seconds
+	maxInFlightBlock, _ := strconv.ParseBool(os.Getenv("SURESQL_MAX_IN_FLIGHT_BLOCK"))
+	fairAcquire, _ := strconv.ParseBool(os.Getenv("SURESQL_FAIR_ACQUIRE"))
+	scaleUpInterval := utils.GetEnvInt("SURESQL_SCALE_UP_INTERVAL", 0) // in seconds
+	scaleUpJitter := utils.GetEnvInt("SURESQL_SCALE_UP_JITTER", 0)     // in milliseconds
+
+	config := PoolConfig{
+		MinPoolSize:             utils.GetEnvInt("SURESQL_POOL_MINIMUM", DEFAULT_MINIMUM_POOL_SIZE),
+		MaxPoolSize:             utils.GetEnvInt("SURESQL_POOL_MAXIMUM", DEFAULT_MAXIMUM_POOL_SIZE),
+		MaxWritePoolSize:        utils.GetEnvInt("SURESQL_WRITE_POOL_MAXIMUM", DEFAULT_MAXIMUM_WRITE_POOL_SIZE),
+		ScaleUpThreshold:        utils.GetEnvInt("SURESQL_SCALE_UP_THRESHOLD", DEFAULT_SCALE_UP_TRESHOLD),
+		IdleTimeout:             ValueOrDefault(time.Duration(timeout)*time.Minute, DEFAULT_IDLE_TIMEOUT, DurationBiggerThanZero),
+		ScaleDownInterval:       ValueOrDefault(time.Duration(interval)*time.Minute, DEFAULT_SCALE_DOWN_INTERVAL, DurationBiggerThanZero),
+		ConnectionTTL:           ValueOrDefault(time.Duration(ttl)*time.Minute, DEFAULT_CONNECTION_TTL, DurationBiggerThanZero),
+		ScaleUpBatchSize:        utils.GetEnvInt("SURESQL_SCALE_UP_BATCH", DEFAULT_SCALE_UP_BATCH_SIZE),
+		UsageWindowSize:         utils.GetEnvInt("SURESQL_USAGE_WINDOW", DEFAULT_USAGE_WINDOW_SIZE),
+		NodeUseMultiClient:      tmpBool,
+		TopologyRefreshInterval: time.Duration(topologyRefresh) * time.Second,
+		ConnectConcurrency:      utils.GetEnvInt("SURESQL_CONNECT_CONCURRENCY", DEFAULT_CONNECT_CONCURRENCY),
+		AcquireTimeout:          time.Duration(acquireTimeout) * time.Millisecond,
+		MaxInFlightPerNode:      utils.GetEnvInt("SURESQL_MAX_IN_FLIGHT_PER_NODE", 0),
+		MaxInFlightBlock:        maxInFlightBlock,
+		FairAcquire:             fairAcquire,
+		ScaleUpInterval:         ValueOrDefault(time.Duration(scaleUpInterval)*time.Second, DEFAULT_SCALE_UP_INTERVAL, DurationBiggerThanZero),
+		ScaleUpJitter:           ValueOrDefault(time.Duration(scaleUpJitter)*time.Millisecond, DEFAULT_SCALE_UP_JITTER, DurationBiggerThanZero),
+		EventHistorySize:        utils.GetEnvInt("SURESQL_POOL_EVENT_HISTORY", 0),
+		SaturationWindow:        ValueOrDefault(time.Duration(saturationWindow)*time.Second, DEFAULT_SATURATION_WINDOW, DurationBiggerThanZero),
+		resolved:                true,
 	}
 	for _, option := range options {
 		option(&config)
@@ -355,14 +733,36 @@ type ClientConfigOption func(*ClientConfig)
 func NewClientConfig(options ...ClientConfigOption) ClientConfig {
 	// tmpBool, _ := strconv.ParseBool(os.Getenv("DB_SSL"))
 	tmpTimeout, _ := strconv.ParseInt(os.Getenv("SURESQL_HTTP_TIMEOUT"), 10, 64)
+	tmpFlush, _ := strconv.ParseInt(os.Getenv("SURESQL_STREAM_FLUSH_INTERVAL"), 10, 64) // in milliseconds
+	tmpSlow, _ := strconv.ParseInt(os.Getenv("SURESQL_SLOW_QUERY_THRESHOLD"), 10, 64)   // in milliseconds
+	tmpIdempotent, _ := strconv.ParseBool(os.Getenv("SURESQL_IDEMPOTENT_WRITES"))
+	tmpCoercion, _ := strconv.ParseBool(os.Getenv("SURESQL_SCHEMA_TYPE_COERCION"))
+	tmpDedup, _ := strconv.ParseBool(os.Getenv("SURESQL_READ_DEDUPLICATION"))
+	tmpInsecure, _ := strconv.ParseBool(os.Getenv("SURESQL_ALLOW_INSECURE"))
+	tmpRequireHTTPS, _ := strconv.ParseBool(os.Getenv("SURESQL_REQUIRE_HTTPS"))
+	tmpVerifyWrite, _ := strconv.ParseBool(os.Getenv("SURESQL_VERIFY_WRITE_POOL"))
+	tmpStatusTTL, _ := strconv.ParseInt(os.Getenv("SURESQL_STATUS_CACHE_TTL"), 10, 64) // in milliseconds
 
 	config := ClientConfig{
-		ServerURL:   utils.GetEnv("SURESQL_SERVER_URL", "http://localhost:8080"),
-		APIKey:      utils.GetEnv("SURESQL_API_KEY", "development_api_key"),
-		ClientID:    utils.GetEnv("SURESQL_CLIENT_ID", "development_client_id"),
-		Username:    utils.GetEnv("SURESQL_USERNAME", "admin"),
-		Password:    utils.GetEnv("SURESQL_PASSWORD", "admin"),
-		HTTPTimeout: ValueOrDefault(time.Duration(tmpTimeout)*time.Second, DEFAULT_TIMEOUT, DurationBiggerThanZero),
+		ServerURL:           utils.GetEnv("SURESQL_SERVER_URL", "http://localhost:8080"),
+		APIKey:              utils.GetEnv("SURESQL_API_KEY", "development_api_key"),
+		ClientID:            utils.GetEnv("SURESQL_CLIENT_ID", "development_client_id"),
+		Username:            utils.GetEnv("SURESQL_USERNAME", "admin"),
+		Password:            utils.GetEnv("SURESQL_PASSWORD", "admin"),
+		HTTPTimeout:         ValueOrDefault(time.Duration(tmpTimeout)*time.Second, DEFAULT_TIMEOUT, DurationBiggerThanZero),
+		PrimaryKeyColumn:    utils.GetEnv("SURESQL_PRIMARY_KEY_COLUMN", DEFAULT_PRIMARY_KEY_COLUMN),
+		StreamFlushInterval: ValueOrDefault(time.Duration(tmpFlush)*time.Millisecond, DEFAULT_STREAM_FLUSH_INTERVAL, DurationBiggerThanZero),
+		SlowQueryThreshold:  ValueOrDefault(time.Duration(tmpSlow)*time.Millisecond, DEFAULT_SLOW_QUERY_THRESHOLD, DurationBiggerThanZero),
+		IdempotentWrites:    tmpIdempotent,
+		DefaultQueryLimit:   utils.GetEnvInt("SURESQL_DEFAULT_QUERY_LIMIT", 0),
+		SchemaTypeCoercion:  tmpCoercion,
+		LeaderURLOverride:   os.Getenv("SURESQL_LEADER_URL_OVERRIDE"),
+		ReadDeduplication:   tmpDedup,
+		AllowInsecure:       tmpInsecure,
+		RequireHTTPS:        tmpRequireHTTPS,
+		VerifyWritePool:     tmpVerifyWrite,
+		TimeFormat:          os.Getenv("SURESQL_TIME_FORMAT"),
+		StatusCacheTTL:      time.Duration(tmpStatusTTL) * time.Millisecond,
 		// PoolConfig: NewPoolConfig(),
 	}
 	for _, option := range options {
@@ -371,6 +771,30 @@ func NewClientConfig(options ...ClientConfigOption) ClientConfig {
 	return config
 }
 
+// Allow plain http:// to a server other than localhost, credentials and tokens are then sent
+// unencrypted. Without it NewClient warns (or fails with WithRequireHTTPS).
+func AllowInsecure() ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.AllowInsecure = true
+	}
+}
+
+// Set whether NewClient fails instead of warning when the server URL is plain http:// to a host
+// other than localhost (AllowInsecure still permits it)
+func WithRequireHTTPS(val bool) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.RequireHTTPS = val
+	}
+}
+
+// Set how often .env.client is checked for changes, a change calls ReloadConfig (see it for the
+// settings that are applied). Disabled if 0.
+func WithConfigWatch(interval time.Duration) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.ConfigWatchInterval = interval
+	}
+}
+
 // Set the server URL for client
 func WithServerURL(val string) ClientConfigOption {
 	return func(config *ClientConfig) {
@@ -420,6 +844,204 @@ func WithPoolConfig(val *PoolConfig) ClientConfigOption {
 	}
 }
 
+// Set the primary key column used by FindByID
+func WithPrimaryKeyColumn(val string) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.PrimaryKeyColumn = val
+	}
+}
+
+// Set the same client-side rate limit for both read and write requests
+func WithRateLimit(rps, burst int) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.ReadRateLimit = RateLimit{RPS: rps, Burst: burst}
+		config.WriteRateLimit = RateLimit{RPS: rps, Burst: burst}
+	}
+}
+
+// Set the client-side rate limit for read requests
+func WithReadRateLimit(rps, burst int) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.ReadRateLimit = RateLimit{RPS: rps, Burst: burst}
+	}
+}
+
+// Set the client-side rate limit for write requests
+func WithWriteRateLimit(rps, burst int) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.WriteRateLimit = RateLimit{RPS: rps, Burst: burst}
+	}
+}
+
+// Set the duration after which a request is reported to the slow query hook
+func WithSlowQueryThreshold(val time.Duration) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.SlowQueryThreshold = val
+	}
+}
+
+// Set the LIMIT added to SelectMany and SelectManyWithCondition when the caller didn't set one,
+// use Unlimited to opt out for a single query
+func WithDefaultQueryLimit(val int) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.DefaultQueryLimit = val
+	}
+}
+
+// Set whether query results are converted to the declared column types (int64 for INTEGER,
+// bool for BOOLEAN, time.Time for TIMESTAMP) using the schema, loaded once and cached.
+// Columns of tables that are not in the schema are left as decoded from JSON.
+func WithSchemaTypeCoercion(val bool) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.SchemaTypeCoercion = val
+	}
+}
+
+// Set whether concurrent identical reads (same endpoint, database, query and parameters) share
+// one in-flight request and all receive its result. Writes are never deduplicated.
+func WithReadDeduplication(val bool) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.ReadDeduplication = val
+	}
+}
+
+// Set the client-side cache of SQL query responses (SelectOneSQL, SelectOnlyOneSQL, the parameterized
+// and many variants), keyed by database, query and parameters. Up to size responses are kept for ttl,
+// the least recently used are evicted first. Writes through this client invalidate the cached
+// reads of the tables they touch, use Client.InvalidateCache for writes made by others.
+// Reads can be stale for up to ttl, so only enable it for rarely changing data.
+func WithReadCache(size int, ttl time.Duration) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.ReadCacheSize = size
+		config.ReadCacheTTL = ttl
+	}
+}
+
+// Set whether every write gets a generated idempotency key, reused across its retries
+func WithIdempotentWrites(val bool) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.IdempotentWrites = val
+	}
+}
+
+// Set the URL used to reach the leader node (ie: a load balancer VIP) instead of the URL the
+// leader advertises in the cluster status
+func WithLeaderURLOverride(val string) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.LeaderURLOverride = val
+	}
+}
+
+// Set the function mapping the URLs advertised in the cluster status (ie: pod IPs) to URLs
+// the client can reach (ie: service DNS). It runs on every node URL discovered by InitializePool
+// and RefreshTopology, leader included, before connections are created. LeaderURLOverride,
+// if set, still wins for the leader.
+func WithNodeURLRewriter(val func(nodeID, advertisedURL string) string) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.NodeURLRewriter = val
+	}
+}
+
+// Set the hook called when the cluster leader changes
+func WithLeaderChangeHook(val func(oldLeader, newLeader string)) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.OnLeaderChange = val
+	}
+}
+
+// Set the upper bounds of the latency histogram buckets
+func WithLatencyBuckets(val ...time.Duration) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.LatencyBuckets = val
+	}
+}
+
+// Set the hook called for requests slower than SlowQueryThreshold
+func WithSlowQueryHook(val func(info QueryInfo)) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.OnSlowQuery = val
+	}
+}
+
+// Set the hook called before every HTTP request to the server (including token refresh retries and
+// the fallback to leader, which share the RequestID), ie: for audit logging.
+// The hook runs synchronously on the request path, keep it fast or hand the info off to a goroutine/channel.
+func WithBeforeRequest(val func(info RequestInfo)) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.BeforeRequest = val
+	}
+}
+
+// Set the hook called after every HTTP request with the HTTP status (0 if no response was received),
+// the elapsed time and the error of the request. Like BeforeRequest it must be fast or dispatch async.
+func WithAfterResponse(val func(info RequestInfo, status int, elapsed time.Duration, err error)) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.AfterResponse = val
+	}
+}
+
+// Set the nodes a failed read is retried on, ie: FailoverFollowersFirst(2), default is FailoverLeader
+func WithFailoverPolicy(val FailoverPolicy) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.FailoverPolicy = val
+	}
+}
+
+// Set whether Connect verifies the write pool after creating the pools: at least one connection to a
+// node that allows writes must accept a no-op write probe, otherwise Connect fails with ErrWritePoolUnusable
+func WithWritePoolCheck(val bool) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.VerifyWritePool = val
+	}
+}
+
+// Set the layout (see time.Layout) time.Time and *time.Time parameters are sent with, in parameterized
+// SQL values, inserted records and query conditions, ie: "2006-01-02 15:04:05" for SQLite datetime columns.
+// With SchemaTypeCoercion the timestamp columns read back are parsed with it first. Default is DEFAULT_TIME_FORMAT.
+func WithTimeFormat(val string) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.TimeFormat = val
+	}
+}
+
+// Set the hook called when a node's pool is at its max size and the requests stayed over
+// ScaleUpThreshold for PoolConfig.SaturationWindow, a sign that MaxPoolSize (or MaxWritePoolSize)
+// should be raised. It fires once until the load goes back under the threshold, in its own goroutine.
+func WithPoolSaturatedHook(val func(nodeID string, activeRequests, maxPool int)) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.OnPoolSaturated = val
+	}
+}
+
+// Set how long Status, Leader and Peers reuse the last status instead of calling the server,
+// use a negative value to always call it. ForceStatus bypasses the cache.
+func WithStatusCacheTTL(val time.Duration) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.StatusCacheTTL = val
+	}
+}
+
+// Add a request middleware, middlewares run in the order they are added
+func WithMiddleware(val Middleware) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.Middlewares = append(config.Middlewares, val)
+	}
+}
+
+// Set the JSON encoder/decoder
+func WithJSONCodec(val JSONCodec) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.JSONCodec = val
+	}
+}
+
+// Set the interval after which InsertStream flushes a partial batch
+func WithStreamFlushInterval(val time.Duration) ClientConfigOption {
+	return func(config *ClientConfig) {
+		config.StreamFlushInterval = val
+	}
+}
+
 // Set the HTTP client configuration
 func WithHTTPClientConfig(val *HTTPClientConfig) ClientConfigOption {
 	return func(config *ClientConfig) {
@@ -427,15 +1049,34 @@ func WithHTTPClientConfig(val *HTTPClientConfig) ClientConfigOption {
 	}
 }
 
+// Set a custom transport for all HTTP clients, see WithTransport
+func WithHTTPTransport(val http.RoundTripper) ClientConfigOption {
+	return func(config *ClientConfig) {
+		if config.HTTPClientConfig == nil {
+			config.HTTPClientConfig = NewHTTPClientConfig()
+		}
+		config.HTTPClientConfig.Transport = val
+	}
+}
+
 //-----------------------------------------------------------------------------
 // Client initialization function - enhanced with pool setup
 //-----------------------------------------------------------------------------
 
 // NewClient creates a new SureSQL client with the provided config and connection pooling
 func NewClient(config ClientConfig) (*Client, error) {
+	if err := checkClientConfig(&config); err != nil {
+		return nil, err
+	}
 	if config.HTTPTimeout == 0 {
 		config.HTTPTimeout = DEFAULT_TIMEOUT
 	}
+	if config.PrimaryKeyColumn == "" {
+		config.PrimaryKeyColumn = DEFAULT_PRIMARY_KEY_COLUMN
+	}
+	if config.StreamFlushInterval == 0 {
+		config.StreamFlushInterval = DEFAULT_STREAM_FLUSH_INTERVAL
+	}
 
 	// Initialize pool config with defaults or provided values
 	poolConfig := NewPoolConfig()
@@ -451,6 +1092,19 @@ func NewClient(config ClientConfig) (*Client, error) {
 		poolConfig.ConnectionTTL = ValueOrDefault(config.PoolConfig.ConnectionTTL, poolConfig.ConnectionTTL, DurationBiggerThanZero)
 		poolConfig.ScaleUpBatchSize = ValueOrDefault(config.PoolConfig.ScaleUpBatchSize, poolConfig.ScaleUpBatchSize, IntBiggerThanZero)
 		poolConfig.UsageWindowSize = ValueOrDefault(config.PoolConfig.UsageWindowSize, poolConfig.UsageWindowSize, IntBiggerThanZero)
+		poolConfig.TopologyRefreshInterval = ValueOrDefault(config.PoolConfig.TopologyRefreshInterval, poolConfig.TopologyRefreshInterval, DurationBiggerThanZero)
+		poolConfig.ConnectConcurrency = ValueOrDefault(config.PoolConfig.ConnectConcurrency, poolConfig.ConnectConcurrency, IntBiggerThanZero)
+		poolConfig.AcquireTimeout = ValueOrDefault(config.PoolConfig.AcquireTimeout, poolConfig.AcquireTimeout, DurationBiggerThanZero)
+		poolConfig.MaxInFlightPerNode = ValueOrDefault(config.PoolConfig.MaxInFlightPerNode, poolConfig.MaxInFlightPerNode, IntBiggerThanZero)
+		poolConfig.MaxInFlightBlock = mergePoolBool(config.PoolConfig, config.PoolConfig.MaxInFlightBlock, poolConfig.MaxInFlightBlock)
+		poolConfig.FairAcquire = mergePoolBool(config.PoolConfig, config.PoolConfig.FairAcquire, poolConfig.FairAcquire)
+		poolConfig.ScaleUpInterval = ValueOrDefault(config.PoolConfig.ScaleUpInterval, poolConfig.ScaleUpInterval, DurationBiggerThanZero)
+		poolConfig.ScaleUpJitter = ValueOrDefault(config.PoolConfig.ScaleUpJitter, poolConfig.ScaleUpJitter, DurationBiggerThanZero)
+		poolConfig.EventHistorySize = ValueOrDefault(config.PoolConfig.EventHistorySize, poolConfig.EventHistorySize, IntBiggerThanZero)
+		poolConfig.SaturationWindow = ValueOrDefault(config.PoolConfig.SaturationWindow, poolConfig.SaturationWindow, DurationBiggerThanZero)
+	}
+	if err := checkPoolConfig(config.PoolConfig, poolConfig); err != nil {
+		return nil, err
 	}
 
 	// Initialize HTTP client config if not provided
@@ -476,7 +1130,14 @@ func NewClient(config ClientConfig) (*Client, error) {
 		statsPerNodeRead:  make(map[string]*ConnectionStats),
 		statsPerNodeWrite: make(map[string]*ConnectionStats),
 		PoolConfig:        *poolConfig,
+		readLimiter:       newRateLimiter(config.ReadRateLimit),
+		readCache:         newReadCache(config.ReadCacheSize, config.ReadCacheTTL),
+		writeLimiter:      newRateLimiter(config.WriteRateLimit),
+		latency:           newLatencyRecorder(config.LatencyBuckets),
+		bulkhead:          newBulkhead(poolConfig.MaxInFlightPerNode, poolConfig.FairAcquire),
+		events:            newEventRing(poolConfig.EventHistorySize),
 	}
+	client.settings.Store(newHotConfig(poolConfig, &config))
 	// Connect to server to get a token
 	// if config.Username != "" && config.Password != "" {
 	// 	err := client.Connect(config.Username, config.Password)
@@ -493,6 +1154,10 @@ func IntBiggerThanZero(a, b int) bool {
 	return a > 0
 }
 
+func Int64BiggerThanZero(a, b int64) bool {
+	return a > 0
+}
+
 func DurationBiggerThanZero(a, b time.Duration) bool {
 	return a > 0
 }
@@ -519,6 +1184,8 @@ func NewHTTPClientConfig(options ...HTTPClientConfigOption) *HTTPClientConfig {
 	maxIdle := object.IntPlus(utils.GetEnv("SURESQL_HTTP_MAX_IDLE_CONNECTION", ""), 0)
 	maxIdlePerHost := object.IntPlus(utils.GetEnv("SURESQL_HTTP_MAX_IDLE_CONNS_PER_HOST", ""), 0)
 	maxConnsPerHost := object.IntPlus(utils.GetEnv("SURESQL_HTTP_MAX_CONNS_PER_HOST", ""), 0)
+	http2, _ := strconv.ParseBool(os.Getenv("SURESQL_HTTP2"))
+	maxResponseBytes := object.IntPlus(utils.GetEnv("SURESQL_HTTP_MAX_RESPONSE_BYTES", ""), 0)
 	idleConnTimeout := object.IntPlus(utils.GetEnv("SURESQL_HTTP_IDLE_CONN_TIMEOUT", ""), 0)
 
 	config := HTTPClientConfig{
@@ -532,6 +1199,8 @@ func NewHTTPClientConfig(options ...HTTPClientConfigOption) *HTTPClientConfig {
 		MaxIdleConnsPerHost:   ValueOrDefault(maxIdlePerHost, DEFAULT_MAX_IDLE_CONNECTIONS_PER_HOST, IntBiggerThanZero),
 		MaxConnsPerHost:       ValueOrDefault(maxConnsPerHost, DEFAULT_MAX_CONNECTIONS_PER_HOST, IntBiggerThanZero),
 		IdleConnTimeout:       ValueOrDefault(time.Duration(idleConnTimeout)*time.Second, DEFAULT_IDLE_CONNECTION_TIMEOUT, DurationBiggerThanZero),
+		MaxResponseBytes:      ValueOrDefault(int64(maxResponseBytes), DEFAULT_MAX_RESPONSE_BYTES, Int64BiggerThanZero),
+		HTTP2:                 http2,
 	}
 
 	for _, option := range options {
@@ -555,6 +1224,25 @@ func WithTimeout(timeout time.Duration) HTTPClientConfigOption {
 	}
 }
 
+// WithHTTP2 sets whether the transport attempts HTTP/2. HTTP/2 is negotiated during the TLS
+// handshake, so it only applies to https:// servers, plain http:// stays on HTTP/1.1.
+// With HTTP/2 many requests share one TCP connection per node, so MaxConnsPerHost limits the
+// TCP connections (each carrying many concurrent streams) instead of the concurrent requests,
+// and pool sizes no longer map to socket counts.
+func WithHTTP2(http2 bool) HTTPClientConfigOption {
+	return func(config *HTTPClientConfig) {
+		config.HTTP2 = http2
+	}
+}
+
+// WithMaxResponseBytes sets the maximum size of a response body, a bigger response (ie: a query
+// without LIMIT on a huge table) fails with ErrResponseTooLarge instead of exhausting memory
+func WithMaxResponseBytes(maxBytes int64) HTTPClientConfigOption {
+	return func(config *HTTPClientConfig) {
+		config.MaxResponseBytes = maxBytes
+	}
+}
+
 // WithDialTimeout sets the dial timeout
 func WithDialTimeout(timeout time.Duration) HTTPClientConfigOption {
 	return func(config *HTTPClientConfig) {
@@ -611,6 +1299,37 @@ func WithMaxConnsPerHost(max int) HTTPClientConfigOption {
 	}
 }
 
+// WithTransport sets a custom transport (ie: proxy, tracing, httptest server or mock.Transport).
+// When set, all dial/TLS/idle settings of this config are ignored, only Timeout is used.
+func WithTransport(transport http.RoundTripper) HTTPClientConfigOption {
+	return func(config *HTTPClientConfig) {
+		config.Transport = transport
+	}
+}
+
+// WithNodeTransport sets a custom transport for a single node (by node ID)
+func WithNodeTransport(nodeID string, transport http.RoundTripper) HTTPClientConfigOption {
+	return func(config *HTTPClientConfig) {
+		if config.NodeTransports == nil {
+			config.NodeTransports = make(map[string]http.RoundTripper)
+		}
+		config.NodeTransports[nodeID] = transport
+	}
+}
+
+// forNode returns the config to use for a node, with the node's own transport if it has one
+func (config *HTTPClientConfig) forNode(nodeID string) *HTTPClientConfig {
+	if config == nil {
+		return nil
+	}
+	if transport, exists := config.NodeTransports[nodeID]; exists {
+		nodeConfig := *config
+		nodeConfig.Transport = transport
+		return &nodeConfig
+	}
+	return config
+}
+
 // WithIdleConnTimeout sets the idle connection timeout
 func WithIdleConnTimeout(timeout time.Duration) HTTPClientConfigOption {
 	return func(config *HTTPClientConfig) {
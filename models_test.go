@@ -0,0 +1,39 @@
+package client
+
+import "testing"
+
+func TestNewClientPoolBoolsOverrideEnv(t *testing.T) {
+	t.Setenv("SURESQL_MAX_IN_FLIGHT_BLOCK", "true")
+	t.Setenv("SURESQL_FAIR_ACQUIRE", "true")
+
+	c, err := NewClient(ClientConfig{
+		ServerURL:  "http://localhost:1",
+		PoolConfig: NewPoolConfig(WithMaxInFlightPerNode(2, false)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.PoolConfig.MaxInFlightBlock {
+		t.Error("MaxInFlightBlock = true, the explicit false should win over the environment")
+	}
+
+	c, err = NewClient(ClientConfig{
+		ServerURL:  "http://localhost:1",
+		PoolConfig: NewPoolConfig(WithFairAcquire(false)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.PoolConfig.FairAcquire {
+		t.Error("FairAcquire = true, the explicit false should win over the environment")
+	}
+
+	// a hand built config has no say on booleans left false
+	c, err = NewClient(ClientConfig{ServerURL: "http://localhost:1", PoolConfig: &PoolConfig{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.PoolConfig.MaxInFlightBlock {
+		t.Error("MaxInFlightBlock = false, want true from the environment")
+	}
+}
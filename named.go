@@ -0,0 +1,135 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	orm "github.com/medatechnology/simpleorm"
+)
+
+//------------------------------------------------------------------
+// NAMED PARAMETERS
+//------------------------------------------------------------------
+
+// NamedToParameterized converts a query with named parameters (:name) into orm.ParametereizedSQL
+// with positional ? placeholders, building Values in the order the names appear in the query.
+// The same name can be used multiple times. Rules:
+//   - string literals, quoted identifiers ("x", `x` and [x]) and comments (-- and /* */) are left untouched
+//   - "::" is an escaped literal colon and is written as ":"
+//   - a name must start with a letter or underscore, followed by letters, digits or underscores
+//
+// Usage:
+//
+//	paramSQL, err := NamedToParameterized("UPDATE users SET name=:name WHERE id=:id", map[string]interface{}{"name": "x", "id": 1})
+func NamedToParameterized(query string, params map[string]interface{}) (orm.ParametereizedSQL, error) {
+	var sb strings.Builder
+	values := make([]interface{}, 0, len(params))
+
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		kind, end, terminated := skipSQLText(query, i)
+		switch {
+		case kind == sqlQuoted && !terminated:
+			return orm.ParametereizedSQL{}, fmt.Errorf("unterminated quoted string at position %d", i)
+		case kind == sqlComment && !terminated:
+			return orm.ParametereizedSQL{}, fmt.Errorf("unterminated comment at position %d", i)
+		case kind != sqlCode:
+			// copy the quoted literal or comment as is
+			sb.WriteString(query[i : end+1])
+			i = end
+		case ch == ':' && i+1 < len(query) && query[i+1] == ':':
+			// escaped literal colon
+			sb.WriteByte(':')
+			i++
+		case ch == ':' && i+1 < len(query) && isNameStart(query[i+1]):
+			end := i + 1
+			for end < len(query) && isNamePart(query[end]) {
+				end++
+			}
+			name := query[i+1 : end]
+			value, exists := params[name]
+			if !exists {
+				return orm.ParametereizedSQL{}, fmt.Errorf("missing value for named parameter :%s", name)
+			}
+			sb.WriteByte('?')
+			values = append(values, value)
+			i = end - 1
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+
+	return orm.ParametereizedSQL{Query: sb.String(), Values: values}, nil
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isNamePart(ch byte) bool {
+	return isNameStart(ch) || (ch >= '0' && ch <= '9')
+}
+
+// sqlText is what skipSQLText found at a position of a SQL text
+type sqlText int
+
+const (
+	sqlCode    sqlText = iota // anything else, handled by the caller
+	sqlQuoted                 // string literal or quoted identifier: '...', "...", `...` or [...]
+	sqlComment                // -- line comment (up to its newline) or /* block comment */
+)
+
+// skipSQLText finds the quoted literal or comment starting at content[i], so that the named
+// parameters and splitSQLStatements agree on what is SQL code. It returns the index of its last
+// byte, or the last byte of content and false if it is not terminated.
+func skipSQLText(content string, i int) (kind sqlText, end int, terminated bool) {
+	ch := content[i]
+	switch {
+	case ch == '\'' || ch == '"' || ch == '`' || ch == '[':
+		closing := ch
+		if ch == '[' {
+			closing = ']'
+		}
+		for end = i + 1; end < len(content); end++ {
+			if content[end] != closing {
+				continue
+			}
+			// a doubled quote is an escaped one
+			if closing != ']' && end+1 < len(content) && content[end+1] == closing {
+				end++
+				continue
+			}
+			return sqlQuoted, end, true
+		}
+		return sqlQuoted, len(content) - 1, false
+	case ch == '-' && i+1 < len(content) && content[i+1] == '-':
+		if n := strings.IndexByte(content[i:], '\n'); n >= 0 {
+			return sqlComment, i + n - 1, true
+		}
+		return sqlComment, len(content) - 1, true
+	case ch == '/' && i+1 < len(content) && content[i+1] == '*':
+		if n := strings.Index(content[i+2:], "*/"); n >= 0 {
+			return sqlComment, i + n + 3, true
+		}
+		return sqlComment, len(content) - 1, false
+	}
+	return sqlCode, i, true
+}
+
+// ExecOneSQLNamed executes a single SQL statement with named parameters (:name)
+func (c *Client) ExecOneSQLNamed(query string, params map[string]interface{}) orm.BasicSQLResult {
+	paramSQL, err := NamedToParameterized(query, params)
+	if err != nil {
+		return orm.BasicSQLResult{Error: err}
+	}
+	return c.ExecOneSQLParameterized(paramSQL)
+}
+
+// SelectOneSQLNamed executes a single SQL query with named parameters (:name)
+func (c *Client) SelectOneSQLNamed(query string, params map[string]interface{}) (orm.DBRecords, error) {
+	paramSQL, err := NamedToParameterized(query, params)
+	if err != nil {
+		return nil, err
+	}
+	return c.SelectOneSQLParameterized(paramSQL)
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNamedToParameterized(t *testing.T) {
+	params := map[string]interface{}{"id": 1, "name": "x"}
+	tests := []struct {
+		name       string
+		query      string
+		wantQuery  string
+		wantValues []interface{}
+		wantErr    string
+	}{
+		{
+			name:       "parameters",
+			query:      "UPDATE users SET name = :name WHERE id = :id",
+			wantQuery:  "UPDATE users SET name = ? WHERE id = ?",
+			wantValues: []interface{}{"x", 1},
+		},
+		{
+			name:       "repeated name",
+			query:      "SELECT * FROM t WHERE a = :id OR b = :id",
+			wantQuery:  "SELECT * FROM t WHERE a = ? OR b = ?",
+			wantValues: []interface{}{1, 1},
+		},
+		{
+			name:       "single quotes",
+			query:      "SELECT ':id', 'it'':s :name' FROM t WHERE id = :id",
+			wantQuery:  "SELECT ':id', 'it'':s :name' FROM t WHERE id = ?",
+			wantValues: []interface{}{1},
+		},
+		{
+			name:       "quoted identifiers",
+			query:      "SELECT \"a:id\", `b:id`, [c:id] FROM t WHERE id = :id",
+			wantQuery:  "SELECT \"a:id\", `b:id`, [c:id] FROM t WHERE id = ?",
+			wantValues: []interface{}{1},
+		},
+		{
+			name:       "line comment",
+			query:      "SELECT * FROM t -- by :missing\nWHERE id = :id",
+			wantQuery:  "SELECT * FROM t -- by :missing\nWHERE id = ?",
+			wantValues: []interface{}{1},
+		},
+		{
+			name:       "block comment",
+			query:      "SELECT * FROM t /* :missing\n :id */ WHERE id = :id",
+			wantQuery:  "SELECT * FROM t /* :missing\n :id */ WHERE id = ?",
+			wantValues: []interface{}{1},
+		},
+		{
+			name:       "escaped colon",
+			query:      "SELECT '10:30' AS t, time(x)::id FROM t WHERE id = :id",
+			wantQuery:  "SELECT '10:30' AS t, time(x):id FROM t WHERE id = ?",
+			wantValues: []interface{}{1},
+		},
+		{
+			name:       "not a name",
+			query:      "SELECT a : b, :1 FROM t",
+			wantQuery:  "SELECT a : b, :1 FROM t",
+			wantValues: []interface{}{},
+		},
+		{
+			name:    "missing value",
+			query:   "SELECT * FROM t WHERE id = :other",
+			wantErr: "missing value for named parameter :other",
+		},
+		{
+			name:    "unterminated quote",
+			query:   "SELECT 'abc WHERE id = :id",
+			wantErr: "unterminated quoted string at position 7",
+		},
+		{
+			name:    "unterminated comment",
+			query:   "SELECT 1 /* :id",
+			wantErr: "unterminated comment at position 9",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NamedToParameterized(tt.query, params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Query != tt.wantQuery {
+				t.Errorf("query = %q, want %q", got.Query, tt.wantQuery)
+			}
+			if !reflect.DeepEqual(got.Values, tt.wantValues) {
+				t.Errorf("values = %v, want %v", got.Values, tt.wantValues)
+			}
+		})
+	}
+}
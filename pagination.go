@@ -0,0 +1,271 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	orm "github.com/medatechnology/simpleorm"
+	"github.com/medatechnology/suresql"
+)
+
+//------------------------------------------------------------------
+// KEYSET PAGINATION
+//------------------------------------------------------------------
+
+// Page is one page of a keyset pagination
+type Page struct {
+	Records    []orm.DBRecord
+	NextCursor interface{} // orderColumn value of the last row, pass it as afterValue for the next page
+	HasMore    bool        // false when this is the last page
+}
+
+// KeysetPage returns the rows after afterValue ordered by orderColumn (WHERE orderColumn > ? ORDER BY orderColumn LIMIT pageSize).
+// Unlike OFFSET it stays fast on big tables and is stable when rows are inserted between pages.
+// Use nil afterValue for the first page. orderColumn should be unique (ie: the primary key),
+// otherwise rows sharing the cursor value at a page boundary are skipped. Only the WHERE part
+// of condition is used. pageSize <= 0 uses orm.DEFAULT_PAGINATION_LIMIT.
+// Usage:
+//
+//	page, err := db.KeysetPage("users", "id", nil, 100, nil)
+//	for page.HasMore {
+//	    page, err = db.KeysetPage("users", "id", page.NextCursor, 100, nil)
+//	}
+func (c *Client) KeysetPage(tableName string, orderColumn string, afterValue interface{}, pageSize int, condition *orm.Condition) (Page, error) {
+	if pageSize <= 0 {
+		pageSize = orm.DEFAULT_PAGINATION_LIMIT
+	}
+	whereClause, values, err := ConditionToWhere(condition)
+	if err != nil {
+		return Page{}, err
+	}
+
+	var clauses []string
+	if strings.TrimSpace(whereClause) != "" {
+		clauses = append(clauses, "("+whereClause+")")
+	}
+	if afterValue != nil {
+		clauses = append(clauses, orderColumn+" > ?")
+		values = append(values, afterValue)
+	}
+	query := "SELECT * FROM " + tableName
+	if len(clauses) > 0 {
+		query += " WHERE " + strings.Join(clauses, " AND ")
+	}
+	// ask for one more row to know if there is a next page
+	query += fmt.Sprintf(" ORDER BY %s LIMIT %d", orderColumn, pageSize+1)
+
+	records, err := c.SelectOneSQLParameterized(orm.ParametereizedSQL{Query: query, Values: values})
+	if err != nil {
+		if errors.Is(err, orm.ErrSQLNoRows) {
+			return Page{Records: []orm.DBRecord{}}, nil
+		}
+		return Page{}, err
+	}
+
+	page := Page{Records: records}
+	if len(records) > pageSize {
+		page.Records = records[:pageSize]
+		page.HasMore = true
+	}
+	page.NextCursor = page.Records[len(page.Records)-1].Data[orderColumn]
+	return page, nil
+}
+
+//------------------------------------------------------------------
+// CONDITION STREAMING
+//------------------------------------------------------------------
+
+// RowIterator walks the rows of a query fetched in batches, only one batch is held in memory.
+// Usage:
+//
+//	it, err := db.SelectManyWithConditionStream(ctx, "orders", condition)
+//	defer it.Close()
+//	for it.Next() {
+//	    record := it.Record()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type RowIterator struct {
+	ctx     context.Context
+	fetch   func(ctx context.Context) ([]orm.DBRecord, bool, error) // next batch and whether there are more
+	batch   []orm.DBRecord
+	pos     int
+	more    bool
+	current orm.DBRecord
+	err     error
+}
+
+// Next advances to the next row, fetching the next batch when needed. It returns false at the
+// end of the rows, on error or when the context is cancelled, check Err afterwards.
+func (it *RowIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	for it.pos >= len(it.batch) {
+		if !it.more {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		it.batch, it.more, it.err = it.fetch(it.ctx)
+		it.pos = 0
+		if it.err != nil {
+			return false
+		}
+	}
+	it.current = it.batch[it.pos]
+	it.pos++
+	return true
+}
+
+// Record returns the current row
+func (it *RowIterator) Record() orm.DBRecord {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, nil if all rows were read
+func (it *RowIterator) Err() error {
+	return it.err
+}
+
+// Close stops the iteration, no more batches are fetched. It is safe to call more than once.
+func (it *RowIterator) Close() {
+	it.batch, it.more = nil, false
+}
+
+// keysetColumn is one column of a keyset ordering
+type keysetColumn struct {
+	column string // as written in the condition, ie: "orders.created_at"
+	key    string // name in DBRecord.Data, ie: "created_at"
+	desc   bool
+}
+
+// SelectManyWithConditionStream selects the rows matching condition in batches of DEFAULT_STREAM_PAGE_SIZE
+// using keyset pagination, so big results are never held in memory at once. Rows are ordered by the
+// condition OrderBy (plain columns with optional ASC/DESC) followed by the primary key, which makes
+// the order unique. Between batches the condition gets "after the last row" appended, so rows inserted
+// or deleted meanwhile don't shift the batches. The ordering columns must not be NULL. A condition
+// Limit caps the total number of rows, Offset and GroupBy are not supported. Fetching stops when ctx
+// is cancelled.
+// Usage:
+//
+//	it, err := db.SelectManyWithConditionStream(ctx, "orders", &orm.Condition{Field: "status", Operator: "=", Value: "paid", OrderBy: []string{"created_at DESC"}})
+func (c *Client) SelectManyWithConditionStream(ctx context.Context, tableName string, condition *orm.Condition) (*RowIterator, error) {
+	if err := ValidateCondition(condition); err != nil {
+		return nil, err
+	}
+	var where *orm.Condition
+	var orderBy []string
+	remaining := 0
+	if condition != nil {
+		if condition.Offset > 0 || len(condition.GroupBy) > 0 {
+			return nil, errors.New("stream does not support Offset or GroupBy")
+		}
+		orderBy, remaining = condition.OrderBy, condition.Limit
+		if condition.Field != "" || len(condition.Nested) > 0 {
+			inner := *condition
+			inner.OrderBy, inner.GroupBy, inner.Limit, inner.Offset = nil, nil, 0, 0
+			where = &inner
+		}
+	}
+	columns, err := c.keysetColumns(orderBy)
+	if err != nil {
+		return nil, err
+	}
+	ordering := make([]string, len(columns))
+	for i, col := range columns {
+		ordering[i] = col.column
+		if col.desc {
+			ordering[i] += " DESC"
+		}
+	}
+
+	var cursor []interface{} // ordering values of the last row, nil before the first batch
+	fetch := func(ctx context.Context) ([]orm.DBRecord, bool, error) {
+		pageSize := DEFAULT_STREAM_PAGE_SIZE
+		if remaining > 0 {
+			pageSize = min(pageSize, remaining)
+		}
+		page := &orm.Condition{OrderBy: ordering, Limit: pageSize}
+		var parts []orm.Condition
+		if where != nil {
+			parts = append(parts, *where)
+		}
+		if cursor != nil {
+			parts = append(parts, keysetAfter(columns, cursor))
+		}
+		if len(parts) > 0 {
+			page.Logic, page.Nested = "AND", parts
+		}
+		paramSQL, err := conditionToSelect(tableName, page)
+		if err != nil {
+			return nil, false, err
+		}
+		req := &suresql.SQLRequest{ParamSQL: []orm.ParametereizedSQL{paramSQL}}
+		response, err := sendRequestContext[suresql.QueryResponseSQL](ctx, c, "POST", "/db/api/querysql", req, RequestTypeSQLQuery, AUTO_REFRESH, FALLBACK_LEADER)
+		if err != nil || len(response) == 0 || len(response[0].Records) == 0 {
+			return nil, false, err
+		}
+
+		records := response[0].Records
+		last := records[len(records)-1].Data
+		cursor = make([]interface{}, len(columns))
+		for i, col := range columns {
+			if cursor[i] = last[col.key]; cursor[i] == nil {
+				return nil, false, fmt.Errorf("order column %s is NULL or not in the result, cannot continue the stream", col.column)
+			}
+		}
+		more := len(records) == pageSize
+		if remaining > 0 {
+			remaining -= len(records)
+			more = more && remaining > 0
+		}
+		return records, more, nil
+	}
+	return &RowIterator{ctx: ctx, fetch: fetch, more: true}, nil
+}
+
+// keysetColumns parses OrderBy entries ("col", "col DESC") and appends the primary key if missing
+func (c *Client) keysetColumns(orderBy []string) ([]keysetColumn, error) {
+	columns := make([]keysetColumn, 0, len(orderBy)+1)
+	hasPrimaryKey := false
+	for _, entry := range orderBy {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 || len(fields) > 2 || (len(fields) == 2 && !strings.EqualFold(fields[1], "ASC") && !strings.EqualFold(fields[1], "DESC")) {
+			return nil, fmt.Errorf("stream cannot order by %q, use a column with optional ASC or DESC", entry)
+		}
+		key := fields[0]
+		if dot := strings.LastIndexByte(key, '.'); dot >= 0 {
+			key = key[dot+1:]
+		}
+		key = unquoteIdentifier(key)
+		hasPrimaryKey = hasPrimaryKey || strings.EqualFold(key, c.Config.PrimaryKeyColumn)
+		columns = append(columns, keysetColumn{column: fields[0], key: key, desc: len(fields) == 2 && strings.EqualFold(fields[1], "DESC")})
+	}
+	if !hasPrimaryKey {
+		columns = append(columns, keysetColumn{column: c.Config.PrimaryKeyColumn, key: c.Config.PrimaryKeyColumn})
+	}
+	return columns, nil
+}
+
+// keysetAfter returns the condition of the rows after cursor in the columns order:
+// (c0 > v0) OR (c0 = v0 AND c1 > v1) OR ..., with < for DESC columns
+func keysetAfter(columns []keysetColumn, cursor []interface{}) orm.Condition {
+	after := orm.Condition{Logic: "OR"}
+	for i, col := range columns {
+		group := orm.Condition{Logic: "AND"}
+		for j := 0; j < i; j++ {
+			group.Nested = append(group.Nested, orm.Condition{Field: columns[j].column, Operator: "=", Value: cursor[j]})
+		}
+		operator := ">"
+		if col.desc {
+			operator = "<"
+		}
+		group.Nested = append(group.Nested, orm.Condition{Field: col.column, Operator: operator, Value: cursor[i]})
+		after.Nested = append(after.Nested, group)
+	}
+	return after
+}
@@ -1,9 +1,13 @@
 package client
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/medatechnology/suresql"
@@ -24,9 +28,34 @@ func NewConnectionPool(isWritePool bool, maxRead, maxWrite int) *ConnectionPool
 		maxPool:               maxRead,
 		maxWritePool:          maxWrite,
 		nodeHTTPClients:       make(map[string]*http.Client),
+		drainedNodes:          make(map[string]bool),
 	}
 }
 
+// NodeCount returns the number of nodes in the round-robin order
+func (p *ConnectionPool) NodeCount() int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return len(p.nodeOrder)
+}
+
+// nodeIDs returns the nodes in the round-robin order (drained nodes are not included)
+func (p *ConnectionPool) nodeIDs() []string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return append([]string(nil), p.nodeOrder...)
+}
+
+// peekNode returns the next node in the round-robin order without advancing it
+func (p *ConnectionPool) peekNode() string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if len(p.nodeOrder) == 0 {
+		return ""
+	}
+	return p.nodeOrder[p.nodeOrderIndex%len(p.nodeOrder)]
+}
+
 // Size returns the total number of connections in the pool
 func (p *ConnectionPool) Size() int {
 	p.mutex.RLock()
@@ -62,8 +91,10 @@ func (p *ConnectionPool) Add(conn *Connection) {
 		p.nodeConnections[nodeID] = make([]*Connection, 0)
 		p.nodeRoundRobinIndices[nodeID] = 0
 
-		// Add to node order for node-level round-robin
-		p.nodeOrder = append(p.nodeOrder, nodeID)
+		// Add to node order for node-level round-robin, unless the node is drained
+		if !p.drainedNodes[nodeID] {
+			p.nodeOrder = append(p.nodeOrder, nodeID)
+		}
 	}
 
 	// Add connection to the node's pool
@@ -95,8 +126,10 @@ func (p *ConnectionPool) AddBatch(conns []*Connection) {
 			p.nodeConnections[nodeID] = make([]*Connection, 0)
 			p.nodeRoundRobinIndices[nodeID] = 0
 
-			// Add to node order for node-level round-robin
-			p.nodeOrder = append(p.nodeOrder, nodeID)
+			// Add to node order for node-level round-robin, unless the node is drained
+			if !p.drainedNodes[nodeID] {
+				p.nodeOrder = append(p.nodeOrder, nodeID)
+			}
 		}
 
 		p.nodeConnections[nodeID] = append(p.nodeConnections[nodeID], nodeConns...)
@@ -125,11 +158,9 @@ func (p *ConnectionPool) Remove(conn *Connection) bool {
 				delete(p.nodeConnections, nodeID)
 				delete(p.nodeRoundRobinIndices, nodeID)
 
-				// Clean up HTTP client if this is the last connection
-				// Note: Only delete if we're the write pool (to avoid race conditions)
-				if p.isWritePool {
-					delete(p.nodeHTTPClients, nodeID)
-				}
+				// Clean up this pool's reference to the node HTTP client, it is not closed
+				// because the other pool might still use it (see ForgetNode)
+				delete(p.nodeHTTPClients, nodeID)
 				for i, id := range p.nodeOrder {
 					if id == nodeID {
 						p.nodeOrder = append(p.nodeOrder[:i], p.nodeOrder[i+1:]...)
@@ -150,8 +181,35 @@ func (p *ConnectionPool) Remove(conn *Connection) bool {
 	return false
 }
 
-// GetConnection gets the next connection using true node-level round-robin
+// modeAllows reports whether a node with mode ("r", "w" or "rw") serves reads or writes.
+// An empty mode (not reported by the server) allows both.
+func modeAllows(mode string, isWrite bool) bool {
+	if mode == "" {
+		return true
+	}
+	if isWrite {
+		return strings.Contains(mode, "w")
+	}
+	return strings.Contains(mode, "r")
+}
+
+// poolMode returns the node mode required by the pool type
+func (p *ConnectionPool) poolMode() string {
+	if p.isWritePool {
+		return "w"
+	}
+	return "r"
+}
+
+// GetConnection gets the next connection using true node-level round-robin, nodes whose
+// mode doesn't allow the pool type (ie: read-only replicas in the write pool) are skipped
 func (p *ConnectionPool) GetConnection() (*Connection, error) {
+	return p.GetConnectionByMode(p.poolMode())
+}
+
+// GetConnectionByMode gets the next connection (node-level round-robin) of a node whose mode
+// contains mode, ie: "w" for a node accepting writes
+func (p *ConnectionPool) GetConnectionByMode(mode string) (*Connection, error) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
@@ -166,7 +224,7 @@ func (p *ConnectionPool) GetConnection() (*Connection, error) {
 		nodeID := p.nodeOrder[nodeIdx]
 
 		nodeConns := p.nodeConnections[nodeID]
-		if len(nodeConns) > 0 {
+		if len(nodeConns) > 0 && (nodeConns[0].Mode == "" || strings.Contains(nodeConns[0].Mode, mode)) {
 			// Get connection from this node using round-robin
 			connIdx := p.nodeRoundRobinIndices[nodeID]
 			conn := nodeConns[connIdx]
@@ -182,7 +240,7 @@ func (p *ConnectionPool) GetConnection() (*Connection, error) {
 		}
 	}
 
-	return nil, errors.New("no connections available in pool despite having nodes")
+	return nil, fmt.Errorf("no connections available in pool for mode %q", mode)
 }
 
 // GetConnectionForNode gets a connection for a specific node
@@ -194,6 +252,9 @@ func (p *ConnectionPool) GetConnectionForNode(nodeID string) (*Connection, error
 	if !exists || len(nodeConns) == 0 {
 		return nil, fmt.Errorf("no connections available for node %s", nodeID)
 	}
+	if !modeAllows(nodeConns[0].Mode, p.isWritePool) {
+		return nil, fmt.Errorf("node %s mode %q does not allow %s", nodeID, nodeConns[0].Mode, p.poolMode())
+	}
 
 	// Get connection using round-robin
 	connIdx := p.nodeRoundRobinIndices[nodeID]
@@ -246,7 +307,8 @@ func (p *ConnectionPool) GetIdleConnections(idleTimeout time.Duration) []*Connec
 
 	for _, conns := range p.nodeConnections {
 		for _, conn := range conns {
-			if now.Sub(conn.LastUsed) > idleTimeout {
+			// connections pinned by a Session are never idle
+			if atomic.LoadInt32(&conn.pinned) == 0 && now.Sub(conn.LastUsed) > idleTimeout {
 				idle = append(idle, conn)
 			}
 		}
@@ -275,7 +337,8 @@ func (p *ConnectionPool) RemoveIdleConnections(idleTimeout time.Duration, minSiz
 		var idle []*Connection
 
 		for _, conn := range conns {
-			if now.Sub(conn.LastUsed) > idleTimeout {
+			// connections pinned by a Session are never idle
+			if atomic.LoadInt32(&conn.pinned) == 0 && now.Sub(conn.LastUsed) > idleTimeout {
 				idle = append(idle, conn)
 			} else {
 				active = append(active, conn)
@@ -341,72 +404,345 @@ func (p *ConnectionPool) Clear() {
 	p.nodeOrderIndex = 0
 	// Clear HTTP clients (they'll be garbage collected)
 	p.nodeHTTPClients = make(map[string]*http.Client)
+	p.drainedNodes = make(map[string]bool)
+}
+
+// RemoveNode removes all connections of a node from the pool (including its HTTP client)
+// and returns the number of connections removed
+func (p *ConnectionPool) RemoveNode(nodeID string) int {
+	removed, _ := p.removeNode(nodeID)
+	return removed
+}
+
+// removeNode is RemoveNode also returning the HTTP clients used by the removed connections
+func (p *ConnectionPool) removeNode(nodeID string) (int, []*http.Client) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var clients []*http.Client
+	if client, exists := p.nodeHTTPClients[nodeID]; exists {
+		clients = append(clients, client)
+	}
+	for _, conn := range p.nodeConnections[nodeID] {
+		if conn.HTTPClient != nil {
+			clients = append(clients, conn.HTTPClient)
+		}
+	}
+
+	removed := len(p.nodeConnections[nodeID])
+	delete(p.nodeConnections, nodeID)
+	delete(p.nodeRoundRobinIndices, nodeID)
+	delete(p.nodeHTTPClients, nodeID)
+	delete(p.drainedNodes, nodeID)
+	p.removeFromNodeOrder(nodeID)
+
+	return removed, clients
+}
+
+// Drain takes a node out of the round-robin rotation so no new requests are routed to it.
+// Existing connections are kept, so in-flight requests can finish normally.
+// Returns false if the node has no connections in this pool.
+func (p *ConnectionPool) Drain(nodeID string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, exists := p.nodeConnections[nodeID]; !exists {
+		return false
+	}
+	p.drainedNodes[nodeID] = true
+	p.removeFromNodeOrder(nodeID)
+	return true
+}
+
+// Undrain puts a drained node back into the round-robin rotation
+func (p *ConnectionPool) Undrain(nodeID string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.drainedNodes[nodeID] {
+		return
+	}
+	delete(p.drainedNodes, nodeID)
+
+	// Only nodes that still have connections go back into rotation
+	if len(p.nodeConnections[nodeID]) > 0 {
+		p.nodeOrder = append(p.nodeOrder, nodeID)
+	}
+}
+
+// IsDraining returns true if the node is currently drained in this pool
+func (p *ConnectionPool) IsDraining(nodeID string) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.drainedNodes[nodeID]
+}
+
+// setLeaderNode marks the connections of nodeID as leader and all others as not leader
+func (p *ConnectionPool) setLeaderNode(nodeID string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for id, conns := range p.nodeConnections {
+		for _, conn := range conns {
+			conn.IsLeader = id == nodeID
+		}
+	}
+}
+
+// removeFromNodeOrder removes a node from the node-level round-robin order.
+// Caller must hold the pool mutex.
+func (p *ConnectionPool) removeFromNodeOrder(nodeID string) {
+	for i, id := range p.nodeOrder {
+		if id == nodeID {
+			p.nodeOrder = append(p.nodeOrder[:i], p.nodeOrder[i+1:]...)
+
+			// Adjust nodeOrderIndex if needed
+			if p.nodeOrderIndex >= len(p.nodeOrder) {
+				p.nodeOrderIndex = 0
+			}
+			break
+		}
+	}
 }
 
 //-----------------------------------------------------------------------------
 // Client pool management methods
 //-----------------------------------------------------------------------------
 
-// createPoolConnections creates a batch of connections for a pool
-func (c *Client) createPoolConnections(nodeURL, nodeID, nodeMode string, isLeader bool, count int) []*Connection {
+// createPoolConnections creates a batch of connections for a pool. Connections are created
+// in parallel, bounded by PoolConfig.ConnectConcurrency. Failed connections are skipped.
+// If ctx is cancelled midway, the connections created so far are discarded and nil is returned.
+func (c *Client) createPoolConnections(ctx context.Context, nodeURL, nodeID, nodeMode string, isLeader bool, count int) []*Connection {
 	if count <= 0 {
 		return nil
 	}
 
 	connections := make([]*Connection, 0, count)
+	var connMutex sync.Mutex
+	var wg sync.WaitGroup
+
+	// Bounded worker pool, semaphore style
+	limit := ValueOrDefault(c.PoolConfig.ConnectConcurrency, DEFAULT_CONNECT_CONCURRENCY, IntBiggerThanZero)
+	sem := make(chan struct{}, limit)
 
 	for i := 0; i < count; i++ {
-		// Always create a new connection with its own token
-		// Never reuse tokens - each connection must have a unique token
-		conn, err := c.createAndConnectNewConnection(nodeURL, nodeID, nodeMode, isLeader)
-		if err != nil {
-			fmt.Printf("Warning: Failed to create connection to %s: %v\n", nodeURL, err)
-			continue
+		if ctx.Err() != nil {
+			break
 		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// Always create a new connection with its own token
+			// Never reuse tokens - each connection must have a unique token
+			conn, err := c.createAndConnectNewConnection(ctx, nodeURL, nodeID, nodeMode, isLeader)
+			if err != nil {
+				fmt.Printf("Warning: Failed to create connection to %s: %v\n", nodeURL, err)
+				return
+			}
 
-		connections = append(connections, conn)
+			connMutex.Lock()
+			connections = append(connections, conn)
+			connMutex.Unlock()
+		}()
 	}
+	wg.Wait()
 
+	if ctx.Err() != nil {
+		return nil
+	}
 	return connections
 }
 
 // InitializePool initializes connection pools based on node status. This should be called only from Connect()
 func (c *Client) InitializePool() error {
+	return c.initializePool(context.Background())
+}
+
+// initializePool is InitializePool with cancellation, if ctx is cancelled the partially
+// filled pools are cleared and ctx error is returned
+func (c *Client) initializePool(ctx context.Context) error {
 	// Get status to discover nodes
-	status, err := c.getStatusWithoutLock()
+	status, err := c.getStatusWithoutLock(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get status for pool initialization: %w", err)
 	}
 
 	fmt.Println("Status:", status)
-	c.status = &status
+	c.setStatus(&status)
 
 	// If this is called from Connect() which should be only called once, all variables for readPool, writePool and statsPerNode
 	// should be properly initialized (made)
 
 	// Initialize self node pools (should be the leader)
 	// Since the scaleUpNode take in form of connection (for node info like URL, Mode etc) we prepare the empty connection
-	leaderConn := NewConnection(&c.Config, status.URL, status.NodeID, status.Mode, status.IsLeader, suresql.TokenTable{})
-	c.scaleUpNode(leaderConn, IS_WRITE)
-	c.scaleUpNode(leaderConn, IS_READ)
+	leaderConn := NewConnection(&c.Config, c.nodeURL(&status, status.StatusStruct), status.NodeID, status.Mode, status.IsLeader, suresql.TokenTable{})
+	c.scaleUpNode(ctx, leaderConn, IS_WRITE)
+	c.scaleUpNode(ctx, leaderConn, IS_READ)
 	// c.initializePoolForNode(status.URL, status.NodeID, status.Mode, status.IsLeader, status.MaxPool)
 
 	// Initialize peer nodes pools
 	for _, peer := range status.Peers {
-		tmpConn := NewConnection(&c.Config, peer.URL, peer.NodeID, peer.Mode, peer.IsLeader, suresql.TokenTable{})
-		c.scaleUpNode(tmpConn, IS_WRITE)
-		c.scaleUpNode(tmpConn, IS_READ)
+		tmpConn := NewConnection(&c.Config, c.nodeURL(&status, peer), peer.NodeID, peer.Mode, peer.IsLeader, suresql.TokenTable{})
+		c.scaleUpNode(ctx, tmpConn, IS_WRITE)
+		c.scaleUpNode(ctx, tmpConn, IS_READ)
 		// c.initializePoolForNode(peer.URL, peer.NodeID, peer.Mode, peer.IsLeader, peer.MaxPool)
 	}
 
+	// Startup was cancelled, clean up whatever was created so far
+	if err := ctx.Err(); err != nil {
+		c.readPool.Clear()
+		c.writePool.Clear()
+		return fmt.Errorf("pool initialization cancelled: %w", err)
+	}
+
+	if c.Config.VerifyWritePool {
+		if err := c.verifyWritePool(ctx); err != nil {
+			c.readPool.Clear()
+			c.writePool.Clear()
+			return err
+		}
+	}
+
 	// Start the cleanup timer if not already running
 	if c.cleanupTimer == nil {
 		c.startCleanupTimer()
 	}
 
+	// Start the topology refresh if enabled and not already running
+	if c.hot().topologyRefreshInterval > 0 && c.topologyTimer == nil {
+		c.startTopologyRefresh()
+	}
+
+	// Start watching .env.client if enabled and not already running
+	if c.Config.ConfigWatchInterval > 0 && c.configWatchDone == nil {
+		c.startConfigWatch()
+	}
+
 	return nil
 }
 
+// ErrWritePoolUnusable is returned by Connect (with VerifyWritePool) when no node of the write pool accepts writes
+var ErrWritePoolUnusable = errors.New("write pool is unusable")
+
+// verifyWritePool checks that at least one connection of the write pool can write: its node mode
+// allows writes and it accepts a no-op statement on the execute endpoint (which goes through the
+// cluster consensus like any write). The leader is probed first.
+func (c *Client) verifyWritePool(ctx context.Context) error {
+	var candidates []*Connection
+	for _, conn := range c.writePool.GetAllConnections() {
+		if !modeAllows(conn.Mode, IS_WRITE) {
+			continue
+		}
+		if conn.IsLeader {
+			candidates = append([]*Connection{conn}, candidates...)
+		} else {
+			candidates = append(candidates, conn)
+		}
+	}
+	if len(candidates) == 0 {
+		return fmt.Errorf("%w: no connection to a node that allows writes", ErrWritePoolUnusable)
+	}
+
+	req := &suresql.SQLRequest{Statements: []string{"SELECT 1"}}
+	var err error
+	for _, conn := range candidates {
+		_, errP := c.sendRequestToPoolRaw(ctx, conn, "POST", "/db/api/sql", req, WITH_TOKEN, AUTO_REFRESH, NO_FALLBACK)
+		if errP == nil {
+			return nil
+		}
+		err = fmt.Errorf("%w: write probe on node %s failed: %w", ErrWritePoolUnusable, conn.NodeID, errP)
+	}
+	return err
+}
+
+// acquireConnection gets a read or write connection. If none is available it keeps retrying
+// (which also re-initializes an empty pool) up to the acquire timeout or until ctx is done.
+func (c *Client) acquireConnection(ctx context.Context, isWrite bool) (*Connection, error) {
+	getConnection := c.getReadConnection
+	if isWrite {
+		getConnection = c.getWriteConnection
+	}
+	conn, err := getConnection()
+	if errors.Is(err, errNodesAtCapacity) && c.PoolConfig.MaxInFlightBlock && c.PoolConfig.FairAcquire {
+		return c.acquireConnectionFIFO(ctx, isWrite)
+	}
+	// All nodes are busy, wait for any request to finish if the bulkhead is set to block
+	for errors.Is(err, errNodesAtCapacity) && c.PoolConfig.MaxInFlightBlock {
+		released := c.bulkhead.queue(c.firstNode(isWrite))
+		// retry once in case a slot was released before we started waiting
+		if conn, err = getConnection(); !errors.Is(err, errNodesAtCapacity) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", err, ctx.Err())
+		case <-released:
+		}
+		conn, err = getConnection()
+	}
+	acquireTimeout := c.hot().acquireTimeout
+	if err == nil || errors.Is(err, errNodesAtCapacity) || acquireTimeout <= 0 {
+		return conn, err
+	}
+
+	atomic.AddInt64(&c.acquireWaits, 1)
+	ctx, cancel := context.WithTimeout(ctx, acquireTimeout)
+	defer cancel()
+	ticker := time.NewTicker(DEFAULT_ACQUIRE_RETRY_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			atomic.AddInt64(&c.acquireTimeouts, 1)
+			return nil, fmt.Errorf("%w (waited %s)", err, acquireTimeout)
+		case <-ticker.C:
+			if conn, err = getConnection(); err == nil {
+				return conn, nil
+			}
+		}
+	}
+}
+
+// acquireConnectionFIFO waits in the bulkhead queue, the longest waiting request gets the next
+// released slot of any node in its pool
+func (c *Client) acquireConnectionFIFO(ctx context.Context, isWrite bool) (*Connection, error) {
+	pool := c.readPool
+	if isWrite {
+		pool = c.writePool
+	}
+	nodeID, err := c.bulkhead.wait(ctx, pool.nodeIDs(), c.firstNode(isWrite))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errNodesAtCapacity, err)
+	}
+	conn, err := pool.GetConnectionForNode(nodeID)
+	if err != nil {
+		c.bulkhead.release(nodeID)
+		return nil, err
+	}
+
+	// Record usage outside the lock
+	go c.recordNodeUsage(conn.NodeID, isWrite)
+
+	// Track that a request is beginning
+	go c.beginRequest(conn, isWrite)
+
+	return conn, nil
+}
+
+// firstNode returns the node a request would try first, the leader for writes
+func (c *Client) firstNode(isWrite bool) string {
+	if isWrite {
+		if leaderNodeID := c.leaderNode(); leaderNodeID != "" {
+			return leaderNodeID
+		}
+		return c.writePool.peekNode()
+	}
+	return c.readPool.peekNode()
+}
+
 // getReadConnection gets the next available read connection using node-level round-robin
 func (c *Client) getReadConnection() (*Connection, error) {
 	// Try to initialize pool if it's empty
@@ -417,7 +753,7 @@ func (c *Client) getReadConnection() (*Connection, error) {
 		}
 	}
 
-	conn, err := c.readPool.GetConnection()
+	conn, err := c.pickConnection(c.readPool, "")
 	if err != nil {
 		return nil, err
 	}
@@ -465,7 +801,9 @@ func (c *Client) getWriteConnection() (*Connection, error) {
 		}
 	}
 
-	conn, err := c.writePool.GetConnection()
+	// Prioritize the current leader node for writes, the round-robin is used only if the leader
+	// is unknown, drained, busy or has no write connections
+	conn, err := c.pickConnection(c.writePool, c.leaderNode())
 	if err != nil {
 		return nil, err
 	}
@@ -485,7 +823,7 @@ func (c *Client) cleanupIdleConnections() {
 	now := time.Now()
 
 	// Check if we have status info
-	if c.status == nil {
+	if c.getStatus() == nil {
 		return
 	}
 
@@ -493,18 +831,25 @@ func (c *Client) cleanupIdleConnections() {
 	// c.getOrCreateNodeStats(c.status.NodeID,IS_WRITE)
 
 	// Process read pool
-	readRemoved := c.readPool.RemoveIdleConnections(c.PoolConfig.IdleTimeout, c.PoolConfig.MinPoolSize)
+	idleTimeout := c.hot().idleTimeout
+	readRemoved := c.readPool.RemoveIdleConnections(idleTimeout, c.PoolConfig.MinPoolSize)
 
 	// Process write pool
-	writeRemoved := c.writePool.RemoveIdleConnections(c.PoolConfig.IdleTimeout, c.PoolConfig.MinPoolSize)
+	writeRemoved := c.writePool.RemoveIdleConnections(idleTimeout, c.PoolConfig.MinPoolSize)
+
+	if readRemoved > 0 {
+		c.recordPoolEvent(POOL_EVENT_SCALE_DOWN, "", "read pool -%d idle connections", readRemoved)
+	}
+	if writeRemoved > 0 {
+		c.recordPoolEvent(POOL_EVENT_SCALE_DOWN, "", "write pool -%d idle connections", writeRemoved)
+	}
 
 	// Update stats if connections were removed
 	if readRemoved > 0 {
-		for nodeID := range c.statsPerNodeRead {
-			stats := c.getOrCreateNodeStats(nodeID, IS_READ)
+		for _, stats := range c.allNodeStats(IS_READ) {
 			stats.HistoryMutex.Lock()
 			// stats.CurrentConnections = readCount + writeCount
-			stats.CurrentConnections = c.writePool.SizeForNode(nodeID)
+			stats.CurrentConnections = c.readPool.SizeForNode(stats.NodeID)
 			stats.LastScaleDown = now
 			stats.LastCleanup = now
 			stats.ScaleDownEvents++
@@ -512,10 +857,9 @@ func (c *Client) cleanupIdleConnections() {
 		}
 	}
 	if writeRemoved > 0 {
-		for nodeID := range c.statsPerNodeRead {
-			stats := c.getOrCreateNodeStats(nodeID, IS_READ)
+		for _, stats := range c.allNodeStats(IS_WRITE) {
 			stats.HistoryMutex.Lock()
-			stats.CurrentConnections = c.readPool.SizeForNode(nodeID)
+			stats.CurrentConnections = c.writePool.SizeForNode(stats.NodeID)
 			stats.LastScaleDown = now
 			stats.LastCleanup = now
 			stats.ScaleDownEvents++
@@ -524,17 +868,49 @@ func (c *Client) cleanupIdleConnections() {
 	}
 }
 
+// DrainNode stops routing new requests to a node (in both read and write pools) while
+// letting in-flight requests finish. Use this for rolling maintenance of the cluster,
+// then call UndrainNode to put the node back into rotation.
+func (c *Client) DrainNode(nodeID string) error {
+	drainedRead := c.readPool.Drain(nodeID)
+	drainedWrite := c.writePool.Drain(nodeID)
+	if !drainedRead && !drainedWrite {
+		return fmt.Errorf("no connections found for node %s", nodeID)
+	}
+	c.recordPoolEvent(POOL_EVENT_NODE_DRAINED, nodeID, "read=%t write=%t", drainedRead, drainedWrite)
+	return nil
+}
+
+// UndrainNode restores a drained node into the read and write pools rotation
+func (c *Client) UndrainNode(nodeID string) {
+	c.readPool.Undrain(nodeID)
+	c.writePool.Undrain(nodeID)
+	c.recordPoolEvent(POOL_EVENT_NODE_UNDRAIN, nodeID, "")
+}
+
+// IsNodeDraining returns true if the node is drained in either pool
+func (c *Client) IsNodeDraining(nodeID string) bool {
+	return c.readPool.IsDraining(nodeID) || c.writePool.IsDraining(nodeID)
+}
+
 // CloseConnections properly closes all connections
 func (c *Client) CloseConnections() {
-	// Stop the cleanup routine
+	// Stop the cleanup routine (it drains its own timer)
 	if c.cleanupTimer != nil {
-		if !c.cleanupTimer.Stop() {
-			select {
-			case <-c.cleanupTimer.C:
-			default:
-			}
-		}
 		close(c.cleanupDone)
+		c.cleanupTimer, c.cleanupDone = nil, nil
+	}
+
+	// Stop the topology refresh routine
+	if c.topologyTimer != nil {
+		close(c.topologyDone)
+		c.topologyTimer, c.topologyDone = nil, nil
+	}
+
+	// Stop the config watcher
+	if c.configWatchDone != nil {
+		close(c.configWatchDone)
+		c.configWatchDone = nil
 	}
 
 	// Clear all connection references
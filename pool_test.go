@@ -0,0 +1,25 @@
+package client
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRemoveIdleConnectionsKeepsPinned(t *testing.T) {
+	pool := NewConnectionPool(IS_WRITE, 10, 10)
+	old := time.Now().Add(-time.Hour)
+	pinned := &Connection{NodeID: "node1", LastUsed: old}
+	idle := &Connection{NodeID: "node1", LastUsed: old}
+	pool.AddBatch([]*Connection{pinned, idle})
+	atomic.AddInt32(&pinned.pinned, 1)
+
+	removed := pool.RemoveIdleConnections(time.Minute, 0)
+	if removed != 1 {
+		t.Fatalf("removed %d connections, want 1", removed)
+	}
+	conns := pool.GetAllConnectionsForNode("node1")
+	if len(conns) != 1 || conns[0] != pinned {
+		t.Fatalf("pinned connection was removed, left %v", conns)
+	}
+}
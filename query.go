@@ -0,0 +1,242 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	orm "github.com/medatechnology/simpleorm"
+)
+
+//------------------------------------------------------------------
+// QUERY BUILDER (JOINS)
+//------------------------------------------------------------------
+
+// Join types for QueryBuilder
+const (
+	INNER_JOIN = "INNER JOIN"
+	LEFT_JOIN  = "LEFT JOIN"
+)
+
+// QueryBuilder builds a SELECT over one or more joined tables as parameterized SQL, for the
+// relational queries orm.Condition (single table) can't express. Create it with Client.Query.
+type QueryBuilder struct {
+	client     *Client
+	table      string
+	joins      []queryJoin
+	columns    []string
+	condition  *orm.Condition
+	namespaced bool
+	err        error
+}
+
+type queryJoin struct {
+	kind  string
+	table string
+	on    string
+}
+
+// Query starts a SELECT on table (an alias is allowed, ie: "orders o"). Without Select all columns
+// are selected, the condition given to Where filters the rows and also sets the ORDER BY, GROUP BY,
+// LIMIT and OFFSET, same as SelectManyWithCondition.
+// Usage:
+//
+//	records, err := db.Query("orders").
+//		Join("users", "orders.user_id = users.id").
+//		Where(&orm.Condition{Field: "users.active", Operator: "=", Value: true}).
+//		Select("orders.*", "users.name").
+//		All()
+func (c *Client) Query(table string) *QueryBuilder {
+	qb := &QueryBuilder{client: c, table: strings.TrimSpace(table)}
+	if qb.table == "" {
+		qb.err = errors.New("query needs a table")
+	}
+	return qb
+}
+
+// Join adds an INNER JOIN of table on the given expression, ie: Join("users u", "o.user_id = u.id")
+func (qb *QueryBuilder) Join(table, on string) *QueryBuilder {
+	return qb.addJoin(INNER_JOIN, table, on)
+}
+
+// LeftJoin adds a LEFT JOIN of table on the given expression, rows without a match get NULL columns
+func (qb *QueryBuilder) LeftJoin(table, on string) *QueryBuilder {
+	return qb.addJoin(LEFT_JOIN, table, on)
+}
+
+func (qb *QueryBuilder) addJoin(kind, table, on string) *QueryBuilder {
+	table, on = strings.TrimSpace(table), strings.TrimSpace(on)
+	if table == "" || on == "" {
+		qb.err = errors.Join(qb.err, fmt.Errorf("%s needs a table and an ON expression", kind))
+		return qb
+	}
+	qb.joins = append(qb.joins, queryJoin{kind: kind, table: table, on: on})
+	return qb
+}
+
+// Where sets the condition of the query, fields can be qualified with the table (ie: "users.active")
+func (qb *QueryBuilder) Where(condition *orm.Condition) *QueryBuilder {
+	qb.condition = condition
+	return qb
+}
+
+// Select adds result columns or expressions, ie: "orders.*", "users.name", "COUNT(*) AS total".
+// Can be called multiple times.
+func (qb *QueryBuilder) Select(columns ...string) *QueryBuilder {
+	qb.columns = append(qb.columns, columns...)
+	return qb
+}
+
+// SelectAs adds a result column under another name, ie: SelectAs("users.id", "user_id")
+func (qb *QueryBuilder) SelectAs(column, alias string) *QueryBuilder {
+	return qb.Select(column + " AS " + quoteIdentifier(alias))
+}
+
+// Namespaced names every qualified result column after its table, ie: "users.id" comes back as
+// the "users.id" key of DBRecord.Data instead of "id" (which another table could overwrite).
+// "table.*" is expanded with DescribeTable to alias every column, columns that already have an
+// alias or are not qualified are left as is.
+func (qb *QueryBuilder) Namespaced() *QueryBuilder {
+	qb.namespaced = true
+	return qb
+}
+
+// Build returns the SELECT as parameterized SQL without running it
+func (qb *QueryBuilder) Build() (orm.ParametereizedSQL, error) {
+	if qb.err != nil {
+		return orm.ParametereizedSQL{}, qb.err
+	}
+	whereClause, values, err := ConditionToWhere(qb.condition)
+	if err != nil {
+		return orm.ParametereizedSQL{}, err
+	}
+	columns, err := qb.selectList()
+	if err != nil {
+		return orm.ParametereizedSQL{}, err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT " + strings.Join(columns, ", ") + " FROM " + qb.table)
+	for _, join := range qb.joins {
+		sb.WriteString(fmt.Sprintf(" %s %s ON %s", join.kind, join.table, join.on))
+	}
+	if strings.TrimSpace(whereClause) != "" {
+		sb.WriteString(" WHERE " + whereClause)
+	}
+	if condition := qb.condition; condition != nil {
+		if len(condition.GroupBy) > 0 {
+			sb.WriteString(" GROUP BY " + strings.Join(condition.GroupBy, ", "))
+		}
+		if len(condition.OrderBy) > 0 {
+			sb.WriteString(" ORDER BY " + strings.Join(condition.OrderBy, ", "))
+		}
+		limit := condition.Limit
+		// if offset has value but limit is not, then use default limit
+		if condition.Offset > 0 && limit < 1 {
+			limit = orm.DEFAULT_PAGINATION_LIMIT
+		}
+		if limit > 0 {
+			sb.WriteString(fmt.Sprintf(" LIMIT %d", limit))
+			if condition.Offset > 0 {
+				sb.WriteString(fmt.Sprintf(" OFFSET %d", condition.Offset))
+			}
+		}
+	}
+	return orm.ParametereizedSQL{Query: sb.String(), Values: values}, nil
+}
+
+// All runs the query and returns the records, orm.ErrSQLNoRows if nothing matches
+func (qb *QueryBuilder) All() ([]orm.DBRecord, error) {
+	paramSQL, err := qb.Build()
+	if err != nil {
+		return nil, err
+	}
+	return qb.client.SelectOneSQLParameterized(paramSQL)
+}
+
+// One runs the query and returns the first record, orm.ErrSQLNoRows if nothing matches
+func (qb *QueryBuilder) One() (orm.DBRecord, error) {
+	records, err := qb.All()
+	if err != nil {
+		return orm.DBRecord{}, err
+	}
+	return records[0], nil
+}
+
+// selectList returns the result columns, namespaced if asked
+func (qb *QueryBuilder) selectList() ([]string, error) {
+	if len(qb.columns) == 0 {
+		if !qb.namespaced {
+			return []string{"*"}, nil
+		}
+		// namespacing needs the tables spelled out
+		columns := []string{tableRef(qb.table) + ".*"}
+		for _, join := range qb.joins {
+			columns = append(columns, tableRef(join.table)+".*")
+		}
+		return qb.namespace(columns)
+	}
+	if !qb.namespaced {
+		return qb.columns, nil
+	}
+	return qb.namespace(qb.columns)
+}
+
+// namespace aliases every "table.column" as "table.column" and expands "table.*"
+func (qb *QueryBuilder) namespace(columns []string) ([]string, error) {
+	result := make([]string, 0, len(columns))
+	for _, column := range columns {
+		column = strings.TrimSpace(column)
+		tokens := tokenizeSQL(column)
+		// only a bare qualified column can be namespaced, anything else (alias, expression) is kept
+		if len(tokens) != 1 || !strings.Contains(column, ".") {
+			result = append(result, column)
+			continue
+		}
+		dot := strings.LastIndexByte(column, '.')
+		table, name := column[:dot], unquoteIdentifier(column[dot+1:])
+		if name != "*" {
+			result = append(result, column+" AS "+quoteIdentifier(unquoteIdentifier(table)+"."+name))
+			continue
+		}
+		described, err := qb.client.DescribeTable(qb.tableName(table))
+		if err != nil {
+			return nil, fmt.Errorf("cannot namespace %s: %w", column, err)
+		}
+		for _, col := range described {
+			result = append(result, table+"."+quoteIdentifier(col.Name)+" AS "+quoteIdentifier(unquoteIdentifier(table)+"."+col.Name))
+		}
+	}
+	return result, nil
+}
+
+// tableName returns the real table of a reference used in the query, resolving the aliases
+// of the FROM and JOIN tables (ie: "u" of "users u")
+func (qb *QueryBuilder) tableName(ref string) string {
+	ref = unquoteIdentifier(ref)
+	tables := []string{qb.table}
+	for _, join := range qb.joins {
+		tables = append(tables, join.table)
+	}
+	for _, table := range tables {
+		if strings.EqualFold(unquoteIdentifier(tableRef(table)), ref) {
+			return unquoteIdentifier(tokenizeSQL(table)[0])
+		}
+	}
+	return ref
+}
+
+// tableRef returns how a FROM or JOIN table is referenced in the query: its alias if any
+// ("users u", "users AS u" -> "u"), otherwise its name
+func tableRef(table string) string {
+	tokens := tokenizeSQL(table)
+	if len(tokens) == 0 {
+		return table
+	}
+	return tokens[len(tokens)-1]
+}
+
+// quoteIdentifier quotes an identifier with "", doubling any " inside
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
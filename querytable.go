@@ -0,0 +1,195 @@
+package client
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	orm "github.com/medatechnology/simpleorm"
+)
+
+//------------------------------------------------------------------
+// COLUMN ORDERED RESULTS
+//------------------------------------------------------------------
+
+// QueryTable runs a SELECT and returns the rows as values in column order, ie: for a query
+// console or a CSV export where the header has to follow the SELECT.
+//
+// The server sends each row as a JSON object (DBRecord.Data), which doesn't keep the column order,
+// so the order is derived from the statement itself:
+//   - explicit columns follow the SELECT list, using the alias if any ("price * 2 AS total" -> "total")
+//   - "*" and "table.*" of a single table FROM are expanded with DescribeTable (declared order)
+//   - any returned column the statement doesn't explain (ie: "*" on a join) is appended in alphabetical order
+//
+// Usage:
+//
+//	columns, rows, err := db.QueryTable("SELECT id, username, email FROM users ORDER BY id")
+func (c *Client) QueryTable(sql string) ([]string, [][]interface{}, error) {
+	records, err := c.SelectOneSQL(sql)
+	if err != nil && !errors.Is(err, orm.ErrSQLNoRows) {
+		return nil, nil, err
+	}
+
+	columns := c.selectColumns(sql)
+	if len(records) > 0 {
+		columns = orderColumns(columns, records[0].Data)
+	}
+
+	rows := make([][]interface{}, 0, len(records))
+	for _, rec := range records {
+		row := make([]interface{}, len(columns))
+		for i, col := range columns {
+			row[i] = rec.Data[col]
+		}
+		rows = append(rows, row)
+	}
+	return columns, rows, nil
+}
+
+// selectColumns returns the result column names of a SELECT statement in order, expanding "*"
+// with the table schema. Returns nil if the statement can't be parsed.
+func (c *Client) selectColumns(sql string) []string {
+	items, from := splitSelect(sql)
+	var columns []string
+	for _, item := range items {
+		tokens := tokenizeSQL(item)
+		if len(tokens) == 0 {
+			continue
+		}
+		last := tokens[len(tokens)-1]
+		switch {
+		case last == "*" || strings.HasSuffix(last, ".*"):
+			// only a single table can be expanded, joins are left to the returned columns
+			fromTokens := tokenizeSQL(from)
+			if len(fromTokens) == 0 || strings.Contains(strings.ToUpper(from), "JOIN") || strings.Contains(from, ",") {
+				continue
+			}
+			described, err := c.DescribeTable(unquoteIdentifier(fromTokens[0]))
+			if err != nil {
+				continue
+			}
+			for _, col := range described {
+				columns = append(columns, col.Name)
+			}
+		case len(tokens) >= 3 && strings.EqualFold(tokens[len(tokens)-2], "AS"):
+			columns = append(columns, unquoteIdentifier(last))
+		case len(tokens) == 1:
+			name := unquoteIdentifier(last)
+			if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+				name = unquoteIdentifier(name[dot+1:])
+			}
+			columns = append(columns, name)
+		case isImplicitAlias(tokens):
+			// "expr alias"
+			columns = append(columns, unquoteIdentifier(last))
+		default:
+			// an expression without alias is named by its text
+			columns = append(columns, strings.TrimSpace(item))
+		}
+	}
+	return columns
+}
+
+// isImplicitAlias checks if the item ends with an alias without AS, ie: "lower(name) lname"
+func isImplicitAlias(tokens []string) bool {
+	if len(tokens) < 2 {
+		return false
+	}
+	last, prev := tokens[len(tokens)-1], tokens[len(tokens)-2]
+	if strings.EqualFold(last, "END") || (!isNameStart(last[0]) && last[0] != '"' && last[0] != '`' && last[0] != '[') {
+		return false
+	}
+	for i := 0; i < len(last); i++ {
+		if last[0] != '"' && last[0] != '`' && last[0] != '[' && !isNamePart(last[i]) {
+			return false
+		}
+	}
+	// the alias follows an operand, not an operator
+	end := prev[len(prev)-1]
+	return isNamePart(end) || end == ')' || end == '\'' || end == '"' || end == '`' || end == ']'
+}
+
+// orderColumns keeps the derived columns that exist in the row (matching case-insensitively),
+// then appends the remaining row columns sorted
+func orderColumns(derived []string, data map[string]interface{}) []string {
+	used := make(map[string]bool, len(data))
+	columns := make([]string, 0, len(data))
+	for _, name := range derived {
+		key, exists := name, false
+		if _, exists = data[name]; !exists {
+			for k := range data {
+				if strings.EqualFold(k, name) {
+					key, exists = k, true
+					break
+				}
+			}
+		}
+		if exists && !used[key] {
+			used[key] = true
+			columns = append(columns, key)
+		}
+	}
+	var rest []string
+	for k := range data {
+		if !used[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	return append(columns, rest...)
+}
+
+// splitSelect returns the items of the SELECT list and the text after the top-level FROM
+// (up to WHERE, GROUP, ORDER, LIMIT ...)
+func splitSelect(sql string) ([]string, string) {
+	trimmed := strings.TrimSpace(sql)
+	upper := strings.ToUpper(trimmed)
+	if !strings.HasPrefix(upper, "SELECT") {
+		return nil, ""
+	}
+	body := trimmed[len("SELECT"):]
+	if fields := strings.Fields(body); len(fields) > 0 && (strings.EqualFold(fields[0], "DISTINCT") || strings.EqualFold(fields[0], "ALL")) {
+		body = strings.TrimSpace(body)[len(fields[0]):]
+	}
+
+	list, rest := body, ""
+	if pos := topLevelKeyword(body, "FROM"); pos >= 0 {
+		list, rest = body[:pos], body[pos+len("FROM"):]
+	}
+	for _, keyword := range []string{"WHERE", "GROUP", "ORDER", "LIMIT", "HAVING", "WINDOW", "UNION"} {
+		if pos := topLevelKeyword(rest, keyword); pos >= 0 {
+			rest = rest[:pos]
+		}
+	}
+	return splitTopLevel(list, ','), strings.TrimSpace(rest)
+}
+
+// topLevelKeyword returns the position of the keyword outside parentheses and quotes, or -1
+func topLevelKeyword(s, keyword string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '[':
+			quote = ']'
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case depth == 0 && i+len(keyword) <= len(s) && strings.EqualFold(s[i:i+len(keyword)], keyword):
+			before := i == 0 || !isNamePart(s[i-1])
+			after := i+len(keyword) == len(s) || !isNamePart(s[i+len(keyword)])
+			if before && after {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//------------------------------------------------------------------
+// CLIENT-SIDE RATE LIMITER
+//------------------------------------------------------------------
+
+// RateLimit defines requests per second and burst size for the client-side rate limiter.
+// RPS <= 0 means no limit.
+type RateLimit struct {
+	RPS   int
+	Burst int
+}
+
+// rateLimiter is a simple token bucket, same semantic as golang.org/x/time/rate:
+// the bucket starts full (Burst tokens) and is refilled at RPS tokens per second.
+// Only Wait is needed, so it lives here instead of adding x/time/rate as a dependency.
+type rateLimiter struct {
+	mutex     sync.Mutex
+	rate      float64   // tokens per second
+	burst     float64   // maximum tokens in the bucket
+	tokens    float64   // current tokens, can go negative for reserved (waiting) requests
+	last      time.Time // last time tokens was updated
+	throttled int64     // number of requests that had to wait, atomic
+}
+
+// newRateLimiter returns nil if the limit is not set, so there is zero overhead when disabled
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	if limit.RPS <= 0 {
+		return nil
+	}
+	burst := ValueOrDefault(limit.Burst, 1, IntBiggerThanZero)
+	return &rateLimiter{
+		rate:   float64(limit.RPS),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	l.mutex.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	// reserve the token, if there is none left we wait until it is refilled
+	l.tokens--
+	wait := time.Duration(0)
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mutex.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	atomic.AddInt64(&l.throttled, 1)
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// give back the reserved token
+		l.mutex.Lock()
+		l.tokens++
+		l.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Throttled returns how many requests had to wait for the limiter
+func (l *rateLimiter) Throttled() int64 {
+	if l == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&l.throttled)
+}
+
+// waitRateLimit waits on the read or write limiter (if configured)
+func (c *Client) waitRateLimit(ctx context.Context, isWrite bool) error {
+	limiter := c.readLimiter
+	if isWrite {
+		limiter = c.writeLimiter
+	}
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
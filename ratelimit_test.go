@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBurst(t *testing.T) {
+	l := newRateLimiter(RateLimit{RPS: 1, Burst: 3})
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("burst of 3 took %s, want no wait", elapsed)
+	}
+	if got := l.Throttled(); got != 0 {
+		t.Errorf("throttled = %d, want 0 within the burst", got)
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	l := newRateLimiter(RateLimit{RPS: 20, Burst: 1})
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// the bucket is empty, the next token comes after 1/20s
+	start := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond || elapsed > time.Second {
+		t.Errorf("waited %s for a refilled token, want about 50ms", elapsed)
+	}
+	if got := l.Throttled(); got != 1 {
+		t.Errorf("throttled = %d, want 1", got)
+	}
+
+	// an idle limiter refills up to the burst, not more: after 3 tokens worth of idling only
+	// the first request passes without waiting
+	time.Sleep(150 * time.Millisecond)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Throttled(); got != 2 {
+		t.Errorf("throttled = %d, want 2 (the refill is capped at the burst)", got)
+	}
+}
+
+func TestRateLimiterCancelReturnsToken(t *testing.T) {
+	l := newRateLimiter(RateLimit{RPS: 1, Burst: 1})
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if got := l.Throttled(); got != 1 {
+		t.Errorf("throttled = %d, want 1", got)
+	}
+	// the cancelled request gave its reserved token back, the bucket is not in debt
+	l.mutex.Lock()
+	tokens := l.tokens
+	l.mutex.Unlock()
+	if tokens < -0.1 {
+		t.Errorf("tokens = %v, want the reserved token returned", tokens)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	if l := newRateLimiter(RateLimit{}); l != nil {
+		t.Errorf("limiter = %+v, want nil without RPS", l)
+	}
+	var c Client
+	if err := c.waitRateLimit(context.Background(), IS_WRITE); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got := c.readLimiter.Throttled(); got != 0 {
+		t.Errorf("throttled = %d, want 0", got)
+	}
+}
@@ -0,0 +1,225 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	utils "github.com/medatechnology/goutil"
+)
+
+//------------------------------------------------------------------
+// CONFIG RELOAD
+//------------------------------------------------------------------
+
+// ErrReconnectRequired is returned by ReloadConfig when changed settings only apply to a new client
+var ErrReconnectRequired = errors.New("reconnect required to apply")
+
+// hotConfig holds the settings ReloadConfig can change on a running client. It is never modified:
+// ReloadConfig stores a changed copy, so requests and background routines read it without a lock.
+// PoolConfig and Config keep the values the client was created with.
+type hotConfig struct {
+	scaleUpThreshold        int
+	scaleUpBatchSize        int
+	idleTimeout             time.Duration
+	scaleDownInterval       time.Duration
+	connectionTTL           time.Duration
+	topologyRefreshInterval time.Duration
+	acquireTimeout          time.Duration
+	scaleUpInterval         time.Duration
+	scaleUpJitter           time.Duration
+	slowQueryThreshold      time.Duration
+	streamFlushInterval     time.Duration
+	defaultQueryLimit       int
+}
+
+func newHotConfig(pool *PoolConfig, config *ClientConfig) *hotConfig {
+	return &hotConfig{
+		scaleUpThreshold:        pool.ScaleUpThreshold,
+		scaleUpBatchSize:        pool.ScaleUpBatchSize,
+		idleTimeout:             pool.IdleTimeout,
+		scaleDownInterval:       pool.ScaleDownInterval,
+		connectionTTL:           pool.ConnectionTTL,
+		topologyRefreshInterval: pool.TopologyRefreshInterval,
+		acquireTimeout:          pool.AcquireTimeout,
+		scaleUpInterval:         pool.ScaleUpInterval,
+		scaleUpJitter:           pool.ScaleUpJitter,
+		slowQueryThreshold:      config.SlowQueryThreshold,
+		streamFlushInterval:     config.StreamFlushInterval,
+		defaultQueryLimit:       config.DefaultQueryLimit,
+	}
+}
+
+// hot returns the current hot-reloadable settings
+func (c *Client) hot() *hotConfig {
+	return c.settings.Load()
+}
+
+// hotReloadSetting is an environment variable that can be applied to a running client
+type hotReloadSetting struct {
+	env   string
+	apply func(c *Client, hot *hotConfig, value int) error
+}
+
+// hotReloadSettings are the settings ReloadConfig applies, with the same units as NewPoolConfig
+// and NewClientConfig. Background routines (idle cleanup, topology refresh) pick them up on their next run.
+var hotReloadSettings = []hotReloadSetting{
+	{"SURESQL_SCALE_UP_THRESHOLD", func(c *Client, h *hotConfig, v int) error { return setPositive(&h.scaleUpThreshold, v) }},
+	{"SURESQL_SCALE_UP_BATCH", func(c *Client, h *hotConfig, v int) error { return setPositive(&h.scaleUpBatchSize, v) }},
+	{"SURESQL_POOL_IDLE_TIMEOUT", func(c *Client, h *hotConfig, v int) error { return setDuration(&h.idleTimeout, v, time.Minute) }},
+	{"SURESQL_SCALE_DOWN_INTERVAL", func(c *Client, h *hotConfig, v int) error { return setDuration(&h.scaleDownInterval, v, time.Minute) }},
+	{"SURESQL_CONNECTION_TTL", func(c *Client, h *hotConfig, v int) error { return setDuration(&h.connectionTTL, v, time.Minute) }},
+	{"SURESQL_TOPOLOGY_REFRESH_INTERVAL", func(c *Client, h *hotConfig, v int) error {
+		if c.topologyTimer == nil {
+			return errors.New("topology refresh is not running, enabling it needs a reconnect")
+		}
+		return setDuration(&h.topologyRefreshInterval, v, time.Second)
+	}},
+	{"SURESQL_ACQUIRE_TIMEOUT", func(c *Client, h *hotConfig, v int) error {
+		h.acquireTimeout = time.Duration(v) * time.Millisecond
+		return nil
+	}},
+	{"SURESQL_SCALE_UP_INTERVAL", func(c *Client, h *hotConfig, v int) error { return setDuration(&h.scaleUpInterval, v, time.Second) }},
+	{"SURESQL_SCALE_UP_JITTER", func(c *Client, h *hotConfig, v int) error {
+		h.scaleUpJitter = time.Duration(v) * time.Millisecond
+		return nil
+	}},
+	{"SURESQL_SLOW_QUERY_THRESHOLD", func(c *Client, h *hotConfig, v int) error {
+		h.slowQueryThreshold = time.Duration(v) * time.Millisecond
+		return nil
+	}},
+	{"SURESQL_STREAM_FLUSH_INTERVAL", func(c *Client, h *hotConfig, v int) error {
+		return setDuration(&h.streamFlushInterval, v, time.Millisecond)
+	}},
+	{"SURESQL_DEFAULT_QUERY_LIMIT", func(c *Client, h *hotConfig, v int) error {
+		h.defaultQueryLimit = v
+		return nil
+	}},
+}
+
+func setPositive(field *int, value int) error {
+	if value < 1 {
+		return fmt.Errorf("must be at least 1, got %d", value)
+	}
+	*field = value
+	return nil
+}
+
+func setDuration(field *time.Duration, value int, unit time.Duration) error {
+	if value < 1 {
+		return fmt.Errorf("must be at least 1, got %d", value)
+	}
+	*field = time.Duration(value) * unit
+	return nil
+}
+
+// ReloadConfig re-reads .env.client (and .env) and applies the settings that can change on a
+// running client. Only variables that are set are applied, values given with options are kept otherwise.
+//
+// Hot-reloadable: SURESQL_SCALE_UP_THRESHOLD, SURESQL_SCALE_UP_BATCH, SURESQL_POOL_IDLE_TIMEOUT,
+// SURESQL_SCALE_DOWN_INTERVAL, SURESQL_CONNECTION_TTL, SURESQL_TOPOLOGY_REFRESH_INTERVAL (only if it
+// was already enabled), SURESQL_ACQUIRE_TIMEOUT, SURESQL_SCALE_UP_INTERVAL, SURESQL_SCALE_UP_JITTER,
+// SURESQL_SLOW_QUERY_THRESHOLD, SURESQL_STREAM_FLUSH_INTERVAL and SURESQL_DEFAULT_QUERY_LIMIT.
+//
+// The applied values are not written to PoolConfig and Config, which keep the values the client
+// was created with (so they can be read without a lock). ConnectionStats reports the running values.
+//
+// Need a new client (Close, NewClient, Connect): the server URL, API key, client ID, credentials,
+// pool sizes and all SURESQL_HTTP_* settings. When one of those changed the error wraps
+// ErrReconnectRequired and names them, the hot-reloadable settings are applied anyway.
+func (c *Client) ReloadConfig() error {
+	if _, err := os.Stat(DEFAULT_ENVIRONMENT_FILE); err == nil {
+		utils.ReloadEnvEach(DEFAULT_ENVIRONMENT_FILE)
+	}
+
+	var errs []error
+	c.scalingMutex.Lock()
+	hot := *c.hot()
+	for _, setting := range hotReloadSettings {
+		raw, exists := os.LookupEnv(setting.env)
+		if !exists {
+			continue
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err == nil {
+			err = setting.apply(c, &hot, value)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s=%q not applied: %w", setting.env, raw, err))
+		}
+	}
+	c.settings.Store(&hot)
+	c.scalingMutex.Unlock()
+
+	if changed := c.changedColdSettings(); len(changed) > 0 {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrReconnectRequired, strings.Join(changed, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// changedColdSettings returns the set environment variables that differ from the running config
+// but cannot be applied without a new client
+func (c *Client) changedColdSettings() []string {
+	var changed []string
+	for env, current := range map[string]string{
+		"SURESQL_SERVER_URL": c.Config.ServerURL,
+		"SURESQL_API_KEY":    c.Config.APIKey,
+		"SURESQL_CLIENT_ID":  c.Config.ClientID,
+		"SURESQL_USERNAME":   c.Config.Username,
+		"SURESQL_PASSWORD":   c.Config.Password,
+	} {
+		if value, exists := os.LookupEnv(env); exists && value != current {
+			changed = append(changed, env)
+		}
+	}
+	for env, current := range map[string]int{
+		"SURESQL_POOL_MAXIMUM":       c.PoolConfig.MaxPoolSize,
+		"SURESQL_WRITE_POOL_MAXIMUM": c.PoolConfig.MaxWritePoolSize,
+		"SURESQL_HTTP_TIMEOUT":       int(c.Config.HTTPTimeout / time.Second),
+	} {
+		if value, exists := os.LookupEnv(env); exists && value != strconv.Itoa(current) {
+			changed = append(changed, env)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// startConfigWatch polls the modification time of .env.client and calls ReloadConfig when it changes
+func (c *Client) startConfigWatch() {
+	done := make(chan struct{})
+	c.configWatchDone = done
+	lastModified := envFileModTime()
+
+	go func() {
+		ticker := time.NewTicker(c.Config.ConfigWatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				modified := envFileModTime()
+				if modified.Equal(lastModified) {
+					continue
+				}
+				lastModified = modified
+				if err := c.ReloadConfig(); err != nil {
+					fmt.Printf("Warning: config reload: %v\n", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+func envFileModTime() time.Time {
+	info, err := os.Stat(DEFAULT_ENVIRONMENT_FILE)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
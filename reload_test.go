@@ -0,0 +1,54 @@
+package client
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReloadConfigDuringRequests(t *testing.T) {
+	for _, env := range []string{"SURESQL_SCALE_UP_THRESHOLD", "SURESQL_ACQUIRE_TIMEOUT", "SURESQL_SLOW_QUERY_THRESHOLD", "SURESQL_DEFAULT_QUERY_LIMIT", "SURESQL_POOL_IDLE_TIMEOUT"} {
+		t.Setenv(env, "1")
+	}
+	c := newStubClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return okResponse(req, `[{"records":[{"TableName":"users","Data":{"id":1}}]}]`), nil
+	}))
+	c.Config.OnSlowQuery = func(QueryInfo) {}
+
+	// run with -race: the requests read the settings ReloadConfig replaces
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				c.SelectManyWithCondition("users", nil)
+				c.cleanupIdleConnections()
+			}
+		}()
+	}
+	for i := 1; i <= 50; i++ {
+		os.Setenv("SURESQL_DEFAULT_QUERY_LIMIT", strconv.Itoa(i))
+		os.Setenv("SURESQL_ACQUIRE_TIMEOUT", strconv.Itoa(i))
+		if err := c.ReloadConfig(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(done)
+	wg.Wait()
+
+	hot := c.hot()
+	if hot.defaultQueryLimit != 50 || hot.acquireTimeout != 50*time.Millisecond || hot.idleTimeout != time.Minute {
+		t.Errorf("settings = %+v, want the last reload applied", *hot)
+	}
+}
@@ -0,0 +1,182 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/medatechnology/goutil/object"
+	orm "github.com/medatechnology/simpleorm"
+)
+
+//------------------------------------------------------------------
+// REPOSITORY
+//------------------------------------------------------------------
+
+// Repository binds a struct type to its table, so the table name doesn't have to be repeated.
+// The table name comes from T's TableName() method and columns from its json/db tags.
+// Usage:
+//
+//	users := client.NewRepository[UserTable](db)
+//	res := users.Insert(UserTable{Username: "jane"})
+//	user, err := users.FindByID(5)
+//	active, err := users.Find(&orm.Condition{Field: "active", Operator: "=", Value: true})
+type Repository[T orm.TableStruct] struct {
+	client         *Client
+	tableName      string
+	softDelete     string // soft-delete timestamp column, empty for physical deletes
+	includeDeleted bool
+}
+
+// RepositoryOption configures a Repository
+type RepositoryOption func(options *repositoryOptions)
+
+type repositoryOptions struct {
+	softDelete string
+}
+
+// WithSoftDelete makes Delete set column (ie: "deleted_at") to the current time instead of deleting
+// the rows, and the read methods skip rows where column is not NULL. Use IncludeDeleted to read them.
+func WithSoftDelete(column string) RepositoryOption {
+	return func(options *repositoryOptions) {
+		options.softDelete = column
+	}
+}
+
+// NewRepository creates a Repository for T
+func NewRepository[T orm.TableStruct](c *Client, options ...RepositoryOption) *Repository[T] {
+	var opts repositoryOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	var zero T
+	return &Repository[T]{client: c, tableName: zero.TableName(), softDelete: opts.softDelete}
+}
+
+// IncludeDeleted returns a copy of the repository whose read methods also return soft-deleted rows
+func (r *Repository[T]) IncludeDeleted() *Repository[T] {
+	clone := *r
+	clone.includeDeleted = true
+	return &clone
+}
+
+// TableName returns the table of the repository
+func (r *Repository[T]) TableName() string {
+	return r.tableName
+}
+
+// Insert inserts one record
+func (r *Repository[T]) Insert(record T) orm.BasicSQLResult {
+	return r.client.InsertOneTableStruct(record, false)
+}
+
+// InsertMany inserts multiple records in one request
+func (r *Repository[T]) InsertMany(records []T) ([]orm.BasicSQLResult, error) {
+	tableStructs := make([]orm.TableStruct, 0, len(records))
+	for _, record := range records {
+		tableStructs = append(tableStructs, record)
+	}
+	return r.client.InsertManyTableStructs(tableStructs, false)
+}
+
+// FindByID returns the record with the primary key id, orm.ErrSQLNoRows if not found
+func (r *Repository[T]) FindByID(id interface{}) (T, error) {
+	return r.FindOne(&orm.Condition{Field: r.client.Config.PrimaryKeyColumn, Operator: "=", Value: id})
+}
+
+// FindOne returns the first record matching the condition, orm.ErrSQLNoRows if none
+func (r *Repository[T]) FindOne(condition *orm.Condition) (T, error) {
+	record, err := r.client.SelectOneWithCondition(r.tableName, r.notDeleted(condition))
+	if err != nil {
+		var empty T
+		return empty, err
+	}
+	return object.MapToStructSlowDB[T](record.Data), nil
+}
+
+// Find returns the records matching the condition (nil for all), an empty slice if none
+func (r *Repository[T]) Find(condition *orm.Condition) ([]T, error) {
+	return SelectManyWithConditionInto[T](r.client, r.tableName, r.notDeleted(condition))
+}
+
+// Update updates the row with the same primary key as record (see WithPrimaryKeyColumn) with all its fields
+func (r *Repository[T]) Update(record T) orm.BasicSQLResult {
+	condition, err := r.primaryKeyCondition(record)
+	if err != nil {
+		return orm.BasicSQLResult{Error: err}
+	}
+	return r.client.UpdateTableStruct(record, condition, false)
+}
+
+// UpdateWhere updates the rows matching condition, see UpdateTableStruct
+func (r *Repository[T]) UpdateWhere(record T, condition *orm.Condition, onlyNonZero bool) orm.BasicSQLResult {
+	return r.client.UpdateTableStruct(record, condition, onlyNonZero)
+}
+
+// Delete deletes the rows matching condition, a condition is required. With WithSoftDelete the
+// rows that are not deleted yet get the soft-delete column set to the current time instead.
+func (r *Repository[T]) Delete(condition *orm.Condition) orm.BasicSQLResult {
+	if r.softDelete == "" {
+		return r.client.DeleteWithCondition(r.tableName, condition)
+	}
+	whereClause, _, err := ConditionToWhere(condition)
+	if err != nil {
+		return orm.BasicSQLResult{Error: err}
+	}
+	if strings.TrimSpace(whereClause) == "" {
+		return orm.BasicSQLResult{Error: errors.New("delete requires a condition")}
+	}
+	data := map[string]interface{}{r.softDelete: time.Now().UTC().Format(time.RFC3339)}
+	paramSQL, err := updateParameterized(r.tableName, data, notDeletedCondition(condition, r.softDelete))
+	if err != nil {
+		return orm.BasicSQLResult{Error: err}
+	}
+	return r.client.ExecOneSQLParameterized(paramSQL)
+}
+
+// HardDelete physically deletes the rows matching condition, even with WithSoftDelete
+func (r *Repository[T]) HardDelete(condition *orm.Condition) orm.BasicSQLResult {
+	return r.client.DeleteWithCondition(r.tableName, condition)
+}
+
+// notDeleted adds the soft-delete filter to the condition of the read methods
+func (r *Repository[T]) notDeleted(condition *orm.Condition) *orm.Condition {
+	if r.softDelete == "" || r.includeDeleted {
+		return condition
+	}
+	return notDeletedCondition(condition, r.softDelete)
+}
+
+// notDeletedCondition returns condition AND column IS NULL, the ordering and pagination of
+// condition are kept on the returned (outer) condition
+func notDeletedCondition(condition *orm.Condition, column string) *orm.Condition {
+	isNull := orm.Condition{Field: column, Operator: "IS NULL"}
+	if condition == nil {
+		return &isNull
+	}
+	inner := *condition
+	inner.OrderBy, inner.GroupBy, inner.Limit, inner.Offset = nil, nil, 0, 0
+	if inner.Field == "" && len(inner.Nested) == 0 {
+		isNull.OrderBy, isNull.GroupBy, isNull.Limit, isNull.Offset = condition.OrderBy, condition.GroupBy, condition.Limit, condition.Offset
+		return &isNull
+	}
+	return &orm.Condition{
+		Logic:   "AND",
+		Nested:  []orm.Condition{inner, isNull},
+		OrderBy: condition.OrderBy,
+		GroupBy: condition.GroupBy,
+		Limit:   condition.Limit,
+		Offset:  condition.Offset,
+	}
+}
+
+// primaryKeyCondition returns primary key = value of the record
+func (r *Repository[T]) primaryKeyCondition(record T) (*orm.Condition, error) {
+	data := object.StructToMapWithOptions(record, object.MapOptions{SkipNilPointers: true, TimeFormat: time.RFC3339})
+	id, exists := data[r.client.Config.PrimaryKeyColumn]
+	if !exists {
+		return nil, fmt.Errorf("%s has no primary key column %s", r.tableName, r.client.Config.PrimaryKeyColumn)
+	}
+	return &orm.Condition{Field: r.client.Config.PrimaryKeyColumn, Operator: "=", Value: id}, nil
+}
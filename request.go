@@ -1,10 +1,13 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/medatechnology/suresql"
 )
@@ -27,7 +30,21 @@ func (c *Client) userCredentialsDefault(username, password string) map[string]st
 
 // send Request using leader connection, if not exist create it
 // return is standardResponse.Data which is of type interface{}
-func (c *Client) sendRequestToLeader(method, endpoint string, body interface{}, withToken, autorefresh bool) (interface{}, error) {
+func (c *Client) sendRequestToLeader(ctx context.Context, method, endpoint string, body interface{}, withToken, autorefresh bool) (interface{}, error) {
+	raw, err := c.sendRequestToLeaderRaw(ctx, method, endpoint, body, withToken, autorefresh)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRawData(raw, &c.Config)
+}
+
+// Same as sendRequestToLeader but return standardResponse.Data as raw JSON
+func (c *Client) sendRequestToLeaderRaw(ctx context.Context, method, endpoint string, body interface{}, withToken, autorefresh bool) (json.RawMessage, error) {
+	return c.sendRequestToPoolRaw(ctx, c.getLeaderConnection(), method, endpoint, body, withToken, autorefresh, NO_FALLBACK)
+}
+
+// get the leader connection, if not exist create it
+func (c *Client) getLeaderConnection() *Connection {
 	// if this is called for the first time, maybe from connect, but it shouldn't be because the newClient will create this
 	if c.leaderConn == nil {
 		// c.leaderConn = &Connection{
@@ -40,12 +57,33 @@ func (c *Client) sendRequestToLeader(method, endpoint string, body interface{},
 		// }
 		c.leaderConn = NewConnection(&c.Config, "", "", "", true, suresql.TokenTable{})
 	}
-	return c.sendRequestToPool(c.leaderConn, method, endpoint, body, withToken, autorefresh, NO_FALLBACK)
+	return c.leaderConn
 }
 
 // This will send http call with option of autorefresh
 // return is standardResponse.Data which is of type interface{}
-func (c *Client) sendRequestToPool(conn *Connection, method, endpoint string, body interface{}, withToken, autorefresh, fallback bool) (interface{}, error) {
+func (c *Client) sendRequestToPool(ctx context.Context, conn *Connection, method, endpoint string, body interface{}, withToken, autorefresh, fallback bool) (interface{}, error) {
+	raw, err := c.sendRequestToPoolRaw(ctx, conn, method, endpoint, body, withToken, autorefresh, fallback)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRawData(raw, &c.Config)
+}
+
+// Same as sendRequestToPool but return standardResponse.Data as raw JSON, so the caller
+// can decode it directly into the final type. The request goes through the middleware chain (if any).
+func (c *Client) sendRequestToPoolRaw(ctx context.Context, conn *Connection, method, endpoint string, body interface{}, withToken, autorefresh, fallback bool) (json.RawMessage, error) {
+	handler := func(ctx context.Context, method, endpoint string, body interface{}) (json.RawMessage, error) {
+		return c.doRequestToPool(ctx, conn, method, endpoint, body, withToken, autorefresh, fallback)
+	}
+	return chainMiddlewares(c.Config.Middlewares, handler)(ctx, method, endpoint, body)
+}
+
+// doRequestToPool does the actual HTTP call (with token refresh and fallback to leader)
+func (c *Client) doRequestToPool(ctx context.Context, conn *Connection, method, endpoint string, body interface{}, withToken, autorefresh, fallback bool) (json.RawMessage, error) {
+	if c.IsClosed() {
+		return nil, ErrClientClosed
+	}
 	// double check connection is there
 	if conn == nil {
 		return nil, errors.New("no DB connection")
@@ -59,40 +97,65 @@ func (c *Client) sendRequestToPool(conn *Connection, method, endpoint string, bo
 		return nil, err
 	}
 
-	resp, err := conn.sendHttpRequest(method, endpoint, body, &c.Config, withToken)
-	if err != nil {
-		// AutoRefresh logic, if it's on, make sure the error is UnAuthorized (which is token expires)
-		// NOTE: neede to check resp!= nil first, sometimes it is nil and create panic
-		// resp.Body.Close()
-		if autorefresh && resp != nil && resp.StatusCode == http.StatusUnauthorized && withToken {
-			err = conn.tryRefreshAndRenew(&c.Config)
-			if err == nil {
-				// 2nd try if auto-refresh
-				resp, err = conn.sendHttpRequest(method, endpoint, body, &c.Config, withToken)
-				if err != nil {
-					resp.Body.Close()
-					return nil, fmt.Errorf("api-call failed, after refresh success, err: %w", err)
-				}
-			}
-		}
-		// other error or auto-refresh=false + other error, check if there is fallback to leader (and current connection is not already leader!)
-		// NOTE: this err!= nil is important because it could be carry over error from refresh and 2nd try sendRequest
+	var info RequestInfo
+	if c.auditEnabled() {
+		ctx, info = c.beforeRequest(ctx, conn, method, endpoint, body)
+	}
+
+	start := time.Now()
+	staleToken := conn.token().Token
+	resp, err := conn.sendHttpRequest(ctx, method, endpoint, body, &c.Config, withToken)
+
+	// AutoRefresh logic, if it's on, make sure the response is UnAuthorized (which is token expires).
+	// NOTE: http.Client.Do does not return error for 401, so check the status code of the response
+	if err == nil && autorefresh && resp.StatusCode == http.StatusUnauthorized {
+		closeResponseBody(resp)
+		resp = nil
+		err = c.renewToken(ctx, conn, staleToken)
 		if err != nil {
-			if fallback && conn != c.leaderConn {
-				// could also return c.sendRequestToLeader but the error won't say this is the leader fallback
-				data, err := c.sendRequestToLeader(method, endpoint, body, withToken, autorefresh)
-				if err != nil {
-					// resp.Body.Close()
-					return nil, fmt.Errorf("api-call fallback to leader failed, err:%w", err)
-				}
-				return data, err
-			} else {
-				return nil, fmt.Errorf("api-call failed, err: %w", err)
+			err = fmt.Errorf("token refresh failed: %w", err)
+		} else {
+			// 2nd try if auto-refresh
+			resp, err = conn.sendHttpRequest(ctx, method, endpoint, body, &c.Config, withToken)
+			if err == nil && resp.StatusCode == http.StatusUnauthorized {
+				closeResponseBody(resp)
+				resp = nil
+				err = errors.New("still unauthorized after token refresh")
 			}
 		}
 	}
+
+	elapsed := time.Since(start)
+	c.latency.record(endpoint, conn.NodeID, elapsed)
+	if err == nil && resp != nil {
+		c.nodeProtocols.Store(conn.NodeID, resp.Proto)
+	}
+	c.checkSlowQuery(conn, method, endpoint, body, elapsed, err)
+
+	// Error from the 1st try, the refresh or the 2nd try, check if there is fallback to leader (and current connection is not already leader!)
+	if err != nil {
+		c.latency.recordError(endpoint)
+		if c.auditEnabled() {
+			c.afterResponse(info, resp, elapsed, err)
+		}
+		closeResponseBody(resp)
+		if fallback && conn != c.leaderConn {
+			return c.failover(ctx, conn, method, endpoint, body, withToken, autorefresh, err)
+		}
+		return nil, fmt.Errorf("api-call failed, err: %w", err)
+	}
 	// process the response and return only the Data part
-	return conn.getAndCheckResponseData(resp)
+	data, err := conn.getAndCheckResponseRaw(resp, &c.Config)
+	if err != nil {
+		c.latency.recordError(endpoint)
+		if errors.Is(err, ErrServerThrottled) {
+			atomic.AddInt64(&c.serverThrottles, 1)
+		}
+	}
+	if c.auditEnabled() {
+		c.afterResponse(info, resp, elapsed, err)
+	}
+	return data, err
 }
 
 //------------------------------------------------------------------
@@ -113,31 +176,89 @@ func (c *Client) sendRequestToPool(conn *Connection, method, endpoint string, bo
 // }
 
 // Generic sendRequest wrapper to call Connection.sendHttpRequest
-// write=true means it's write operation (insert/update/delete) because we use different connection pool
-// write=false means it's read operation (insert/update/delete)
+// reqType decides the connection pool: writes (insert/update/delete) use the write pool,
+// reads (select, status) use the read pool, see RequestType.isWrite
 // return is of type T which is generics, can be set from caller to be
 // orm.NodeStatusStruct
 // orm.SchemaStruct
 // suresql.QueryResponse     - all singular query (basically is Records)
 // suresql.QueryResponseSQL
 // suresql.SQLResponse
-// Converted using json.Marshal and json.Unmarshal to the generic types from  standardResponse.Data which is of type interface{}
+// standardResponse.Data is decoded directly (single Unmarshal) from the raw response into T
 // This function always requires token, which is connection essentially
-func sendRequest[T any](c *Client, method, endpoint string, body interface{}, isWrite, autorefresh, fallback bool) (T, error) {
-	var conn *Connection
+func sendRequest[T any](c *Client, method, endpoint string, body interface{}, reqType RequestType, autorefresh, fallback bool) (T, error) {
+	return sendRequestContext[T](context.Background(), c, method, endpoint, body, reqType, autorefresh, fallback)
+}
+
+// Same as sendRequest with a context. If IdempotentWrites is on, a write without an idempotency key
+// in ctx gets a new one, which is then reused by all retries (refresh, fallback, leader redirect) of this call.
+func sendRequestContext[T any](ctx context.Context, c *Client, method, endpoint string, body interface{}, reqType RequestType, autorefresh, fallback bool) (T, error) {
 	var err error
 	var typedResp T
-	var ok bool
+	if c.IsClosed() {
+		return typedResp, ErrClientClosed
+	}
+	isWrite := reqType.isWrite()
+	ctx = contextWithRequestType(ctx, reqType)
+
+	ctx, done := c.inflight.register(ctx)
+	defer done()
+
+	if isWrite && c.Config.IdempotentWrites && idempotencyKeyFromContext(ctx) == "" {
+		ctx = contextWithIdempotencyKey(ctx, newIdempotencyKey())
+	}
 
+	fetch := func() (json.RawMessage, error) {
+		return c.fetchFromPool(ctx, method, endpoint, body, isWrite, autorefresh, fallback)
+	}
+	rawData, cacheKey := c.cachedRead(endpoint, body, isWrite)
+	switch {
+	case rawData != nil:
+		// served from the read cache
+	case !isWrite && c.Config.ReadDeduplication:
+		rawData, err = c.dedupRead(endpoint, body, fetch)
+	default:
+		rawData, err = fetch()
+	}
 	if isWrite {
-		conn, err = c.getWriteConnection()
-	} else {
-		conn, err = c.getReadConnection()
+		// also on error, a write that timed out or partly failed may have been applied
+		c.readCache.invalidateWrite(body)
 	}
 	if err != nil {
+		return typedResp, err
+	}
+	if cacheKey != "" {
+		c.cacheRead(cacheKey, body, rawData)
+	}
+	if len(rawData) == 0 {
+		return typedResp, nil
+	}
+
+	// Decode the Data part directly into the typed response
+	if err = c.Config.codec().Unmarshal(rawData, &typedResp); err != nil {
+		return typedResp, fmt.Errorf("failed to unmarshal SQL response: %w", err)
+	}
+	if c.Config.SchemaTypeCoercion {
+		c.coerceResponse(&typedResp, body)
+	}
+	return typedResp, nil
+}
+
+// fetchFromPool acquires a read or write connection and sends the request, returning the raw Data part
+func (c *Client) fetchFromPool(ctx context.Context, method, endpoint string, body interface{}, isWrite, autorefresh, fallback bool) (json.RawMessage, error) {
+	// Client-side rate limit, before acquiring a connection (deduplicated reads don't take a token)
+	if err := c.waitRateLimit(ctx, isWrite); err != nil {
+		return nil, err
+	}
+
+	conn, err := c.acquireConnection(ctx, isWrite)
+	if err == nil {
+		// give back the node's in-flight slot taken by acquireConnection
+		defer c.bulkhead.release(conn.NodeID)
+	} else {
 		// If no connection found, and not falling back, return error!
 		if !fallback {
-			return typedResp, err
+			return nil, err
 		}
 		// Fall back to direct request if no read connections
 		fmt.Println("fallback to leader right away")
@@ -145,27 +266,14 @@ func sendRequest[T any](c *Client, method, endpoint string, body interface{}, is
 	}
 	defer c.markRequestComplete(conn, isWrite)
 	// fmt.Println("DEBUG: calling request to Pool")
-	rawData, err := c.sendRequestToPool(conn, method, endpoint, body, WITH_TOKEN, autorefresh, fallback)
-	if err != nil {
-		return typedResp, err
-	}
-
-	// Convert to SQLResponse
-	typedResp, ok = rawData.(T)
-	if !ok {
-		// If direct conversion failed, try marshal/unmarshal
-		jsonData, errL := json.Marshal(rawData)
-		if errL != nil {
-			// return typedResp, fmt.Errorf("failed to marshal SQL response data: %w", err)
-			err = fmt.Errorf("failed to marshal SQL response data: %w", errL)
-		} else {
-			if err = json.Unmarshal(jsonData, &typedResp); err != nil {
-				// return typedResp, fmt.Errorf("failed to unmarshal SQL response: %w", err)
-				err = fmt.Errorf("failed to unmarshal SQL response: %w", err)
-			}
-		}
+	rawData, err := c.sendRequestToPoolRaw(ctx, conn, method, endpoint, body, WITH_TOKEN, autorefresh, fallback)
+	// Write landed on a node that is not (or no longer) the leader, redirect it to the leader.
+	// Capped so an ongoing election doesn't make us loop.
+	for redirect := 0; err != nil && isWrite && isNotLeaderError(err) && redirect < DEFAULT_MAX_LEADER_REDIRECTS; redirect++ {
+		rawData, err = c.sendRequestToPoolRaw(ctx, c.redirectLeaderConnection(ctx),
+			method, endpoint, body, WITH_TOKEN, autorefresh, NO_FALLBACK)
 	}
-	return typedResp, err
+	return rawData, err
 }
 
 // executeReadRequest gets a read connection and executes the request
@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/medatechnology/suresql"
+)
+
+// roundTripFunc is a stub http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// stubResponse returns a response with the given status and body
+func stubResponse(req *http.Request, status int, contentType, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Proto:      "HTTP/1.1",
+		Header:     http.Header{"Content-Type": {contentType}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
+
+// okResponse returns a StandardResponse with status 200 and the given data (JSON)
+func okResponse(req *http.Request, data string) *http.Response {
+	return stubResponse(req, http.StatusOK, "application/json", `{"status":200,"message":"OK","data":`+data+`}`)
+}
+
+// newStubClient returns a client whose leader connection has a token and sends every request to rt
+func newStubClient(t *testing.T, rt http.RoundTripper) *Client {
+	t.Helper()
+	config := NewClientConfig(WithServerURL("http://leader.test"), WithHTTPTransport(rt))
+	config.AllowInsecure = true
+	c, err := NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.leaderConn.Token = suresql.TokenTable{Token: "token", Refresh: "refresh"}
+	return c
+}
+
+func TestRequestAfterTokenRefresh(t *testing.T) {
+	tests := []struct {
+		name        string
+		retry       func(req *http.Request) (*http.Response, error) // answer to the request sent again after the refresh
+		pooled      bool                                            // send on a pooled connection, which falls back to the leader
+		wantErr     string
+		wantRefresh int
+	}{
+		{
+			name:        "refresh then success",
+			retry:       func(req *http.Request) (*http.Response, error) { return okResponse(req, `{"ok":true}`), nil },
+			wantRefresh: 1,
+		},
+		{
+			name:        "refresh then network error",
+			retry:       func(req *http.Request) (*http.Response, error) { return nil, errors.New("connection reset by peer") },
+			wantErr:     "connection reset by peer",
+			wantRefresh: 1,
+		},
+		{
+			name:        "refresh then network error falls back to leader",
+			retry:       func(req *http.Request) (*http.Response, error) { return nil, errors.New("connection reset by peer") },
+			pooled:      true,
+			wantRefresh: 1,
+		},
+		{
+			name: "refresh then 401 again",
+			retry: func(req *http.Request) (*http.Response, error) {
+				return stubResponse(req, http.StatusUnauthorized, "application/json", `{"status":401,"message":"unauthorized"}`), nil
+			},
+			wantErr:     "still unauthorized after token refresh",
+			wantRefresh: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			refreshes, sent := 0, 0
+			c := newStubClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				switch {
+				case req.URL.Path == "/db/refresh":
+					refreshes++
+					return okResponse(req, `{"token":"new-token","refresh_token":"new-refresh"}`), nil
+				case req.URL.Host == "leader.test" && tt.pooled:
+					// the fallback
+					return okResponse(req, `{"ok":true}`), nil
+				}
+				sent++
+				if sent == 1 {
+					return stubResponse(req, http.StatusUnauthorized, "application/json", `{"status":401,"message":"token expired"}`), nil
+				}
+				return tt.retry(req)
+			}))
+			conn := c.leaderConn
+			if tt.pooled {
+				conn = NewConnection(&c.Config, "http://node1.test", "node1", "r", false, suresql.TokenTable{Token: "token", Refresh: "refresh"})
+			}
+
+			data, err := c.doRequestToPool(context.Background(), conn, "POST", "/db/api/sql", nil, WITH_TOKEN, AUTO_REFRESH, FALLBACK_LEADER)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if string(data) != `{"ok":true}` {
+					t.Errorf("data = %s", data)
+				}
+			}
+			if refreshes != tt.wantRefresh {
+				t.Errorf("refreshes = %d, want %d", refreshes, tt.wantRefresh)
+			}
+			if token := conn.token().Token; token != "new-token" {
+				t.Errorf("token = %q, want the refreshed token", token)
+			}
+		})
+	}
+}
+
+func TestConcurrentRequestsDuringTokenRefresh(t *testing.T) {
+	var refreshes int32
+	c := newStubClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/db/refresh" {
+			atomic.AddInt32(&refreshes, 1)
+			return okResponse(req, `{"token":"new-token","refresh_token":"new-refresh"}`), nil
+		}
+		if req.Header.Get("Authorization") != "Bearer new-token" {
+			return stubResponse(req, http.StatusUnauthorized, "application/json", `{"status":401,"message":"token expired"}`), nil
+		}
+		return okResponse(req, `{"ok":true}`), nil
+	}))
+
+	// run with -race: the requests read the token while one of them renews it
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.doRequestToPool(context.Background(), c.leaderConn, "POST", "/db/api/sql", nil, WITH_TOKEN, AUTO_REFRESH, NO_FALLBACK); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := atomic.LoadInt32(&refreshes); got != 1 {
+		t.Errorf("refreshes = %d, want 1", got)
+	}
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	orm "github.com/medatechnology/simpleorm"
+)
+
+//------------------------------------------------------------------
+// SCALAR QUERY HELPERS
+//------------------------------------------------------------------
+
+// scalarValue runs a single-row, single-column query and returns the lone value.
+// A NULL value (ie: MAX() on an empty table) returns an error wrapping orm.ErrSQLNoRows.
+func (c *Client) scalarValue(paramSQL orm.ParametereizedSQL) (interface{}, error) {
+	record, err := c.SelectOnlyOneSQLParameterized(paramSQL)
+	if err != nil {
+		return nil, err
+	}
+	if len(record.Data) != 1 {
+		return nil, fmt.Errorf("scalar query must return exactly 1 column, got %d", len(record.Data))
+	}
+	for column, value := range record.Data {
+		if value == nil {
+			return nil, fmt.Errorf("scalar value of column %s is NULL: %w", column, orm.ErrSQLNoRows)
+		}
+		return value, nil
+	}
+	return nil, orm.ErrSQLNoRows
+}
+
+// ScalarInt runs a single-value query (ie: SELECT COUNT(*) FROM users) and returns it as int64.
+// JSON numbers arrive as float64, those are converted as long as they have no fraction.
+func (c *Client) ScalarInt(paramSQL orm.ParametereizedSQL) (int64, error) {
+	value, err := c.scalarValue(paramSQL)
+	if err != nil {
+		return 0, err
+	}
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("scalar value %v is not an integer", v)
+		}
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case json.Number:
+		return v.Int64()
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("cannot convert scalar value of type %T to int", value)
+}
+
+// ScalarFloat runs a single-value query (ie: SELECT AVG(price) FROM products) and returns it as float64
+func (c *Client) ScalarFloat(paramSQL orm.ParametereizedSQL) (float64, error) {
+	value, err := c.scalarValue(paramSQL)
+	if err != nil {
+		return 0, err
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return 0, fmt.Errorf("cannot convert scalar value of type %T to float", value)
+}
+
+// ScalarString runs a single-value query and returns it as string, numbers are formatted without exponent
+func (c *Client) ScalarString(paramSQL orm.ParametereizedSQL) (string, error) {
+	value, err := c.scalarValue(paramSQL)
+	if err != nil {
+		return "", err
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case json.Number:
+		return v.String(), nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// ScalarBool runs a single-value query (ie: SELECT EXISTS(...)) and returns it as bool.
+// SQLite has no boolean type, so 0/1 numbers and "true"/"false"/"0"/"1" strings are accepted.
+func (c *Client) ScalarBool(paramSQL orm.ParametereizedSQL) (bool, error) {
+	value, err := c.scalarValue(paramSQL)
+	if err != nil {
+		return false, err
+	}
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case float64:
+		return v != 0, nil
+	case int64:
+		return v != 0, nil
+	case int:
+		return v != 0, nil
+	case json.Number:
+		f, err := v.Float64()
+		return f != 0, err
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(v))
+	}
+	return false, fmt.Errorf("cannot convert scalar value of type %T to bool", value)
+}
@@ -1,24 +1,29 @@
 package client
 
 import (
+	"context"
+	"math/rand"
 	"time"
 )
 
 // startCleanupTimer starts the periodic cleanup routine
 func (c *Client) startCleanupTimer() {
-	c.cleanupDone = make(chan struct{})
-	c.cleanupTimer = time.NewTimer(c.PoolConfig.ScaleDownInterval)
+	// the routine keeps its own references, CloseConnections clears the fields
+	done := make(chan struct{})
+	timer := time.NewTimer(c.hot().scaleDownInterval)
+	c.cleanupDone = done
+	c.cleanupTimer = timer
 
 	go func() {
 		for {
 			select {
-			case <-c.cleanupTimer.C:
+			case <-timer.C:
 				c.cleanupIdleConnections()
-				c.cleanupTimer.Reset(c.PoolConfig.ScaleDownInterval)
-			case <-c.cleanupDone:
-				if !c.cleanupTimer.Stop() {
+				timer.Reset(c.hot().scaleDownInterval)
+			case <-done:
+				if !timer.Stop() {
 					select {
-					case <-c.cleanupTimer.C:
+					case <-timer.C:
 					default:
 					}
 				}
@@ -53,6 +58,23 @@ func (c *Client) getOrCreateNodeStats(nodeID string, isWrite bool) *ConnectionSt
 	return stats
 }
 
+// allNodeStats returns the stats of every node of the read or write pool. The list is copied under
+// scalingMutex, so it can be ranged while ForgetNode removes nodes.
+func (c *Client) allNodeStats(isWrite bool) []*ConnectionStats {
+	c.scalingMutex.Lock()
+	defer c.scalingMutex.Unlock()
+
+	statsPerNode := c.statsPerNodeRead
+	if isWrite {
+		statsPerNode = c.statsPerNodeWrite
+	}
+	all := make([]*ConnectionStats, 0, len(statsPerNode))
+	for _, stats := range statsPerNode {
+		all = append(all, stats)
+	}
+	return all
+}
+
 // recordNodeUsage records a usage event for a node
 func (c *Client) recordNodeUsage(nodeID string, isWrite bool) {
 	stats := c.getOrCreateNodeStats(nodeID, isWrite)
@@ -78,18 +100,31 @@ func (c *Client) beginRequest(conn *Connection, isWrite bool) {
 	defer stats.HistoryMutex.Unlock()
 
 	stats.ActiveRequests++
+	hot := c.hot()
 
-	// Check if we need to scale up
-	if stats.ActiveRequests >= c.PoolConfig.ScaleUpThreshold {
-		// Avoid frequent scale-ups
-		if time.Since(stats.LastScaleUp) > 10*time.Second {
-			if isWrite {
-				go c.scaleUpNode(conn, isWrite)
-			} else {
-				go c.scaleUpNode(conn, isWrite)
+	// A node over the threshold that couldn't scale up for SaturationWindow is saturated
+	if stats.ActiveRequests >= hot.scaleUpThreshold && !stats.saturated &&
+		!stats.atMaxSince.IsZero() && time.Since(stats.atMaxSince) >= c.PoolConfig.SaturationWindow {
+		stats.saturated = true
+		go c.poolSaturated(conn.NodeID, isWrite, stats.ActiveRequests)
+	}
+
+	// Check if we need to scale up. The decision is made under the lock and LastScaleUp is set
+	// before the scale-up starts, so a burst of requests triggers at most one batch per interval.
+	if stats.ActiveRequests >= hot.scaleUpThreshold &&
+		!stats.scalingUp && time.Since(stats.LastScaleUp) > hot.scaleUpInterval {
+		stats.LastScaleUp = time.Now()
+		stats.scalingUp = true
+		go func() {
+			// Spread connection creation of many nodes/clients scaling up at the same time
+			if hot.scaleUpJitter > 0 {
+				time.Sleep(time.Duration(rand.Int63n(int64(hot.scaleUpJitter))))
 			}
-			stats.LastScaleUp = time.Now()
-		}
+			c.scaleUpNode(context.Background(), conn, isWrite)
+			stats.HistoryMutex.Lock()
+			stats.scalingUp = false
+			stats.HistoryMutex.Unlock()
+		}()
 	}
 }
 
@@ -103,14 +138,45 @@ func (c *Client) endRequest(nodeID string, isWrite bool) {
 	if stats.ActiveRequests > 0 {
 		stats.ActiveRequests--
 	}
+	// back under the threshold, the saturation window starts over
+	if stats.ActiveRequests < c.hot().scaleUpThreshold {
+		stats.atMaxSince = time.Time{}
+		stats.saturated = false
+	}
+}
+
+// markAtMaxPool starts the saturation window of a node that is over the threshold but already
+// has its max pool size
+func (c *Client) markAtMaxPool(nodeID string, isWrite bool) {
+	stats := c.getOrCreateNodeStats(nodeID, isWrite)
+
+	stats.HistoryMutex.Lock()
+	defer stats.HistoryMutex.Unlock()
+
+	if stats.ActiveRequests >= c.hot().scaleUpThreshold && stats.atMaxSince.IsZero() {
+		stats.atMaxSince = time.Now()
+	}
+}
+
+// poolSaturated records the saturation of a node and fires OnPoolSaturated
+func (c *Client) poolSaturated(nodeID string, isWrite bool, activeRequests int) {
+	maxPool := c.maxPoolForNode(nodeID, isWrite)
+	c.recordPoolEvent(POOL_EVENT_SATURATED, nodeID, "write=%t %d active requests, max pool %d", isWrite, activeRequests, maxPool)
+	if c.Config.OnPoolSaturated != nil {
+		c.Config.OnPoolSaturated(nodeID, activeRequests, maxPool)
+	}
 }
 
 // Get maxPool (read) then maxWritePool (write) by NodeID from status
 func (c *Client) findMaxPoolsByNodeID(nodeID string) int {
-	if nodeID == c.status.NodeID {
-		return c.status.MaxPool
+	status := c.getStatus()
+	if status == nil {
+		return c.PoolConfig.MaxPoolSize
 	}
-	for _, p := range c.status.Peers {
+	if nodeID == status.NodeID {
+		return status.MaxPool
+	}
+	for _, p := range status.Peers {
 		if nodeID == p.NodeID {
 			return p.MaxPool
 		}
@@ -118,24 +184,37 @@ func (c *Client) findMaxPoolsByNodeID(nodeID string) int {
 	return c.PoolConfig.MaxPoolSize
 }
 
-// scaleUpNode adds connections to both read and write pools for a node if needed
-func (c *Client) scaleUpNode(conn *Connection, isWrite bool) {
+// maxPoolForNode returns the max read (from status) or write pool size of a node
+func (c *Client) maxPoolForNode(nodeID string, isWrite bool) int {
+	if isWrite {
+		return c.readPool.maxWritePool
+	}
+	return c.findMaxPoolsByNodeID(nodeID)
+}
+
+// scaleUpNode adds connections to the read or write pool for a node if needed, nodes are only
+// added to the pools their mode allows (ie: a read-only replica never joins the write pool)
+func (c *Client) scaleUpNode(ctx context.Context, conn *Connection, isWrite bool) {
+	if !modeAllows(conn.Mode, isWrite) {
+		return
+	}
 	// Get node info from connection
-	maxPool := c.findMaxPoolsByNodeID(conn.NodeID)
+	maxPool := c.maxPoolForNode(conn.NodeID, isWrite)
 	pool := c.readPool
 	if isWrite {
-		maxPool = c.readPool.maxWritePool
 		pool = c.writePool
 	}
 
 	currentSize := pool.SizeForNode(conn.NodeID)
 	// Calculate how many connections we can add
-	addCount := min(c.PoolConfig.ScaleUpBatchSize, maxPool-currentSize)
+	addCount := min(c.hot().scaleUpBatchSize, maxPool-currentSize)
 	if addCount <= 0 {
+		// can't scale further, OnPoolSaturated fires if the load stays
+		c.markAtMaxPool(conn.NodeID, isWrite)
 		return
 	}
 
-	connections := c.createPoolConnections(conn.URL, conn.NodeID, conn.Mode, conn.IsLeader, addCount)
+	connections := c.createPoolConnections(ctx, conn.URL, conn.NodeID, conn.Mode, conn.IsLeader, addCount)
 
 	// Add connections to pool if any were created
 	if len(connections) > 0 {
@@ -146,7 +225,10 @@ func (c *Client) scaleUpNode(conn *Connection, isWrite bool) {
 		stats.HistoryMutex.Lock()
 		stats.CurrentConnections += len(connections)
 		stats.ScaleUpEvents++
+		stats.atMaxSince = time.Time{}
+		stats.saturated = false
 		stats.HistoryMutex.Unlock()
+		c.recordPoolEvent(POOL_EVENT_SCALE_UP, conn.NodeID, "write=%t +%d connections (now %d)", isWrite, len(connections), currentSize+len(connections))
 	}
 }
 
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/medatechnology/suresql"
+)
+
+func TestConcurrentScaleUpCreatesOneBatch(t *testing.T) {
+	var connects int64
+	c := newStubClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/db/connect" {
+			atomic.AddInt64(&connects, 1)
+		}
+		return okResponse(req, `{"token":"token","refresh_token":"refresh"}`), nil
+	}))
+	c.PoolConfig.MaxPoolSize = 100
+	hot := *c.hot()
+	hot.scaleUpThreshold = 1
+	hot.scaleUpBatchSize = 2
+	hot.scaleUpInterval = time.Hour
+	hot.scaleUpJitter = 0
+	c.settings.Store(&hot)
+	conn := NewConnection(&c.Config, "http://node1.test", "node1", "rw", false, suresql.TokenTable{Token: "token"})
+
+	// a burst of requests all over the threshold at once
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.beginRequest(conn, IS_READ)
+		}()
+	}
+	wg.Wait()
+
+	stats := c.getOrCreateNodeStats("node1", IS_READ)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats.HistoryMutex.Lock()
+		done := !stats.scalingUp
+		stats.HistoryMutex.Unlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("scale-up did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if stats.ScaleUpEvents != 1 {
+		t.Errorf("ScaleUpEvents = %d, want 1", stats.ScaleUpEvents)
+	}
+	if size := c.readPool.SizeForNode("node1"); size != 2 {
+		t.Errorf("read pool has %d connections for node1, want one batch of 2", size)
+	}
+	if n := atomic.LoadInt64(&connects); n != 2 {
+		t.Errorf("%d connects, want 2", n)
+	}
+}
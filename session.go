@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	orm "github.com/medatechnology/simpleorm"
+	"github.com/medatechnology/suresql"
+)
+
+//------------------------------------------------------------------
+// SESSION (CONNECTION AFFINITY)
+//------------------------------------------------------------------
+
+// ErrSessionClosed is returned by the methods of a closed Session
+var ErrSessionClosed = errors.New("session is closed")
+
+// Session pins one write connection, so all its statements go to the same node (ie: for temp
+// tables or other server-side session state). It is not a transaction, nothing is wrapped in
+// BEGIN/COMMIT. There is no fallback to another node, if the node fails the calls fail.
+// The pinned connection is not removed by the idle cleanup, and it keeps its in-flight slot
+// (see WithMaxInFlightPerNode) until Close.
+// Usage:
+//
+//	session, err := db.Session()
+//	defer session.Close()
+//	session.ExecOneSQL("CREATE TEMP TABLE t (id INTEGER)")
+//	records, err := session.SelectOneSQL("SELECT * FROM t")
+type Session struct {
+	client    *Client
+	conn      *Connection
+	closeOnce sync.Once
+	closed    int32
+}
+
+// Session creates a Session pinned to a write connection
+func (c *Client) Session() (*Session, error) {
+	if c.IsClosed() {
+		return nil, ErrClientClosed
+	}
+	conn, err := c.acquireConnection(context.Background(), IS_WRITE)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt32(&conn.pinned, 1)
+	return &Session{client: c, conn: conn}, nil
+}
+
+// NodeID returns the node the session is pinned to
+func (s *Session) NodeID() string {
+	return s.conn.NodeID
+}
+
+// Close gives the connection back to the pool, it is safe to call more than once
+func (s *Session) Close() {
+	s.closeOnce.Do(func() {
+		atomic.StoreInt32(&s.closed, 1)
+		atomic.AddInt32(&s.conn.pinned, -1)
+		s.client.bulkhead.release(s.conn.NodeID)
+		s.client.markRequestComplete(s.conn, IS_WRITE)
+	})
+}
+
+// sessionRequest sends the request to the pinned connection and decodes the Data part into T
+func sessionRequest[T any](s *Session, endpoint string, body interface{}) (T, error) {
+	var typedResp T
+	if atomic.LoadInt32(&s.closed) == 1 {
+		return typedResp, ErrSessionClosed
+	}
+	ctx, done := s.client.inflight.register(context.Background())
+	defer done()
+	rawData, err := s.client.sendRequestToPoolRaw(ctx, s.conn, "POST", endpoint, body, WITH_TOKEN, AUTO_REFRESH, NO_FALLBACK)
+	if err != nil {
+		return typedResp, err
+	}
+	if endpoint == "/db/api/sql" {
+		s.client.readCache.invalidateWrite(body)
+	}
+	if len(rawData) == 0 {
+		return typedResp, nil
+	}
+	if err = s.client.Config.codec().Unmarshal(rawData, &typedResp); err != nil {
+		return typedResp, fmt.Errorf("failed to unmarshal SQL response: %w", err)
+	}
+	if s.client.Config.SchemaTypeCoercion {
+		s.client.coerceResponse(&typedResp, body)
+	}
+	return typedResp, nil
+}
+
+// ExecOneSQL executes a single SQL statement on the session's node
+func (s *Session) ExecOneSQL(sql string) orm.BasicSQLResult {
+	results, err := s.ExecManySQL([]string{sql})
+	if err != nil {
+		return orm.BasicSQLResult{Error: err}
+	}
+	return results[0]
+}
+
+// ExecOneSQLParameterized executes a single parameterized SQL statement on the session's node
+func (s *Session) ExecOneSQLParameterized(paramSQL orm.ParametereizedSQL) orm.BasicSQLResult {
+	results, err := s.ExecManySQLParameterized([]orm.ParametereizedSQL{paramSQL})
+	if err != nil {
+		return orm.BasicSQLResult{Error: err}
+	}
+	return results[0]
+}
+
+// ExecManySQL executes multiple SQL statements on the session's node
+func (s *Session) ExecManySQL(sqlStatements []string) ([]orm.BasicSQLResult, error) {
+	return s.exec(&suresql.SQLRequest{Statements: sqlStatements})
+}
+
+// ExecManySQLParameterized executes multiple parameterized SQL statements on the session's node
+func (s *Session) ExecManySQLParameterized(paramSQLs []orm.ParametereizedSQL) ([]orm.BasicSQLResult, error) {
+	return s.exec(&suresql.SQLRequest{ParamSQL: paramSQLs})
+}
+
+func (s *Session) exec(req *suresql.SQLRequest) ([]orm.BasicSQLResult, error) {
+	response, err := sessionRequest[suresql.SQLResponse](s, "/db/api/sql", req)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Results) == 0 {
+		return nil, errors.New("no results returned")
+	}
+	return response.Results, nil
+}
+
+// SelectOneSQL executes a single SQL query on the session's node
+func (s *Session) SelectOneSQL(sql string) (orm.DBRecords, error) {
+	return s.query(&suresql.SQLRequest{Statements: []string{sql}})
+}
+
+// SelectOneSQLParameterized executes a single parameterized SQL query on the session's node
+func (s *Session) SelectOneSQLParameterized(paramSQL orm.ParametereizedSQL) (orm.DBRecords, error) {
+	return s.query(&suresql.SQLRequest{ParamSQL: []orm.ParametereizedSQL{paramSQL}})
+}
+
+func (s *Session) query(req *suresql.SQLRequest) (orm.DBRecords, error) {
+	response, err := sessionRequest[suresql.QueryResponseSQL](s, "/db/api/querysql", req)
+	if err != nil {
+		return nil, err
+	}
+	// let user know this is not error, just no rows found
+	if len(response) == 0 || len(response[0].Records) == 0 {
+		return nil, orm.ErrSQLNoRows
+	}
+	return response[0].Records, nil
+}
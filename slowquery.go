@@ -0,0 +1,98 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/medatechnology/suresql"
+)
+
+//------------------------------------------------------------------
+// SLOW QUERY REPORTING
+//------------------------------------------------------------------
+
+// QueryInfo describes a request that took longer than ClientConfig.SlowQueryThreshold.
+// SQL never contains bound parameter values, and quoted literals of raw statements are
+// replaced with '?', so it's safe to log.
+type QueryInfo struct {
+	Method   string
+	Endpoint string
+	NodeID   string
+	NodeURL  string
+	Elapsed  time.Duration
+	SQL      string
+	Error    error
+}
+
+// checkSlowQuery fires the OnSlowQuery hook if the request was slower than the threshold
+func (c *Client) checkSlowQuery(conn *Connection, method, endpoint string, body interface{}, elapsed time.Duration, err error) {
+	threshold := c.hot().slowQueryThreshold
+	if c.Config.OnSlowQuery == nil || threshold <= 0 || elapsed < threshold {
+		return
+	}
+	c.Config.OnSlowQuery(QueryInfo{
+		Method:   method,
+		Endpoint: endpoint,
+		NodeID:   conn.NodeID,
+		NodeURL:  conn.URL,
+		Elapsed:  elapsed,
+		SQL:      truncateSQL(querySQLFromBody(body), DEFAULT_SLOW_QUERY_SQL_LENGTH),
+		Error:    err,
+	})
+}
+
+// querySQLFromBody describes the SQL of a request body without any values
+func querySQLFromBody(body interface{}) string {
+	switch req := body.(type) {
+	case *suresql.SQLRequest:
+		statements := make([]string, 0, len(req.Statements)+len(req.ParamSQL))
+		for _, sql := range req.Statements {
+			statements = append(statements, redactSQLLiterals(sql))
+		}
+		for _, paramSQL := range req.ParamSQL {
+			statements = append(statements, paramSQL.Query)
+		}
+		return strings.Join(statements, "; ")
+	case *suresql.QueryRequest:
+		return fmt.Sprintf("SELECT * FROM %s", req.Table)
+	case *suresql.InsertRequest:
+		if len(req.Records) == 0 {
+			return ""
+		}
+		return fmt.Sprintf("INSERT INTO %s (%d records)", req.Records[0].TableName, len(req.Records))
+	}
+	return ""
+}
+
+// redactSQLLiterals replaces single-quoted string literals with '?'
+func redactSQLLiterals(sql string) string {
+	var sb strings.Builder
+	inLiteral := false
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		if ch != '\'' {
+			if !inLiteral {
+				sb.WriteByte(ch)
+			}
+			continue
+		}
+		// a doubled quote inside a literal is an escaped quote
+		if inLiteral && i+1 < len(sql) && sql[i+1] == '\'' {
+			i++
+			continue
+		}
+		if !inLiteral {
+			sb.WriteString("'?'")
+		}
+		inLiteral = !inLiteral
+	}
+	return sb.String()
+}
+
+func truncateSQL(sql string, max int) string {
+	if len(sql) <= max {
+		return sql
+	}
+	return sql[:max] + "..."
+}
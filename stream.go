@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	orm "github.com/medatechnology/simpleorm"
+)
+
+//------------------------------------------------------------------
+// STREAMING METHODS
+//------------------------------------------------------------------
+
+// InsertStream reads records from the channel and inserts them in batches of batchSize.
+// Records are grouped by TableName and each group is sent with InsertManyDBRecordsSameTable.
+// A partial batch is flushed every Config.StreamFlushInterval even if batchSize is not reached.
+// The returned channel gets the results of every insert and is closed when the records channel
+// is closed (after flushing the remaining batch) or when ctx is cancelled. Cancelling ctx also
+// cancels the insert in flight. The records already taken from the channel but not inserted are
+// reported in a last result whose Error wraps ctx.Err(), so read the channel until it is closed.
+// Usage:
+//
+//	results, err := db.InsertStream(ctx, records, 500)
+//	for res := range results {
+//	  if res.Error != nil { ... }
+//	}
+func (c *Client) InsertStream(ctx context.Context, records <-chan orm.DBRecord, batchSize int) (<-chan orm.BasicSQLResult, error) {
+	if records == nil {
+		return nil, errors.New("records channel is nil")
+	}
+	if batchSize <= 0 {
+		return nil, errors.New("batch size must be bigger than zero")
+	}
+	interval := ValueOrDefault(c.hot().streamFlushInterval, DEFAULT_STREAM_FLUSH_INTERVAL, DurationBiggerThanZero)
+
+	results := make(chan orm.BasicSQLResult, batchSize)
+
+	go func() {
+		defer close(results)
+
+		batch := make([]orm.DBRecord, 0, batchSize)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// flush inserts the current batch and sends its results, a cancelled insert is an error result
+		flush := func() {
+			if len(batch) == 0 {
+				return
+			}
+			for _, res := range c.insertBatchByTable(ctx, batch) {
+				results <- res
+			}
+			batch = make([]orm.DBRecord, 0, batchSize)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				if len(batch) > 0 {
+					results <- orm.BasicSQLResult{Error: fmt.Errorf("insert stream cancelled, %d records not inserted: %w", len(batch), ctx.Err())}
+				}
+				return
+			case rec, ok := <-records:
+				if !ok {
+					flush()
+					return
+				}
+				batch = append(batch, rec)
+				if len(batch) >= batchSize {
+					flush()
+				}
+			case <-ticker.C:
+				flush()
+			}
+		}
+	}()
+
+	return results, nil
+}
+
+// insertBatchByTable groups the records by table name and inserts each group, keeping the order
+// in which the tables first appear. Errors are returned as results with Error set.
+func (c *Client) insertBatchByTable(ctx context.Context, batch []orm.DBRecord) []orm.BasicSQLResult {
+	tableOrder := make([]string, 0)
+	byTable := make(map[string][]orm.DBRecord)
+	for _, rec := range batch {
+		if _, exists := byTable[rec.TableName]; !exists {
+			tableOrder = append(tableOrder, rec.TableName)
+		}
+		byTable[rec.TableName] = append(byTable[rec.TableName], rec)
+	}
+
+	all := make([]orm.BasicSQLResult, 0, len(batch))
+	for _, table := range tableOrder {
+		res, err := c.insertManySameTable(ctx, byTable[table], false)
+		if err != nil {
+			all = append(all, orm.BasicSQLResult{Error: err})
+			continue
+		}
+		all = append(all, res...)
+	}
+	return all
+}
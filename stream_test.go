@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	orm "github.com/medatechnology/simpleorm"
+)
+
+// collectResults reads results until the channel is closed
+func collectResults(t *testing.T, results <-chan orm.BasicSQLResult) []orm.BasicSQLResult {
+	t.Helper()
+	var all []orm.BasicSQLResult
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case res, ok := <-results:
+			if !ok {
+				return all
+			}
+			all = append(all, res)
+		case <-timeout:
+			t.Fatal("results channel not closed")
+		}
+	}
+}
+
+func TestInsertStreamCancelReportsPendingBatch(t *testing.T) {
+	c := newStubClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Errorf("unexpected request to %s", req.URL.Path)
+		return okResponse(req, `{}`), nil
+	}))
+	hot := *c.hot()
+	hot.streamFlushInterval = time.Hour
+	c.settings.Store(&hot)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	records := make(chan orm.DBRecord)
+	results, err := c.InsertStream(ctx, records, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		records <- orm.DBRecord{TableName: "events", Data: map[string]interface{}{"id": i}}
+	}
+	cancel()
+
+	all := collectResults(t, results)
+	if len(all) != 1 || !errors.Is(all[0].Error, context.Canceled) || !strings.Contains(all[0].Error.Error(), "3 records not inserted") {
+		t.Errorf("results = %+v, want one error for the 3 pending records", all)
+	}
+}
+
+func TestInsertStreamCancelsInsertInFlight(t *testing.T) {
+	started := make(chan struct{})
+	c := newStubClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/db/api/insert" {
+			// no cluster status, the insert falls back to the leader
+			return stubResponse(req, http.StatusInternalServerError, "text/plain", "no status"), nil
+		}
+		close(started)
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	records := make(chan orm.DBRecord, 2)
+	records <- orm.DBRecord{TableName: "events", Data: map[string]interface{}{"id": 1}}
+	records <- orm.DBRecord{TableName: "events", Data: map[string]interface{}{"id": 2}}
+	results, err := c.InsertStream(ctx, records, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	cancel()
+
+	all := collectResults(t, results)
+	if len(all) != 1 || !errors.Is(all[0].Error, context.Canceled) {
+		t.Errorf("results = %+v, want the cancelled insert as an error", all)
+	}
+}
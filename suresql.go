@@ -1,9 +1,16 @@
 package client
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"math"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	utils "github.com/medatechnology/goutil"
@@ -18,6 +25,16 @@ func (c *Client) Migrate(dir string) error {
 	return ms.Migrate(dir)
 }
 
+// MigrateFS runs schema migrations from a directory of fsys, ie: an embed.FS
+func (c *Client) MigrateFS(fsys fs.FS, dir string) error {
+	return NewMigrationService(c).MigrateFS(fsys, dir)
+}
+
+// MigrationStatus lists the applied and pending migrations of the specified directory
+func (c *Client) MigrationStatus(dir string) ([]MigrationStatusRow, error) {
+	return NewMigrationService(c).MigrationStatus(dir)
+}
+
 const (
 	DEFAULT_ENVIRONMENT_FILE = ".env.client"
 	DEFAULT_AUTO_REFRESH     = true
@@ -33,8 +50,16 @@ const (
 	IS_READ         = false // for read operation, used to find connection from readPools
 	CALL_REFRESH    = true  // for calling /refresh on function newOrRefreshToken
 	CALL_CONNECT    = false // for calling /connect on function newOrRefreshToken
+	KEEP_LAST       = true  // for SelectManyWithConditionMap, a duplicate key replaces the previous record
+	NO_DUPLICATES   = false // for SelectManyWithConditionMap, a duplicate key returns ErrDuplicateKey
 )
 
+// ErrDuplicateKey is returned by SelectManyWithConditionMap when two records have the same key
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// ErrClientClosed is returned by every request of a client after Close
+var ErrClientClosed = errors.New("client is closed")
+
 // Initialized the client package, loading environment file(s)
 func init() {
 	_, err := os.Stat(DEFAULT_ENVIRONMENT_FILE)
@@ -52,13 +77,22 @@ func init() {
 // Connect authenticates with the SureSQL server and initializes the connection pool
 // This has to use leader connection
 func (c *Client) Connect(username, password string) error {
+	return c.ConnectWithContext(context.Background(), username, password)
+}
+
+// ConnectWithContext is like Connect but aborts as soon as ctx is cancelled or its deadline
+// passes, including during the pool initialization. Any partially created pool is discarded.
+func (c *Client) ConnectWithContext(ctx context.Context, username, password string) error {
+	if c.IsClosed() {
+		return ErrClientClosed
+	}
 	// Just in case it is being recalled again
 	if c.Connected {
 		return errors.New("already connected, no need to call again")
 	}
 
 	// Use leader connection. TODO: make DEFAULT_AUTO_REFRESH more dynamic, maybe from environment variable
-	data, err := c.sendRequestToLeader("POST", "/db/connect", c.userCredentialsDefault(username, password), NO_TOKEN, DEFAULT_AUTO_REFRESH)
+	data, err := c.sendRequestToLeader(ctx, "POST", "/db/connect", c.userCredentialsDefault(username, password), NO_TOKEN, DEFAULT_AUTO_REFRESH)
 	if err != nil {
 		return err
 	}
@@ -69,26 +103,61 @@ func (c *Client) Connect(username, password string) error {
 	}
 
 	// save the token
-	c.leaderConn.Token = tokenObj
-	c.leaderConn.LastRefresh = time.Now()
+	c.leaderConn.setToken(tokenObj)
 	c.Connected = true
 
 	fmt.Println("going to call initialize pool")
 	// Initialize the connection pool
-	return c.InitializePool()
+	err = c.initializePool(ctx)
+	if err != nil {
+		c.Connected = false
+	}
+	return err
+}
+
+// ConnectWithToken connects with a token issued elsewhere (ie: by an upstream auth service), without
+// calling /db/connect, so the client never needs the database credentials. Every pooled connection
+// starts with this token. The connections renew it together with one /db/refresh call and all get the
+// new (rotated) token. There is no login fallback: once the refresh token is expired or rejected,
+// requests fail and a new token must be given to ConnectWithToken of a new client. Clients sharing
+// one token rotate it under each other, so give each client its own token.
+func (c *Client) ConnectWithToken(token suresql.TokenTable) error {
+	if c.IsClosed() {
+		return ErrClientClosed
+	}
+	if c.Connected {
+		return errors.New("already connected, no need to call again")
+	}
+	if token.Token == "" {
+		return errors.New("token is empty")
+	}
+	if !token.TokenExpiresAt.IsZero() && time.Now().After(token.TokenExpiresAt) && token.Refresh == "" {
+		return errors.New("token is expired and has no refresh token")
+	}
+
+	c.Config.tokenOnly = true
+	leaderConn := c.getLeaderConnection()
+	leaderConn.setToken(token)
+	c.Connected = true
+
+	err := c.initializePool(context.Background())
+	if err != nil {
+		c.Connected = false
+	}
+	return err
 }
 
 // GetRefreshToken updates the access token using the refresh token
 // Since we are using connection pool, for now, this only checks for the LeaderConn token!
 func (c *Client) GetRefreshToken() error {
-	return c.leaderConn.tryRefreshAndRenew(&c.Config)
+	return c.renewToken(context.Background(), c.leaderConn, c.leaderConn.token().Token)
 }
 
 // GetSchema returns the database schema
 func (c *Client) GetSchema(hideSQL bool, hideSureSQL bool) []orm.SchemaStruct {
 
 	// Since schema returns array of SchemaStruct, first we process as []interface{}
-	data, err := c.sendRequestToLeader("GET", "/db/api/getschema", nil, true, false)
+	data, err := c.sendRequestToLeader(context.Background(), "GET", "/db/api/getschema", nil, true, false)
 	// data, err := c.executeWithConnectionOrFallback("GET", "/db/api/getschema", nil, true)
 	if err != nil {
 		return []orm.SchemaStruct{}
@@ -113,12 +182,27 @@ func (c *Client) GetSchema(hideSQL bool, hideSureSQL bool) []orm.SchemaStruct {
 	return schemaItems
 }
 
-// Status returns the database status with connection pooling
+// Status returns the database status with connection pooling. The status is reused for
+// StatusCacheTTL (so Leader and Peers right after it don't call the server again), a leader
+// change or a topology refresh discards it. Use ForceStatus for a fresh one.
 func (c *Client) Status() (orm.NodeStatusStruct, error) {
+	if status, ok := c.cachedStatus(); ok {
+		return status, nil
+	}
+	return c.ForceStatus()
+}
+
+// ForceStatus fetches the database status from the server, bypassing the status cache
+func (c *Client) ForceStatus() (orm.NodeStatusStruct, error) {
 	// If we already have a connection, use it
 	// conn := c.getAnyConnection()
 	fmt.Println("Calling status")
-	return sendRequest[orm.NodeStatusStruct](c, "GET", "/db/api/status", nil, IS_READ, NO_REFRESH, FALLBACK_LEADER)
+	status, err := sendRequest[orm.NodeStatusStruct](c, "GET", "/db/api/status", nil, RequestTypeOther, NO_REFRESH, FALLBACK_LEADER)
+	if err == nil {
+		c.trackLeader(&status)
+		c.cacheStatus(status)
+	}
+	return status, err
 	// if conn != nil {
 	// 	// Use the connection
 	// 	data, err := c.sendConnectionRequest(conn, "GET", "/db/api/status", nil, true)
@@ -146,9 +230,9 @@ func (c *Client) Status() (orm.NodeStatusStruct, error) {
 
 // New helper method to get status without using the existing connections
 // or acquiring the mutex lock
-func (c *Client) getStatusWithoutLock() (orm.NodeStatusStruct, error) {
+func (c *Client) getStatusWithoutLock(ctx context.Context) (orm.NodeStatusStruct, error) {
 	// Use direct request to get status
-	data, err := c.sendRequestToLeader("GET", "/db/api/status", nil, WITH_TOKEN, NO_REFRESH)
+	data, err := c.sendRequestToLeader(ctx, "GET", "/db/api/status", nil, WITH_TOKEN, NO_REFRESH)
 	if err != nil {
 		return orm.NodeStatusStruct{}, err
 	}
@@ -174,7 +258,7 @@ func (c *Client) SelectOne(tableName string) (orm.DBRecord, error) {
 		SingleRow: true,
 	}
 
-	response, err := sendRequest[suresql.QueryResponse](c, "POST", "/db/api/query", req, IS_READ, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.QueryResponse](c, "POST", "/db/api/query", req, RequestTypeQuery, AUTO_REFRESH, FALLBACK_LEADER)
 	// response, err := c.executeReadQueryRequest("/db/api/query", req)
 	if err != nil {
 		return orm.DBRecord{}, err
@@ -187,15 +271,18 @@ func (c *Client) SelectOne(tableName string) (orm.DBRecord, error) {
 	return response.Records[0], nil
 }
 
-// SelectMany selects multiple records from the table
+// SelectMany selects multiple records from the table, capped by DefaultQueryLimit if set
 func (c *Client) SelectMany(tableName string) (orm.DBRecords, error) {
+	if c.hot().defaultQueryLimit > 0 {
+		return c.SelectManyWithCondition(tableName, nil)
+	}
 	req := &suresql.QueryRequest{
 		Table:     tableName,
 		SingleRow: false,
 	}
 
 	// response, err := c.executeReadQueryRequest("/db/api/query", req)
-	response, err := sendRequest[suresql.QueryResponse](c, "POST", "/db/api/query", req, IS_READ, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.QueryResponse](c, "POST", "/db/api/query", req, RequestTypeQuery, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return nil, err
 	}
@@ -209,6 +296,19 @@ func (c *Client) SelectMany(tableName string) (orm.DBRecords, error) {
 
 // SelectOneWithCondition selects a single record with a condition
 func (c *Client) SelectOneWithCondition(tableName string, condition *orm.Condition) (orm.DBRecord, error) {
+	if err := ValidateCondition(condition); err != nil {
+		return orm.DBRecord{}, err
+	}
+	if needsClientSQL(condition) {
+		// only the first row is needed, copy so the caller's condition keeps its Limit
+		single := *condition
+		single.Limit = 1
+		records, err := c.selectWithClientCondition(tableName, &single)
+		if err != nil {
+			return orm.DBRecord{}, err
+		}
+		return records[0], nil
+	}
 	req := &suresql.QueryRequest{
 		Table:     tableName,
 		Condition: condition,
@@ -216,7 +316,7 @@ func (c *Client) SelectOneWithCondition(tableName string, condition *orm.Conditi
 	}
 
 	// response, err := c.executeReadQueryRequest("/db/api/query", req)
-	response, err := sendRequest[suresql.QueryResponse](c, "POST", "/db/api/query", req, IS_READ, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.QueryResponse](c, "POST", "/db/api/query", req, RequestTypeQuery, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return orm.DBRecord{}, err
 	}
@@ -227,8 +327,16 @@ func (c *Client) SelectOneWithCondition(tableName string, condition *orm.Conditi
 	return response.Records[0], nil
 }
 
-// SelectManyWithCondition selects multiple records with a condition
+// SelectManyWithCondition selects multiple records with a condition. Without a condition Limit,
+// DefaultQueryLimit is applied if set (see Unlimited).
 func (c *Client) SelectManyWithCondition(tableName string, condition *orm.Condition) ([]orm.DBRecord, error) {
+	if err := ValidateCondition(condition); err != nil {
+		return nil, err
+	}
+	condition = c.applyDefaultLimit(condition)
+	if needsClientSQL(condition) {
+		return c.selectWithClientCondition(tableName, condition)
+	}
 	req := &suresql.QueryRequest{
 		Table:     tableName,
 		Condition: condition,
@@ -236,7 +344,7 @@ func (c *Client) SelectManyWithCondition(tableName string, condition *orm.Condit
 	}
 
 	// response, err := c.executeReadQueryRequest("/db/api/query", req)
-	response, err := sendRequest[suresql.QueryResponse](c, "POST", "/db/api/query", req, IS_READ, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.QueryResponse](c, "POST", "/db/api/query", req, RequestTypeQuery, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return nil, err
 	}
@@ -247,6 +355,261 @@ func (c *Client) SelectManyWithCondition(tableName string, condition *orm.Condit
 	return response.Records, nil
 }
 
+// SelectManyWithConditionFields is SelectManyWithCondition returning only the given columns, so wide
+// tables don't transfer the columns that are not needed. Empty fields selects all the columns (same as
+// SelectManyWithCondition). Fields must be column names, optionally qualified (ie: "users.name"),
+// anything else fails with ErrInvalidField. The SELECT is built on the client, see ConditionToWhere.
+// Usage:
+//
+//	records, err := db.SelectManyWithConditionFields("users", condition, []string{"id", "email"})
+func (c *Client) SelectManyWithConditionFields(tableName string, condition *orm.Condition, fields []string) ([]orm.DBRecord, error) {
+	if len(fields) == 0 {
+		return c.SelectManyWithCondition(tableName, condition)
+	}
+	if err := validateFields(fields); err != nil {
+		return nil, err
+	}
+	if err := ValidateCondition(condition); err != nil {
+		return nil, err
+	}
+	paramSQL, err := conditionToSelectFields(tableName, c.applyDefaultLimit(condition), fields)
+	if err != nil {
+		return nil, err
+	}
+	return c.SelectOneSQLParameterized(paramSQL)
+}
+
+// UNLIMITED as condition Limit opts out of DefaultQueryLimit, see Unlimited
+const UNLIMITED = -1
+
+// Unlimited returns a copy of condition (nil for all rows) that is not capped by DefaultQueryLimit
+// Usage:
+//
+//	records, err := db.SelectManyWithCondition("logs", client.Unlimited(nil))
+func Unlimited(condition *orm.Condition) *orm.Condition {
+	unlimited := orm.Condition{}
+	if condition != nil {
+		unlimited = *condition
+	}
+	unlimited.Limit = UNLIMITED
+	return &unlimited
+}
+
+// applyDefaultLimit adds DefaultQueryLimit to a condition without Limit, and turns UNLIMITED back
+// into no limit. The caller's condition is not modified.
+func (c *Client) applyDefaultLimit(condition *orm.Condition) *orm.Condition {
+	limit := c.hot().defaultQueryLimit
+	switch {
+	case condition != nil && condition.Limit == UNLIMITED:
+		unlimited := *condition
+		unlimited.Limit = 0
+		if unlimited.Field == "" && len(unlimited.Nested) == 0 && len(unlimited.OrderBy) == 0 && len(unlimited.GroupBy) == 0 && unlimited.Offset == 0 {
+			return nil
+		}
+		return &unlimited
+	case limit <= 0:
+		return condition
+	case condition == nil:
+		return &orm.Condition{Limit: limit}
+	case condition.Limit > 0:
+		return condition
+	}
+	limited := *condition
+	limited.Limit = limit
+	return &limited
+}
+
+// selectWithClientCondition builds the SELECT on the client (see ConditionToWhere) and runs it as
+// parameterized SQL, used for operators the server's condition translation doesn't support
+func (c *Client) selectWithClientCondition(tableName string, condition *orm.Condition) ([]orm.DBRecord, error) {
+	paramSQL, err := conditionToSelect(tableName, condition)
+	if err != nil {
+		return nil, err
+	}
+	return c.SelectOneSQLParameterized(paramSQL)
+}
+
+// DistinctValues returns the distinct values of a column, ordered by the column, for rows matching
+// the condition (nil for all rows). A condition Limit caps the number of values returned, the
+// rest of the condition (OrderBy, GroupBy, Offset) is ignored. Returns an empty slice when nothing matches.
+// The table and column must be plain (optionally qualified) names, ErrInvalidField otherwise.
+// Usage:
+//
+//	roles, err := db.DistinctValues("users", "role", &orm.Condition{Field: "active", Operator: "=", Value: true, Limit: 100})
+func (c *Client) DistinctValues(tableName, column string, condition *orm.Condition) ([]interface{}, error) {
+	if err := validateFields([]string{tableName, column}); err != nil {
+		return nil, err
+	}
+	whereClause, values, err := ConditionToWhere(condition)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT DISTINCT %s AS distinct_value FROM %s", column, tableName)
+	if strings.TrimSpace(whereClause) != "" {
+		query += " WHERE " + whereClause
+	}
+	query += " ORDER BY distinct_value"
+	if condition != nil && condition.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", condition.Limit)
+	}
+
+	records, err := c.SelectOneSQLParameterized(orm.ParametereizedSQL{Query: query, Values: values})
+	if err != nil {
+		if errors.Is(err, orm.ErrSQLNoRows) {
+			return []interface{}{}, nil
+		}
+		return nil, err
+	}
+	result := make([]interface{}, 0, len(records))
+	for _, rec := range records {
+		result = append(result, rec.Data["distinct_value"])
+	}
+	return result, nil
+}
+
+// FindByID selects a single record by its primary key. The primary key column is "id"
+// unless configured with WithPrimaryKeyColumn. Returns orm.ErrSQLNoRows if not found.
+func (c *Client) FindByID(tableName string, id interface{}) (orm.DBRecord, error) {
+	condition := &orm.Condition{
+		Field:    c.Config.PrimaryKeyColumn,
+		Operator: "=",
+		Value:    id,
+	}
+	return c.SelectOneWithCondition(tableName, condition)
+}
+
+// FindByIDs selects the records of many primary keys with WHERE id IN (...) and returns them keyed
+// by the given ids, ids that are not found are left out. The ids are matched by their text value,
+// so an int id finds the row even though JSON numbers are decoded as float64. Big id lists are split
+// in chunks of DEFAULT_MAX_SQL_PARAMETERS, all sent in one request.
+// Usage:
+//
+//	users, err := db.FindByIDs("users", []interface{}{1, 2, 3})
+//	user, found := users[2]
+func (c *Client) FindByIDs(tableName string, ids []interface{}) (map[interface{}]orm.DBRecord, error) {
+	result := make(map[interface{}]orm.DBRecord, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	wanted := make(map[string]interface{}, len(ids))
+	var paramSQLs []orm.ParametereizedSQL
+	for start := 0; start < len(ids); start += DEFAULT_MAX_SQL_PARAMETERS {
+		chunk := ids[start:min(start+DEFAULT_MAX_SQL_PARAMETERS, len(ids))]
+		for _, id := range chunk {
+			wanted[idKey(id)] = id
+		}
+		paramSQL, err := conditionToSelect(tableName, &orm.Condition{Field: c.Config.PrimaryKeyColumn, Operator: "IN", Value: chunk})
+		if err != nil {
+			return nil, err
+		}
+		paramSQLs = append(paramSQLs, paramSQL)
+	}
+
+	allRecords, err := c.SelectManySQLParameterized(paramSQLs)
+	if err != nil {
+		if errors.Is(err, orm.ErrSQLNoRows) {
+			return result, nil
+		}
+		return nil, err
+	}
+	for _, records := range allRecords {
+		for _, rec := range records {
+			if id, exists := wanted[idKey(rec.Data[c.Config.PrimaryKeyColumn])]; exists {
+				result[id] = rec
+			}
+		}
+	}
+	return result, nil
+}
+
+// SelectManyWithConditionMap selects multiple records with a condition and returns them keyed by
+// the value of keyColumn. Values are keyed as decoded from JSON, so numbers are float64.
+// Two records with the same key return ErrDuplicateKey, unless keepLast is KEEP_LAST, then the
+// later record (in query order) wins. No records is an empty map, not orm.ErrSQLNoRows.
+// Usage:
+//
+//	users, err := db.SelectManyWithConditionMap("users", "username", nil)
+//	alice := users["alice"]
+func (c *Client) SelectManyWithConditionMap(tableName, keyColumn string, condition *orm.Condition, keepLast ...bool) (map[interface{}]orm.DBRecord, error) {
+	records, err := c.SelectManyWithCondition(tableName, condition)
+	if err != nil {
+		if errors.Is(err, orm.ErrSQLNoRows) {
+			return map[interface{}]orm.DBRecord{}, nil
+		}
+		return nil, err
+	}
+	return recordsByKey(records, keyColumn, len(keepLast) > 0 && keepLast[0])
+}
+
+// recordsByKey indexes records by the value of keyColumn
+func recordsByKey(records []orm.DBRecord, keyColumn string, keepLast bool) (map[interface{}]orm.DBRecord, error) {
+	result := make(map[interface{}]orm.DBRecord, len(records))
+	for _, rec := range records {
+		key, exists := rec.Data[keyColumn]
+		if !exists {
+			return nil, fmt.Errorf("key column %s not found in record", keyColumn)
+		}
+		if _, duplicate := result[key]; duplicate && !keepLast {
+			return nil, fmt.Errorf("%w: %s=%v", ErrDuplicateKey, keyColumn, key)
+		}
+		result[key] = rec
+	}
+	return result, nil
+}
+
+// idKey returns the text value of an id, whole floats are written without exponent (1e+06 -> 1000000)
+func idKey(id interface{}) string {
+	if f, ok := id.(float64); ok && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return fmt.Sprint(id)
+}
+
+// SelectManyWhereEq selects multiple records where field = value. More field/value pairs can be
+// given and are joined with AND. Returns orm.ErrSQLNoRows if nothing matches.
+// Usage:
+//
+//	records, err := db.SelectManyWhereEq("users", "active", true, "role", "admin")
+func (c *Client) SelectManyWhereEq(tableName string, field string, value interface{}, more ...interface{}) ([]orm.DBRecord, error) {
+	condition, err := whereEqCondition(field, value, more)
+	if err != nil {
+		return nil, err
+	}
+	return c.SelectManyWithCondition(tableName, condition)
+}
+
+// SelectOneWhereEq selects a single record where field = value, see SelectManyWhereEq
+func (c *Client) SelectOneWhereEq(tableName string, field string, value interface{}, more ...interface{}) (orm.DBRecord, error) {
+	condition, err := whereEqCondition(field, value, more)
+	if err != nil {
+		return orm.DBRecord{}, err
+	}
+	return c.SelectOneWithCondition(tableName, condition)
+}
+
+// whereEqCondition builds field = value [AND field = value ...] from the field/value pairs
+func whereEqCondition(field string, value interface{}, more []interface{}) (*orm.Condition, error) {
+	if len(more)%2 != 0 {
+		return nil, errors.New("field/value pairs must come in pairs")
+	}
+	first := orm.Condition{Field: field, Operator: "=", Value: value}
+	if len(more) == 0 {
+		return &first, nil
+	}
+
+	nested := []orm.Condition{first}
+	for i := 0; i < len(more); i += 2 {
+		name, ok := more[i].(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("field name at position %d must be a non-empty string", i)
+		}
+		nested = append(nested, orm.Condition{Field: name, Operator: "=", Value: more[i+1]})
+	}
+	return &orm.Condition{Logic: "AND", Nested: nested}, nil
+}
+
 //------------------------------------------------------------------
 // ORM SQL QUERY METHODS
 //------------------------------------------------------------------
@@ -259,7 +622,7 @@ func (c *Client) SelectOneSQL(sql string) (orm.DBRecords, error) {
 	}
 
 	// response, err := c.executeReadSQLQueryRequest("/db/api/querysql", req)
-	response, err := sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, IS_READ, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, RequestTypeSQLQuery, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return nil, err
 	}
@@ -278,7 +641,7 @@ func (c *Client) SelectManySQL(sqlStatements []string) ([]orm.DBRecords, error)
 	}
 
 	// response, err := c.executeReadSQLQueryRequest("/db/api/querysql", req)
-	response, err := sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, IS_READ, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, RequestTypeSQLQuery, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return nil, err
 	}
@@ -303,7 +666,7 @@ func (c *Client) SelectOnlyOneSQL(sql string) (orm.DBRecord, error) {
 	}
 
 	// response, err := c.executeReadSQLQueryRequest("/db/api/querysql", req)
-	response, err := sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, IS_READ, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, RequestTypeSQLQuery, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return orm.DBRecord{}, err
 	}
@@ -327,7 +690,7 @@ func (c *Client) SelectOneSQLParameterized(paramSQL orm.ParametereizedSQL) (orm.
 	}
 
 	// response, err := c.executeReadSQLQueryRequest("/db/api/querysql", req)
-	response, err := sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, IS_READ, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, RequestTypeSQLQuery, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return nil, err
 	}
@@ -346,7 +709,7 @@ func (c *Client) SelectManySQLParameterized(paramSQLs []orm.ParametereizedSQL) (
 	}
 
 	// response, err := c.executeReadSQLQueryRequest("/db/api/querysql", req)
-	response, err := sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, IS_READ, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, RequestTypeSQLQuery, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return nil, err
 	}
@@ -371,7 +734,7 @@ func (c *Client) SelectOnlyOneSQLParameterized(paramSQL orm.ParametereizedSQL) (
 	}
 
 	// response, err := c.executeReadSQLQueryRequest("/db/api/querysql", req)
-	response, err := sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, IS_READ, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, RequestTypeSQLQuery, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return orm.DBRecord{}, err
 	}
@@ -398,7 +761,7 @@ func (c *Client) ExecOneSQL(sql string) orm.BasicSQLResult {
 	}
 
 	// response, err := c.executeWriteSQLRequest("/db/api/sql", req)
-	response, err := sendRequest[suresql.SQLResponse](c, "POST", "/db/api/sql", req, IS_WRITE, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.SQLResponse](c, "POST", "/db/api/sql", req, RequestTypeSQLExec, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return orm.BasicSQLResult{Error: err}
 	}
@@ -417,7 +780,7 @@ func (c *Client) ExecOneSQLParameterized(paramSQL orm.ParametereizedSQL) orm.Bas
 	}
 
 	// response, err := c.executeWriteSQLRequest("/db/api/sql", req)
-	response, err := sendRequest[suresql.SQLResponse](c, "POST", "/db/api/sql", req, IS_WRITE, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.SQLResponse](c, "POST", "/db/api/sql", req, RequestTypeSQLExec, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return orm.BasicSQLResult{Error: err}
 	}
@@ -436,7 +799,7 @@ func (c *Client) ExecManySQL(sqlStatements []string) ([]orm.BasicSQLResult, erro
 	}
 
 	// response, err := c.executeWriteSQLRequest("/db/api/sql", req)
-	response, err := sendRequest[suresql.SQLResponse](c, "POST", "/db/api/sql", req, IS_WRITE, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.SQLResponse](c, "POST", "/db/api/sql", req, RequestTypeSQLExec, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return nil, err
 	}
@@ -455,7 +818,7 @@ func (c *Client) ExecManySQLParameterized(paramSQLs []orm.ParametereizedSQL) ([]
 	}
 
 	// response, err := c.executeWriteSQLRequest("/db/api/sql", req)
-	response, err := sendRequest[suresql.SQLResponse](c, "POST", "/db/api/sql", req, IS_WRITE, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.SQLResponse](c, "POST", "/db/api/sql", req, RequestTypeSQLExec, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return nil, err
 	}
@@ -480,7 +843,7 @@ func (c *Client) InsertOneDBRecord(record orm.DBRecord, queue bool) orm.BasicSQL
 	}
 
 	// response, err := c.executeWriteSQLRequest("/db/api/insert", req)
-	response, err := sendRequest[suresql.SQLResponse](c, "POST", "/db/api/insert", req, IS_WRITE, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.SQLResponse](c, "POST", "/db/api/insert", req, RequestTypeInsert, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return orm.BasicSQLResult{Error: err}
 	}
@@ -501,7 +864,7 @@ func (c *Client) InsertManyDBRecords(records []orm.DBRecord, queue bool) ([]orm.
 	}
 
 	// response, err := c.executeWriteSQLRequest("/db/api/insert", req)
-	response, err := sendRequest[suresql.SQLResponse](c, "POST", "/db/api/insert", req, IS_WRITE, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequest[suresql.SQLResponse](c, "POST", "/db/api/insert", req, RequestTypeInsert, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return nil, err
 	}
@@ -515,6 +878,11 @@ func (c *Client) InsertManyDBRecords(records []orm.DBRecord, queue bool) ([]orm.
 
 // InsertManyDBRecordsSameTable inserts multiple records in the same table
 func (c *Client) InsertManyDBRecordsSameTable(records []orm.DBRecord, queue bool) ([]orm.BasicSQLResult, error) {
+	return c.insertManySameTable(context.Background(), records, queue)
+}
+
+// insertManySameTable is InsertManyDBRecordsSameTable with a context
+func (c *Client) insertManySameTable(ctx context.Context, records []orm.DBRecord, queue bool) ([]orm.BasicSQLResult, error) {
 	req := &suresql.InsertRequest{
 		Records:   records,
 		Queue:     queue,
@@ -522,7 +890,7 @@ func (c *Client) InsertManyDBRecordsSameTable(records []orm.DBRecord, queue bool
 	}
 
 	// response, err := c.executeWriteSQLRequest("/db/api/insert", req)
-	response, err := sendRequest[suresql.SQLResponse](c, "POST", "/db/api/insert", req, IS_WRITE, AUTO_REFRESH, FALLBACK_LEADER)
+	response, err := sendRequestContext[suresql.SQLResponse](ctx, c, "POST", "/db/api/insert", req, RequestTypeInsert, AUTO_REFRESH, FALLBACK_LEADER)
 	if err != nil {
 		return nil, err
 	}
@@ -559,6 +927,427 @@ func (c *Client) InsertManyTableStructs(records []orm.TableStruct, queue bool) (
 	return c.InsertManyDBRecords(dbRecords, queue)
 }
 
+// UpdateTableStruct updates the rows matching condition with the fields of record. Columns use the
+// same json/db tags as InsertOneTableStruct. With onlyNonZero, zero-valued fields (0, "", false,
+// zero time, nil pointers, empty slices/maps) are left out of the SET clause, so they don't overwrite
+// the existing values (like a PATCH). A condition is required to avoid updating the whole table.
+func (c *Client) UpdateTableStruct(record orm.TableStruct, condition *orm.Condition, onlyNonZero bool) orm.BasicSQLResult {
+	var data map[string]interface{}
+	if onlyNonZero {
+		dbRecord, err := orm.TableStructToDBRecord(record)
+		if err != nil {
+			return orm.BasicSQLResult{Error: err}
+		}
+		data = dbRecord.Data
+		// TableStructToDBRecord keeps false booleans
+		for col, val := range data {
+			if b, ok := val.(bool); ok && !b {
+				delete(data, col)
+			}
+		}
+	} else {
+		data = object.StructToMapWithOptions(record, object.MapOptions{SkipNilPointers: true, TimeFormat: time.RFC3339})
+	}
+
+	paramSQL, err := updateParameterized(record.TableName(), data, condition)
+	if err != nil {
+		return orm.BasicSQLResult{Error: err}
+	}
+	return c.ExecOneSQLParameterized(paramSQL)
+}
+
+// updateParameterized builds UPDATE table SET col = ?, ... WHERE condition, columns are sorted
+// so the generated SQL is deterministic
+func updateParameterized(tableName string, data map[string]interface{}, condition *orm.Condition) (orm.ParametereizedSQL, error) {
+	if len(data) == 0 {
+		return orm.ParametereizedSQL{}, errors.New("no columns to update")
+	}
+	if condition == nil {
+		return orm.ParametereizedSQL{}, errors.New("update requires a condition")
+	}
+	whereClause, whereValues, err := ConditionToWhere(condition)
+	if err != nil {
+		return orm.ParametereizedSQL{}, err
+	}
+	if strings.TrimSpace(whereClause) == "" {
+		return orm.ParametereizedSQL{}, errors.New("update requires a condition")
+	}
+
+	columns := make([]string, 0, len(data))
+	for col := range data {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+	sets := make([]string, 0, len(columns))
+	values := make([]interface{}, 0, len(columns)+len(whereValues))
+	for _, col := range columns {
+		sets = append(sets, col+" = ?")
+		values = append(values, data[col])
+	}
+	values = append(values, whereValues...)
+
+	return orm.ParametereizedSQL{
+		Query:  fmt.Sprintf("UPDATE %s SET %s WHERE %s", tableName, strings.Join(sets, ", "), whereClause),
+		Values: values,
+	}, nil
+}
+
+// UpsertManyTableStructs inserts the records, or updates the existing row when one of them conflicts
+// on conflictColumns (which need a UNIQUE index or the primary key), with INSERT ... ON CONFLICT
+// (conflictColumns) DO UPDATE SET col = excluded.col for every updateColumns. Empty updateColumns
+// updates all the columns that are not conflict columns (DO NOTHING if there are none left).
+// Every record is its own statement so records of different tables can be mixed, they are sent
+// DEFAULT_UPSERT_BATCH_SIZE statements per request and the results are in the same order as records.
+// If a request fails the results of the previous ones are returned with the error, so the caller can
+// resume with records[len(results):]. Running it again with the same records is safe.
+// Usage:
+//
+//	results, err := db.UpsertManyTableStructs(users, []string{"email"}, []string{"name", "updated_at"})
+func (c *Client) UpsertManyTableStructs(records []orm.TableStruct, conflictColumns []string, updateColumns []string) ([]orm.BasicSQLResult, error) {
+	if len(conflictColumns) == 0 {
+		return nil, errors.New("upsert requires conflict columns")
+	}
+	paramSQLs := make([]orm.ParametereizedSQL, 0, len(records))
+	for i, record := range records {
+		dbRecord, err := orm.TableStructToDBRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		paramSQL, err := upsertParameterized(dbRecord, conflictColumns, updateColumns)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		paramSQLs = append(paramSQLs, paramSQL)
+	}
+
+	results := make([]orm.BasicSQLResult, 0, len(paramSQLs))
+	for start := 0; start < len(paramSQLs); start += DEFAULT_UPSERT_BATCH_SIZE {
+		batch := paramSQLs[start:min(start+DEFAULT_UPSERT_BATCH_SIZE, len(paramSQLs))]
+		batchResults, err := c.ExecManySQLParameterized(batch)
+		if err == nil && len(batchResults) != len(batch) {
+			err = fmt.Errorf("expected %d results, got %d", len(batch), len(batchResults))
+		}
+		if err != nil {
+			return results, fmt.Errorf("upsert stopped after %d of %d records: %w", start, len(paramSQLs), err)
+		}
+		results = append(results, batchResults...)
+	}
+	return results, nil
+}
+
+// upsertParameterized builds INSERT INTO table (cols) VALUES (?, ...) ON CONFLICT (conflictColumns)
+// DO UPDATE SET col = excluded.col, ..., columns are sorted so the generated SQL is deterministic
+func upsertParameterized(record orm.DBRecord, conflictColumns []string, updateColumns []string) (orm.ParametereizedSQL, error) {
+	if record.TableName == "" || len(record.Data) == 0 {
+		return orm.ParametereizedSQL{}, errors.New("table name and data are required")
+	}
+	conflict := make(map[string]bool, len(conflictColumns))
+	for _, col := range conflictColumns {
+		if _, exists := record.Data[col]; !exists {
+			return orm.ParametereizedSQL{}, fmt.Errorf("conflict column %s is not in the %s record", col, record.TableName)
+		}
+		conflict[col] = true
+	}
+
+	columns := make([]string, 0, len(record.Data))
+	for col := range record.Data {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+	values := make([]interface{}, 0, len(columns))
+	for _, col := range columns {
+		values = append(values, record.Data[col])
+	}
+
+	if len(updateColumns) == 0 {
+		for _, col := range columns {
+			if !conflict[col] {
+				updateColumns = append(updateColumns, col)
+			}
+		}
+	}
+	action := "DO NOTHING"
+	if len(updateColumns) > 0 {
+		sets := make([]string, 0, len(updateColumns))
+		for _, col := range updateColumns {
+			if _, exists := record.Data[col]; !exists {
+				return orm.ParametereizedSQL{}, fmt.Errorf("update column %s is not in the %s record", col, record.TableName)
+			}
+			sets = append(sets, col+" = excluded."+col)
+		}
+		action = "DO UPDATE SET " + strings.Join(sets, ", ")
+	}
+
+	return orm.ParametereizedSQL{
+		Query: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) %s", record.TableName, strings.Join(columns, ", "),
+			strings.TrimSuffix(strings.Repeat("?,", len(columns)), ","), strings.Join(conflictColumns, ", "), action),
+		Values: values,
+	}, nil
+}
+
+// DeleteWithCondition deletes the rows matching condition. A condition is required to avoid
+// deleting the whole table.
+func (c *Client) DeleteWithCondition(tableName string, condition *orm.Condition) orm.BasicSQLResult {
+	whereClause, values, err := ConditionToWhere(condition)
+	if err != nil {
+		return orm.BasicSQLResult{Error: err}
+	}
+	if strings.TrimSpace(whereClause) == "" {
+		return orm.BasicSQLResult{Error: errors.New("delete requires a condition")}
+	}
+	return c.ExecOneSQLParameterized(orm.ParametereizedSQL{
+		Query:  fmt.Sprintf("DELETE FROM %s WHERE %s", tableName, whereClause),
+		Values: values,
+	})
+}
+
+// DeleteReturning deletes the rows matching condition and returns them (ie: for audit logging), using
+// DELETE ... RETURNING. Empty returning means all columns, the columns must be column names (see
+// ErrInvalidField). A condition is required to avoid deleting the whole table, its OrderBy, Limit
+// and Offset are ignored. Returns an empty slice when nothing matched.
+// If the server's SQLite does not support RETURNING (before 3.35) the matching rows are selected on
+// the leader with their rowid first, then only those rows are deleted, so a row inserted in between
+// is never deleted without being returned. The two requests are not a transaction: if a selected row
+// was deleted or changed by someone else in between, the selected rows are returned with an error.
+func (c *Client) DeleteReturning(tableName string, condition *orm.Condition, returning []string) ([]orm.DBRecord, error) {
+	if err := validateFields(returning); err != nil {
+		return nil, err
+	}
+	whereClause, values, err := ConditionToWhere(condition)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(whereClause) == "" {
+		return nil, errors.New("delete requires a condition")
+	}
+	returningCols := "*"
+	if len(returning) > 0 {
+		returningCols = strings.Join(returning, ", ")
+	}
+
+	req := &suresql.SQLRequest{ParamSQL: []orm.ParametereizedSQL{{
+		Query:  fmt.Sprintf("DELETE FROM %s WHERE %s RETURNING %s", tableName, whereClause, returningCols),
+		Values: values,
+	}}}
+	// The delete goes to the write pool (leader) even though it returns rows
+	response, err := sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, RequestTypeSQLWriteQuery, AUTO_REFRESH, FALLBACK_LEADER)
+	if err != nil {
+		if !isReturningUnsupported(err) {
+			return nil, err
+		}
+		// Older SQLite without RETURNING, nothing was deleted
+		return c.deleteSelectedRows(tableName, whereClause, values, returningCols)
+	}
+	if len(response) == 0 {
+		return []orm.DBRecord{}, nil
+	}
+	records := response[0].Records
+	for i := range records {
+		if records[i].TableName == "" {
+			records[i].TableName = tableName
+		}
+	}
+	return records, nil
+}
+
+// deleteSelectedRows is the DeleteReturning fallback without RETURNING: it selects the matching rows
+// and their rowid on the leader, then deletes those rowids (if they still match the condition)
+func (c *Client) deleteSelectedRows(tableName, whereClause string, values []interface{}, returningCols string) ([]orm.DBRecord, error) {
+	const rowIDColumn = "_suresql_rowid"
+	selectSQL := orm.ParametereizedSQL{
+		Query:  fmt.Sprintf("SELECT rowid AS %s, %s FROM %s WHERE %s", rowIDColumn, returningCols, tableName, whereClause),
+		Values: values,
+	}
+	// read through the write pool, a follower could be behind the delete
+	req := &suresql.SQLRequest{ParamSQL: []orm.ParametereizedSQL{selectSQL}}
+	response, err := sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, RequestTypeSQLWriteQuery, AUTO_REFRESH, FALLBACK_LEADER)
+	if err != nil {
+		return nil, err
+	}
+	if len(response) == 0 || len(response[0].Records) == 0 {
+		return []orm.DBRecord{}, nil
+	}
+	records := response[0].Records
+	rowIDs := make([]interface{}, 0, len(records))
+	for i := range records {
+		rowIDs = append(rowIDs, records[i].Data[rowIDColumn])
+		delete(records[i].Data, rowIDColumn)
+		if records[i].TableName == "" {
+			records[i].TableName = tableName
+		}
+	}
+
+	// the condition is repeated so a row changed since the SELECT is not deleted
+	chunkSize := DEFAULT_MAX_SQL_PARAMETERS - len(values)
+	if chunkSize < 1 {
+		return nil, fmt.Errorf("condition has too many values (%d) to delete by rowid", len(values))
+	}
+	var paramSQLs []orm.ParametereizedSQL
+	for start := 0; start < len(rowIDs); start += chunkSize {
+		chunk := rowIDs[start:min(start+chunkSize, len(rowIDs))]
+		paramSQLs = append(paramSQLs, orm.ParametereizedSQL{
+			Query: fmt.Sprintf("DELETE FROM %s WHERE rowid IN (%s) AND (%s)", tableName,
+				strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ","), whereClause),
+			Values: append(append([]interface{}{}, chunk...), values...),
+		})
+	}
+	results, err := c.ExecManySQLParameterized(paramSQLs)
+	if err != nil {
+		return nil, err
+	}
+	var deleted int64
+	for _, res := range results {
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		deleted += int64(res.RowsAffected)
+	}
+	if deleted != int64(len(records)) {
+		return records, fmt.Errorf("deleted %d of the %d selected rows, the others were deleted or changed in between", deleted, len(records))
+	}
+	return records, nil
+}
+
+// BulkInsert inserts many rows into one table using multi-values INSERT statements
+// (INSERT INTO table (cols) VALUES (?,?),(?,?),...). Rows are chunked so every statement stays
+// under DEFAULT_MAX_SQL_PARAMETERS, and all chunks are sent in a single request.
+// The request is not a transaction: the server runs every chunk, also the ones after a failed chunk.
+// The returned result has the aggregated RowsAffected and Timing of the chunks that succeeded, and
+// its error lists every failed (0 based) chunk.
+func (c *Client) BulkInsert(tableName string, columns []string, rows [][]interface{}) orm.BasicSQLResult {
+	if tableName == "" || len(columns) == 0 {
+		return orm.BasicSQLResult{Error: errors.New("table name and columns are required")}
+	}
+	if len(rows) == 0 {
+		return orm.BasicSQLResult{}
+	}
+	if len(columns) > DEFAULT_MAX_SQL_PARAMETERS {
+		return orm.BasicSQLResult{Error: fmt.Errorf("too many columns (%d), maximum is %d", len(columns), DEFAULT_MAX_SQL_PARAMETERS)}
+	}
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return orm.BasicSQLResult{Error: fmt.Errorf("row %d has %d values, expected %d", i, len(row), len(columns))}
+		}
+	}
+
+	// Prepare the placeholders for a single row: (?,?,?)
+	rowPlaceholder := "(" + strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",") + ")"
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", tableName, strings.Join(columns, ", "))
+	rowsPerChunk := DEFAULT_MAX_SQL_PARAMETERS / len(columns)
+
+	var paramSQLs []orm.ParametereizedSQL
+	for start := 0; start < len(rows); start += rowsPerChunk {
+		end := min(start+rowsPerChunk, len(rows))
+		placeholders := make([]string, 0, end-start)
+		values := make([]interface{}, 0, (end-start)*len(columns))
+		for _, row := range rows[start:end] {
+			placeholders = append(placeholders, rowPlaceholder)
+			values = append(values, row...)
+		}
+		paramSQLs = append(paramSQLs, orm.ParametereizedSQL{
+			Query:  prefix + strings.Join(placeholders, ","),
+			Values: values,
+		})
+	}
+
+	results, err := c.ExecManySQLParameterized(paramSQLs)
+	if err != nil {
+		return orm.BasicSQLResult{Error: err}
+	}
+
+	// Aggregate the results of the chunks that succeeded, and report all failed ones
+	total := orm.BasicSQLResult{}
+	var errs []error
+	for i, res := range results {
+		if res.Error != nil {
+			errs = append(errs, fmt.Errorf("chunk %d: %w", i, res.Error))
+			continue
+		}
+		total.RowsAffected += res.RowsAffected
+		total.Timing += res.Timing
+		total.LastInsertID = res.LastInsertID
+	}
+	if len(errs) > 0 {
+		total.Error = fmt.Errorf("bulk insert: %d of %d chunks failed, %d of %d rows inserted: %w",
+			len(errs), len(results), total.RowsAffected, len(rows), errors.Join(errs...))
+	}
+	return total
+}
+
+// InsertOneReturning inserts a record and returns the inserted row including server generated
+// columns (ie: defaults like created_at or UUIDs), using INSERT ... RETURNING. Empty returning
+// means all columns, otherwise they must be column names (ErrInvalidField). If the server's SQLite
+// does not support RETURNING it falls back to a normal insert followed by a SELECT on the rowid
+// (LastInsertID) on the leader.
+func (c *Client) InsertOneReturning(record orm.DBRecord, returning []string) (orm.DBRecord, error) {
+	if record.TableName == "" || len(record.Data) == 0 {
+		return orm.DBRecord{}, errors.New("table name and data are required")
+	}
+	if err := validateFields(returning); err != nil {
+		return orm.DBRecord{}, err
+	}
+	returningCols := "*"
+	if len(returning) > 0 {
+		returningCols = strings.Join(returning, ", ")
+	}
+
+	// Sort the columns so the generated SQL is deterministic
+	columns := make([]string, 0, len(record.Data))
+	for col := range record.Data {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+	values := make([]interface{}, 0, len(columns))
+	for _, col := range columns {
+		values = append(values, record.Data[col])
+	}
+	paramSQL := orm.ParametereizedSQL{
+		Query: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING %s", record.TableName, strings.Join(columns, ", "),
+			strings.TrimSuffix(strings.Repeat("?,", len(columns)), ","), returningCols),
+		Values: values,
+	}
+	req := &suresql.SQLRequest{
+		ParamSQL:  []orm.ParametereizedSQL{paramSQL},
+		SingleRow: true,
+	}
+
+	// The insert goes to the write pool (leader) even though it returns rows
+	response, err := sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, RequestTypeSQLWriteQuery, AUTO_REFRESH, FALLBACK_LEADER)
+	if err != nil {
+		if !isReturningUnsupported(err) {
+			return orm.DBRecord{}, err
+		}
+		// Older SQLite without RETURNING, the insert did not happen so it's safe to do it again
+		res := c.InsertOneDBRecord(record, false)
+		if res.Error != nil {
+			return orm.DBRecord{}, res.Error
+		}
+		// read through the write pool, a follower may not have the row yet
+		req.ParamSQL = []orm.ParametereizedSQL{{
+			Query:  fmt.Sprintf("SELECT %s FROM %s WHERE rowid = ?", returningCols, record.TableName),
+			Values: []interface{}{res.LastInsertID},
+		}}
+		response, err = sendRequest[suresql.QueryResponseSQL](c, "POST", "/db/api/querysql", req, RequestTypeSQLWriteQuery, AUTO_REFRESH, FALLBACK_LEADER)
+		if err != nil {
+			return orm.DBRecord{}, err
+		}
+	}
+	if len(response) == 0 || len(response[0].Records) == 0 {
+		return orm.DBRecord{}, orm.ErrSQLNoRows
+	}
+	result := response[0].Records[0]
+	if result.TableName == "" {
+		result.TableName = record.TableName
+	}
+	return result, nil
+}
+
+// isReturningUnsupported checks for SQLite's syntax error on RETURNING (before 3.35)
+func isReturningUnsupported(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "returning") && strings.Contains(msg, "syntax error")
+}
+
 //------------------------------------------------------------------
 // STATUS METHODS
 //------------------------------------------------------------------
@@ -594,8 +1383,17 @@ func (c *Client) Peers() ([]string, error) {
 	return peers, nil
 }
 
-// Close properly cleans up resources and closes connections
+// Close properly cleans up resources and closes connections. The client cannot be used (or
+// connected) again, every request returns ErrClientClosed. It is safe to call more than once.
 func (c *Client) Close() {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return
+	}
 	c.CloseConnections()
 	c.Connected = false
 }
+
+// IsClosed returns true once Close was called
+func (c *Client) IsClosed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
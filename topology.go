@@ -0,0 +1,293 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	orm "github.com/medatechnology/simpleorm"
+	"github.com/medatechnology/suresql"
+)
+
+// getStatus returns the cached cluster status, guarded against concurrent topology refresh
+func (c *Client) getStatus() *orm.NodeStatusStruct {
+	c.statusMutex.RLock()
+	defer c.statusMutex.RUnlock()
+	return c.status
+}
+
+// setStatus replaces the cached cluster status
+func (c *Client) setStatus(status *orm.NodeStatusStruct) {
+	c.statusMutex.Lock()
+	c.status = status
+	// the topology changed, Status must not return the older one
+	c.statusCache = nil
+	c.statusMutex.Unlock()
+	c.trackLeader(status)
+}
+
+// statusCacheTTL returns how long the Status response is reused, 0 if the cache is disabled
+func (config *ClientConfig) statusCacheTTL() time.Duration {
+	switch {
+	case config.StatusCacheTTL < 0:
+		return 0
+	case config.StatusCacheTTL == 0:
+		return DEFAULT_STATUS_CACHE_TTL
+	}
+	return config.StatusCacheTTL
+}
+
+// cachedStatus returns the last Status response if it is not older than StatusCacheTTL
+func (c *Client) cachedStatus() (orm.NodeStatusStruct, bool) {
+	c.statusMutex.RLock()
+	defer c.statusMutex.RUnlock()
+	if c.statusCache == nil || time.Since(c.statusCachedAt) >= c.Config.statusCacheTTL() {
+		return orm.NodeStatusStruct{}, false
+	}
+	// callers may modify the peers of their copy
+	return copyStatus(c.statusCache), true
+}
+
+// copyStatus returns a copy of the status that doesn't share its peers map
+func copyStatus(status *orm.NodeStatusStruct) orm.NodeStatusStruct {
+	cp := *status
+	if status.Peers != nil {
+		cp.Peers = make(map[int]orm.StatusStruct, len(status.Peers))
+		for k, peer := range status.Peers {
+			cp.Peers[k] = peer
+		}
+	}
+	return cp
+}
+
+// cacheStatus keeps a Status response for StatusCacheTTL
+func (c *Client) cacheStatus(status orm.NodeStatusStruct) {
+	if c.Config.statusCacheTTL() <= 0 {
+		return
+	}
+	cached := copyStatus(&status)
+	c.statusMutex.Lock()
+	c.statusCache = &cached
+	c.statusCachedAt = time.Now()
+	c.statusMutex.Unlock()
+}
+
+// CurrentLeader returns the leader URL from the last known status, without a network call
+func (c *Client) CurrentLeader() string {
+	c.statusMutex.RLock()
+	defer c.statusMutex.RUnlock()
+	return c.leaderURL
+}
+
+// trackLeader updates the known leader from a status (of any node) and, if it changed,
+// moves the leader flag of pooled connections to the new leader and fires OnLeaderChange
+func (c *Client) trackLeader(status *orm.NodeStatusStruct) {
+	if status == nil {
+		return
+	}
+	newURL, newNodeID := leaderFromStatus(status)
+	if newURL == "" {
+		return
+	}
+
+	c.statusMutex.Lock()
+	oldURL := c.leaderURL
+	changed := oldURL != newURL || c.leaderNodeID != newNodeID
+	c.leaderURL = newURL
+	c.leaderNodeID = newNodeID
+	if changed {
+		c.statusCache = nil
+	}
+	c.statusMutex.Unlock()
+
+	if !changed {
+		return
+	}
+	c.readPool.setLeaderNode(newNodeID)
+	c.writePool.setLeaderNode(newNodeID)
+	// first discovery (on Connect) is not a change
+	if oldURL != "" {
+		fmt.Printf("Topology: leader changed from %s to %s\n", oldURL, newURL)
+		c.recordPoolEvent(POOL_EVENT_LEADER_CHANGE, newNodeID, "%s -> %s", oldURL, newURL)
+		if c.Config.OnLeaderChange != nil {
+			c.Config.OnLeaderChange(oldURL, newURL)
+		}
+	}
+}
+
+// leaderFromStatus returns the leader URL and node ID, the node ID is empty if the leader
+// is not part of the status nodes
+func leaderFromStatus(status *orm.NodeStatusStruct) (string, string) {
+	leaderURL := status.Leader
+	for nodeID, node := range nodesFromStatus(status) {
+		if node.IsLeader || (leaderURL != "" && node.URL == leaderURL) {
+			if leaderURL == "" {
+				leaderURL = node.URL
+			}
+			return leaderURL, nodeID
+		}
+	}
+	return leaderURL, ""
+}
+
+// nodeURL returns the URL the client uses to connect to a node of the cluster status,
+// the advertised URL goes through NodeURLRewriter and LeaderURLOverride replaces it for the leader
+func (c *Client) nodeURL(status *orm.NodeStatusStruct, node orm.StatusStruct) string {
+	isLeader := node.IsLeader || (status.Leader != "" && node.URL == status.Leader)
+	if isLeader && c.Config.LeaderURLOverride != "" {
+		return c.Config.LeaderURLOverride
+	}
+	if c.Config.NodeURLRewriter != nil {
+		if url := c.Config.NodeURLRewriter(node.NodeID, node.URL); url != "" {
+			return url
+		}
+	}
+	return node.URL
+}
+
+// notLeaderMessages are the server messages of a write sent to a follower or read-only node
+var notLeaderMessages = []string{"not leader", "not the leader", "read only node", "read-only node"}
+
+// isNotLeaderError checks for the server errors of a write sent to a follower or read-only node.
+// The server message ends the wrapped error, it must be the whole message: SQLite errors like
+// "attempt to write a readonly database" are not redirected.
+func isNotLeaderError(err error) bool {
+	msg := strings.TrimRight(strings.ToLower(err.Error()), " .")
+	for _, message := range notLeaderMessages {
+		if msg == message || strings.HasSuffix(msg, ": "+message) {
+			return true
+		}
+	}
+	return false
+}
+
+// redirectLeaderConnection refreshes the leader from the cluster status and returns a write
+// connection to it, or the leader (main) connection if the pool has none for that node
+func (c *Client) redirectLeaderConnection(ctx context.Context) *Connection {
+	if status, err := c.getStatusWithoutLock(ctx); err == nil {
+		c.setStatus(&status)
+	}
+	if leaderNodeID := c.leaderNode(); leaderNodeID != "" {
+		if conn, err := c.writePool.GetConnectionForNode(leaderNodeID); err == nil {
+			return conn
+		}
+	}
+	return c.getLeaderConnection()
+}
+
+// leaderNode returns the node ID of the current leader, empty if unknown
+func (c *Client) leaderNode() string {
+	c.statusMutex.RLock()
+	defer c.statusMutex.RUnlock()
+	return c.leaderNodeID
+}
+
+// nodesFromStatus returns all nodes (self and peers) from the status keyed by NodeID
+func nodesFromStatus(status *orm.NodeStatusStruct) map[string]orm.StatusStruct {
+	nodes := make(map[string]orm.StatusStruct)
+	if status == nil {
+		return nodes
+	}
+	nodes[status.NodeID] = status.StatusStruct
+	for _, peer := range status.Peers {
+		nodes[peer.NodeID] = peer
+	}
+	return nodes
+}
+
+// startTopologyRefresh starts the periodic cluster topology refresh routine
+func (c *Client) startTopologyRefresh() {
+	// the routine keeps its own references, CloseConnections clears the fields
+	done := make(chan struct{})
+	timer := time.NewTimer(c.hot().topologyRefreshInterval)
+	c.topologyDone = done
+	c.topologyTimer = timer
+
+	go func() {
+		for {
+			select {
+			case <-timer.C:
+				if err := c.RefreshTopology(); err != nil {
+					fmt.Printf("Warning: failed to refresh cluster topology: %v\n", err)
+				}
+				timer.Reset(c.hot().topologyRefreshInterval)
+			case <-done:
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				return
+			}
+		}
+	}()
+}
+
+// RefreshTopology re-fetches the cluster status, creates pools for newly added nodes
+// and removes the pools of nodes that are no longer part of the cluster
+func (c *Client) RefreshTopology() error {
+	status, err := c.getStatusWithoutLock(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get status for topology refresh: %w", err)
+	}
+
+	oldNodes := nodesFromStatus(c.getStatus())
+	newNodes := nodesFromStatus(&status)
+
+	// Replace the status first so scaleUpNode uses the new MaxPool values
+	c.setStatus(&status)
+
+	// Add pools for new nodes
+	for nodeID, node := range newNodes {
+		if _, exists := oldNodes[nodeID]; exists {
+			continue
+		}
+		fmt.Printf("Topology: node %s=%s joined the cluster\n", nodeID, node.URL)
+		c.recordPoolEvent(POOL_EVENT_NODE_JOINED, nodeID, "%s", node.URL)
+		tmpConn := NewConnection(&c.Config, c.nodeURL(&status, node), node.NodeID, node.Mode, node.IsLeader, suresql.TokenTable{})
+		c.scaleUpNode(context.Background(), tmpConn, IS_WRITE)
+		c.scaleUpNode(context.Background(), tmpConn, IS_READ)
+	}
+
+	// Remove pools for nodes that disappeared, in-flight requests keep their connection
+	for nodeID, node := range oldNodes {
+		if _, exists := newNodes[nodeID]; exists {
+			continue
+		}
+		fmt.Printf("Topology: node %s=%s left the cluster\n", nodeID, node.URL)
+		c.recordPoolEvent(POOL_EVENT_NODE_LEFT, nodeID, "%s", node.URL)
+		c.ForgetNode(nodeID)
+	}
+
+	return nil
+}
+
+// ForgetNode removes a node that permanently left the cluster: its connections in both pools,
+// its HTTP clients (closing their idle TCP connections right away instead of after
+// IdleConnTimeout) and its stats. Requests in flight on the node can still finish.
+// Returns the number of connections removed. RefreshTopology calls it for every node that disappeared.
+func (c *Client) ForgetNode(nodeID string) int {
+	readRemoved, readClients := c.readPool.removeNode(nodeID)
+	writeRemoved, writeClients := c.writePool.removeNode(nodeID)
+
+	// the node client is shared by both pools, close every client once
+	closed := make(map[*http.Client]bool)
+	for _, client := range append(readClients, writeClients...) {
+		if !closed[client] {
+			closed[client] = true
+			client.CloseIdleConnections()
+		}
+	}
+
+	c.scalingMutex.Lock()
+	delete(c.statsPerNodeRead, nodeID)
+	delete(c.statsPerNodeWrite, nodeID)
+	c.scalingMutex.Unlock()
+	c.nodeProtocols.Delete(nodeID)
+	c.tokenStatsPerNode.Delete(nodeID)
+
+	return readRemoved + writeRemoved
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	orm "github.com/medatechnology/simpleorm"
+	"github.com/medatechnology/suresql"
+)
+
+func TestIsNotLeaderError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("request error: not leader"), true},
+		{fmt.Errorf("api-call failed, err: %w", errors.New("request error: Not Leader")), true},
+		{errors.New("request error: read-only node"), true},
+		{errors.New("request error: attempt to write a readonly database"), false},
+		{errors.New("request error: table is read only"), false},
+		{errors.New("request error: UNIQUE constraint failed: users.email"), false},
+	}
+	for _, tt := range tests {
+		if got := isNotLeaderError(tt.err); got != tt.want {
+			t.Errorf("isNotLeaderError(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// waitFor polls cond until it is true or the timeout elapses
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+func TestForgetNodeClosesConnections(t *testing.T) {
+	var open int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":200,"message":"OK","data":{}}`))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			atomic.AddInt64(&open, 1)
+		case http.StateClosed, http.StateHijacked:
+			atomic.AddInt64(&open, -1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+	goroutines := runtime.NumGoroutine()
+
+	c, err := NewClient(NewClientConfig(WithServerURL(server.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient := c.getOrCreateNodeHTTPClient("node1")
+	token := suresql.TokenTable{Token: "token"}
+	readConn := NewConnectionWithClient(&c.Config, server.URL, "node1", "rw", false, token, httpClient)
+	writeConn := NewConnectionWithClient(&c.Config, server.URL, "node1", "rw", false, token, httpClient)
+	multiConn := NewConnection(&c.Config, server.URL, "node1", "rw", false, token) // NodeUseMultiClient connection
+	c.readPool.AddBatch([]*Connection{readConn, multiConn})
+	c.writePool.Add(writeConn)
+
+	// open keep-alive connections to the node
+	for _, conn := range []*Connection{readConn, writeConn, multiConn} {
+		if _, err := c.doRequestToPool(context.Background(), conn, "POST", "/db/api/sql", nil, WITH_TOKEN, AUTO_REFRESH, NO_FALLBACK); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if atomic.LoadInt64(&open) == 0 {
+		t.Fatal("no connection was opened to the node")
+	}
+
+	if removed := c.ForgetNode("node1"); removed != 3 {
+		t.Errorf("ForgetNode removed %d connections, want 3", removed)
+	}
+	for _, pool := range []*ConnectionPool{c.readPool, c.writePool} {
+		pool.mutex.RLock()
+		_, hasClient := pool.nodeHTTPClients["node1"]
+		pool.mutex.RUnlock()
+		if hasClient || pool.SizeForNode("node1") != 0 {
+			t.Errorf("write pool=%v still has the node: client=%v, connections=%d", pool.isWritePool, hasClient, pool.SizeForNode("node1"))
+		}
+	}
+	if !waitFor(2*time.Second, func() bool { return atomic.LoadInt64(&open) == 0 }) {
+		t.Errorf("%d TCP connections to the forgotten node are still open", atomic.LoadInt64(&open))
+	}
+	if !waitFor(2*time.Second, func() bool { return runtime.NumGoroutine() <= goroutines }) {
+		t.Errorf("%d goroutines after ForgetNode, %d before the connections", runtime.NumGoroutine(), goroutines)
+	}
+}
+
+func TestForgetNodeDuringIdleCleanup(t *testing.T) {
+	c := newStubClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return okResponse(req, `{}`), nil
+	}))
+	c.setStatus(&orm.NodeStatusStruct{})
+	c.PoolConfig.MinPoolSize = 0
+	hot := *c.hot()
+	hot.idleTimeout = time.Nanosecond
+	c.settings.Store(&hot)
+
+	// run with -race: the cleanup ranges the node stats while ForgetNode deletes them
+	for round := 0; round < 20; round++ {
+		nodes := []string{"node1", "node2", "node3", "node4"}
+		for _, nodeID := range nodes {
+			c.readPool.Add(NewConnection(&c.Config, "http://"+nodeID+".test", nodeID, "r", false, suresql.TokenTable{}))
+			c.writePool.Add(NewConnection(&c.Config, "http://"+nodeID+".test", nodeID, "rw", false, suresql.TokenTable{}))
+			c.getOrCreateNodeStats(nodeID, IS_READ)
+			c.getOrCreateNodeStats(nodeID, IS_WRITE)
+		}
+		time.Sleep(time.Millisecond)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.cleanupIdleConnections()
+		}()
+		go func() {
+			defer wg.Done()
+			for _, nodeID := range nodes {
+				c.ForgetNode(nodeID)
+			}
+		}()
+		wg.Wait()
+	}
+	if got := len(c.allNodeStats(IS_READ)) + len(c.allNodeStats(IS_WRITE)); got != 0 {
+		t.Errorf("%d node stats left, want the forgotten nodes removed", got)
+	}
+}
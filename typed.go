@@ -0,0 +1,130 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/medatechnology/goutil/object"
+	orm "github.com/medatechnology/simpleorm"
+)
+
+//------------------------------------------------------------------
+// TYPED (GENERIC) QUERY HELPERS
+//------------------------------------------------------------------
+
+// recordsToStructs converts DBRecords into a slice of T using the `db` tags of T
+func recordsToStructs[T any](records []orm.DBRecord) []T {
+	result := make([]T, 0, len(records))
+	for _, rec := range records {
+		result = append(result, object.MapToStructSlowDB[T](rec.Data))
+	}
+	return result
+}
+
+// SelectManyWithConditionInto selects multiple records with a condition and scans them into a slice of T.
+// Fields are matched using the `db` tags of T. When nothing matches it returns an empty (non-nil) slice
+// instead of orm.ErrSQLNoRows, so callers can range over the result directly.
+// Usage:
+//
+//	users, err := client.SelectManyWithConditionInto[UserModel](db, "users", &orm.Condition{Field: "active", Operator: "=", Value: true})
+func SelectManyWithConditionInto[T any](c *Client, tableName string, condition *orm.Condition) ([]T, error) {
+	records, err := c.SelectManyWithCondition(tableName, condition)
+	if err != nil {
+		if errors.Is(err, orm.ErrSQLNoRows) {
+			return []T{}, nil
+		}
+		return nil, err
+	}
+	return recordsToStructs[T](records), nil
+}
+
+// FindByIDInto selects a single record by its primary key and scans it into T.
+// Returns orm.ErrSQLNoRows if not found.
+func FindByIDInto[T any](c *Client, tableName string, id interface{}) (T, error) {
+	record, err := c.FindByID(tableName, id)
+	if err != nil {
+		var empty T
+		return empty, err
+	}
+	return object.MapToStructSlowDB[T](record.Data), nil
+}
+
+// FindByIDsInto is FindByIDs scanning every record into T, keyed by the given ids
+func FindByIDsInto[T any](c *Client, tableName string, ids []interface{}) (map[interface{}]T, error) {
+	records, err := c.FindByIDs(tableName, ids)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[interface{}]T, len(records))
+	for id, rec := range records {
+		result[id] = object.MapToStructSlowDB[T](rec.Data)
+	}
+	return result, nil
+}
+
+// SelectManyWithConditionMapInto is SelectManyWithConditionMap scanning every record into T and
+// converting the keys to K (ie: float64 JSON numbers to int64, anything to string)
+// Usage:
+//
+//	users, err := client.SelectManyWithConditionMapInto[string, UserModel](db, "users", "username", nil)
+func SelectManyWithConditionMapInto[K comparable, T any](c *Client, tableName, keyColumn string, condition *orm.Condition, keepLast ...bool) (map[K]T, error) {
+	records, err := c.SelectManyWithConditionMap(tableName, keyColumn, condition, keepLast...)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[K]T, len(records))
+	for key, rec := range records {
+		typedKey, err := convertKey[K](key)
+		if err != nil {
+			return nil, err
+		}
+		// different raw keys can convert to the same typed key (ie: 1 and "1" to string)
+		if _, duplicate := result[typedKey]; duplicate && !(len(keepLast) > 0 && keepLast[0]) {
+			return nil, fmt.Errorf("%w: %s=%v", ErrDuplicateKey, keyColumn, typedKey)
+		}
+		result[typedKey] = object.MapToStructSlowDB[T](rec.Data)
+	}
+	return result, nil
+}
+
+// convertKey converts a JSON decoded value to K, numbers convert between numeric kinds (only whole
+// floats to integers) and everything converts to a string kind
+func convertKey[K comparable](value interface{}) (K, error) {
+	var key K
+	if k, ok := value.(K); ok {
+		return k, nil
+	}
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return key, fmt.Errorf("cannot convert NULL key to %T", key)
+	}
+	target := reflect.ValueOf(&key).Elem()
+	if target.Kind() == reflect.String {
+		target.SetString(idKey(value))
+		return key, nil
+	}
+	switch {
+	case v.CanFloat() && (target.CanInt() || target.CanUint()):
+		if f := v.Float(); f == math.Trunc(f) {
+			target.Set(v.Convert(target.Type()))
+			return key, nil
+		}
+	case (v.CanInt() || v.CanUint() || v.CanFloat()) && (target.CanInt() || target.CanUint() || target.CanFloat()):
+		target.Set(v.Convert(target.Type()))
+		return key, nil
+	}
+	return key, fmt.Errorf("cannot convert key %v (%T) to %T", value, value, key)
+}
+
+// SelectOnlyOneSQLParameterizedInto runs a parameterized query that must return exactly one row
+// and scans it into T. Returns orm.ErrSQLNoRows or orm.ErrSQLMoreThanOneRow otherwise.
+func SelectOnlyOneSQLParameterizedInto[T any](c *Client, paramSQL orm.ParametereizedSQL) (T, error) {
+	record, err := c.SelectOnlyOneSQLParameterized(paramSQL)
+	if err != nil {
+		var empty T
+		return empty, err
+	}
+	return object.MapToStructSlowDB[T](record.Data), nil
+}
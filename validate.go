@@ -0,0 +1,110 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+//------------------------------------------------------------------
+// CONFIG VALIDATION
+//------------------------------------------------------------------
+
+// checkClientConfig returns an error for a client config that cannot work
+func checkClientConfig(config *ClientConfig) error {
+	if err := checkServerURL(config); err != nil {
+		return err
+	}
+	if config.HTTPTimeout < 0 {
+		return fmt.Errorf("HTTPTimeout must not be negative, got %s", config.HTTPTimeout)
+	}
+	return nil
+}
+
+// checkServerURL makes sure ServerURL is an absolute http:// or https:// URL. Credentials sent over
+// plain http:// to a host other than localhost print a warning, or fail with RequireHTTPS,
+// unless AllowInsecure is set.
+func checkServerURL(config *ClientConfig) error {
+	if strings.TrimSpace(config.ServerURL) == "" {
+		return errors.New("server URL is empty")
+	}
+	serverURL, err := url.Parse(config.ServerURL)
+	if err != nil {
+		return fmt.Errorf("invalid server URL %q: %w", config.ServerURL, err)
+	}
+	if serverURL.Scheme != "http" && serverURL.Scheme != "https" {
+		return fmt.Errorf("invalid server URL %q: scheme must be http or https", config.ServerURL)
+	}
+	if serverURL.Host == "" {
+		return fmt.Errorf("invalid server URL %q: missing host", config.ServerURL)
+	}
+	if serverURL.Scheme == "https" || config.AllowInsecure || isLoopbackHost(serverURL.Hostname()) {
+		return nil
+	}
+	if config.RequireHTTPS {
+		return fmt.Errorf("server URL %q is plain http, credentials and tokens would be sent unencrypted (use https or AllowInsecure)", config.ServerURL)
+	}
+	fmt.Printf("WARNING: server URL %s is plain http, credentials and tokens are sent unencrypted (use https or AllowInsecure to silence this)\n", config.ServerURL)
+	return nil
+}
+
+// checkPoolConfig returns all the problems of the pool configuration at once. provided is the pool
+// config given by the user (can be nil), its negative values are reported because merging it
+// would silently replace them with defaults. merged is the config the client will use.
+func checkPoolConfig(provided, merged *PoolConfig) error {
+	var errs []error
+	if provided != nil {
+		fields := []struct {
+			name  string
+			value int
+		}{
+			{"MinPoolSize", provided.MinPoolSize},
+			{"MaxPoolSize", provided.MaxPoolSize},
+			{"MaxWritePoolSize", provided.MaxWritePoolSize},
+			{"ScaleUpThreshold", provided.ScaleUpThreshold},
+			{"ScaleUpBatchSize", provided.ScaleUpBatchSize},
+			{"UsageWindowSize", provided.UsageWindowSize},
+			{"ConnectConcurrency", provided.ConnectConcurrency},
+			{"MaxInFlightPerNode", provided.MaxInFlightPerNode},
+			{"EventHistorySize", provided.EventHistorySize},
+		}
+		for _, field := range fields {
+			if field.value < 0 {
+				errs = append(errs, fmt.Errorf("%s must not be negative, got %d", field.name, field.value))
+			}
+		}
+	}
+	if merged.MaxPoolSize < 1 {
+		errs = append(errs, fmt.Errorf("MaxPoolSize must be at least 1, got %d", merged.MaxPoolSize))
+	}
+	if merged.MaxWritePoolSize < 1 {
+		errs = append(errs, fmt.Errorf("MaxWritePoolSize must be at least 1, got %d", merged.MaxWritePoolSize))
+	}
+	if merged.ScaleUpBatchSize < 1 {
+		errs = append(errs, fmt.Errorf("ScaleUpBatchSize must be at least 1, got %d", merged.ScaleUpBatchSize))
+	}
+	if merged.MaxWritePoolSize > merged.MaxPoolSize {
+		errs = append(errs, fmt.Errorf("MaxWritePoolSize (%d) must not be bigger than MaxPoolSize (%d)", merged.MaxWritePoolSize, merged.MaxPoolSize))
+	}
+	// the deprecated MinPoolSize defaults to 5, only a value chosen by the user is an error
+	if merged.MinPoolSize > merged.MaxPoolSize && merged.MinPoolSize == DEFAULT_MINIMUM_POOL_SIZE {
+		merged.MinPoolSize = merged.MaxPoolSize
+	}
+	if merged.MinPoolSize > merged.MaxPoolSize {
+		errs = append(errs, fmt.Errorf("MinPoolSize (%d) must not be bigger than MaxPoolSize (%d)", merged.MinPoolSize, merged.MaxPoolSize))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid pool config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/medatechnology/goutil/object"
+	orm "github.com/medatechnology/simpleorm"
+)
+
+//------------------------------------------------------------------
+// OPTIMISTIC CONCURRENCY
+//------------------------------------------------------------------
+
+// ErrVersionConflict is returned by UpdateWithVersion when the row was changed (or deleted) since
+// it was read, the caller should re-read the row and retry
+var ErrVersionConflict = errors.New("version conflict: the row was modified by another update")
+
+// UpdateWithVersion updates the row with the primary key of record only if its versionColumn is still
+// expectedVersion, and increments the version:
+//
+//	UPDATE table SET col = ?, ..., version = version + 1 WHERE id = ? AND version = ?
+//
+// Returns ErrVersionConflict if no row was updated. The primary key and version columns in
+// record.Data are not used in the SET clause.
+// Usage:
+//
+//	res, err := db.UpdateWithVersion(record, "version", 3)
+//	if errors.Is(err, client.ErrVersionConflict) { // reload and retry }
+func (c *Client) UpdateWithVersion(record orm.DBRecord, versionColumn string, expectedVersion int64) (orm.BasicSQLResult, error) {
+	primaryKey := c.Config.PrimaryKeyColumn
+	id, exists := record.Data[primaryKey]
+	if !exists {
+		err := fmt.Errorf("record has no primary key column %s", primaryKey)
+		return orm.BasicSQLResult{Error: err}, err
+	}
+
+	columns := make([]string, 0, len(record.Data))
+	for col := range record.Data {
+		if col != primaryKey && col != versionColumn {
+			columns = append(columns, col)
+		}
+	}
+	sort.Strings(columns)
+	sets := make([]string, 0, len(columns)+1)
+	values := make([]interface{}, 0, len(columns)+2)
+	for _, col := range columns {
+		sets = append(sets, col+" = ?")
+		values = append(values, record.Data[col])
+	}
+	sets = append(sets, fmt.Sprintf("%s = %s + 1", versionColumn, versionColumn))
+	values = append(values, id, expectedVersion)
+
+	result := c.ExecOneSQLParameterized(orm.ParametereizedSQL{
+		Query: fmt.Sprintf("UPDATE %s SET %s WHERE %s = ? AND %s = ?",
+			record.TableName, strings.Join(sets, ", "), primaryKey, versionColumn),
+		Values: values,
+	})
+	if result.Error != nil {
+		return result, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return result, ErrVersionConflict
+	}
+	return result, nil
+}
+
+// UpdateTableStructWithVersion is UpdateWithVersion for a table struct, all fields are updated
+func (c *Client) UpdateTableStructWithVersion(record orm.TableStruct, versionColumn string, expectedVersion int64) (orm.BasicSQLResult, error) {
+	dbRecord := orm.DBRecord{
+		TableName: record.TableName(),
+		Data:      object.StructToMapWithOptions(record, object.MapOptions{SkipNilPointers: true, TimeFormat: time.RFC3339}),
+	}
+	return c.UpdateWithVersion(dbRecord, versionColumn, expectedVersion)
+}